@@ -1,92 +1,731 @@
-// Package sms 阿里云短信服务实现
-package sms
-
-import (
-	"encoding/json"
-	"fmt"
-	"strings"
-
-	"github.com/aliyun/alibaba-cloud-sdk-go/services/dysmsapi"
-)
-
-// AliyunClient 阿里云短信客户端
-// 封装阿里云短信API调用
-type AliyunClient struct {
-	template string             // 短信模板ID
-	sign     string             // 短信签名
-	core     *dysmsapi.Client   // 阿里云SDK客户端
-}
-
-// AliyunResult 阿里云短信发送结果
-type AliyunResult struct {
-	RequestId string // 请求ID
-	Message   string // 响应消息
-}
-
-// GetAliyunClient 创建阿里云短信客户端
-// 参数:
-//   - accessId: 阿里云访问ID
-//   - accessKey: 阿里云访问密钥
-//   - sign: 短信签名
-//   - template: 短信模板ID
-// 返回:
-//   - *AliyunClient: 阿里云短信客户端实例
-//   - error: 错误信息
-func GetAliyunClient(accessId string, accessKey string, sign string, template string) (*AliyunClient, error) {
-	region := "cn-hangzhou"
-	client, err := dysmsapi.NewClientWithAccessKey(region, accessId, accessKey)
-	if err != nil {
-		return nil, err
-	}
-
-	aliyunClient := &AliyunClient{
-		template: template,
-		core:     client,
-		sign:     sign,
-	}
-
-	return aliyunClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *AliyunClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	requestParam, err := json.Marshal(param)
-	if err != nil {
-		return err
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	request := dysmsapi.CreateSendSmsRequest()
-	request.Scheme = "https"
-	request.PhoneNumbers = strings.Join(targetPhoneNumber, ",")
-	request.TemplateCode = c.template
-	request.TemplateParam = string(requestParam)
-	request.SignName = c.sign
-
-	response, err := c.core.SendSms(request)
-	if err != nil {
-		return err
-	}
-
-	if response.Code != "OK" {
-		aliyunResult := AliyunResult{}
-		err = json.Unmarshal(response.GetHttpContentBytes(), &aliyunResult)
-		if err != nil {
-			return err
-		}
-
-		if aliyunResult.Message != "" {
-			return fmt.Errorf(aliyunResult.Message)
-		}
-	}
-
-	return nil
-}
+// Package sms 阿里云短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	aliyunerr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/dysmsapi"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// aliyunErrorCodes 阿里云错误码与标准错误的对应关系
+// 参考文档: https://help.aliyun.com/document_detail/101346.html
+var aliyunErrorCodes = map[string]error{
+	"isv.MOBILE_NUMBER_ILLEGAL":       ErrInvalidNumber,
+	"isv.MOBILE_COUNT_OVER_LIMIT":     ErrInvalidParameter,
+	"isv.INVALID_PARAMETERS":          ErrInvalidParameter,
+	"isv.INVALID_JSON_PARAM":          ErrInvalidParameter,
+	"isv.PARAM_LENGTH_LIMIT":          ErrInvalidParameter,
+	"isv.TEMPLATE_MISSING_PARAMETERS": ErrTemplateRejected,
+	"isv.SMS_TEMPLATE_ILLEGAL":        ErrTemplateRejected,
+	"isv.SMS_SIGNATURE_ILLEGAL":       ErrSignRejected,
+	"isv.SIGN_NAME_ILLEGAL":           ErrSignRejected,
+	"isv.BUSINESS_LIMIT_CONTROL":      ErrRateLimited,
+	"isv.DAY_LIMIT_CONTROL":           ErrRateLimited,
+	"isv.AMOUNT_NOT_ENOUGH":           ErrInsufficientBalance,
+	"isv.OUT_OF_SERVICE":              ErrInsufficientBalance,
+	"isv.ACCOUNT_NOT_EXISTS":          ErrAuthFailed,
+	"isv.ACCOUNT_ABNORMAL":            ErrAuthFailed,
+	"isv.BLACK_KEY_CONTROL_LIMIT":     ErrContentBlocked,
+	"isp.RAM_PERMISSION_DENY":         ErrAuthFailed,
+	"isp.SYSTEM_ERROR":                ErrServiceUnavailable,
+	"InvalidAccessKeyId.NotFound":     ErrAuthFailed,
+	"InvalidAccessKeyId.Inactive":     ErrAuthFailed,
+	"SignatureDoesNotMatch":           ErrAuthFailed,
+	"Throttling.User":                 ErrRateLimited,
+	"Throttling.Api":                  ErrRateLimited,
+	"ServiceUnavailable":              ErrServiceUnavailable,
+	"InternalError":                   ErrServiceUnavailable,
+	aliyunerr.TimeoutErrorCode:        ErrServiceUnavailable,
+}
+
+// aliyunNumberFormat 阿里云要求的号码格式
+// 国内号码不带国家代码，国际/港澳台号码为国家代码加号码，不带"+"
+var aliyunNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL, international: phone.FORMAT_DIGITS}
+
+// aliyunLocation 阿里云短信接口使用的时区（北京时间）
+var aliyunLocation = time.FixedZone("CST", 8*60*60)
+
+// 阿里云短信发送状态
+const (
+	ALIYUN_SEND_WAITING   = 1 // 等待回执
+	ALIYUN_SEND_FAILED    = 2 // 发送失败
+	ALIYUN_SEND_DELIVERED = 3 // 发送成功
+)
+
+// 阿里云短信模板类型
+const (
+	ALIYUN_TEMPLATE_VERIFICATION  = 0 // 验证码
+	ALIYUN_TEMPLATE_NOTIFICATION  = 1 // 短信通知
+	ALIYUN_TEMPLATE_PROMOTION     = 2 // 推广短信
+	ALIYUN_TEMPLATE_INTERNATIONAL = 3 // 国际/港澳台消息
+)
+
+// ALIYUN_LIST_PAGE_SIZE 阿里云查询短信模板和签名列表的每页数量
+const ALIYUN_LIST_PAGE_SIZE = 50
+
+// aliyunSignSources 标准签名来源与阿里云签名来源的对应关系
+var aliyunSignSources = map[SignSource]int{
+	SIGN_COMPANY:   0, // 企事业单位的全称或简称
+	SIGN_WEBSITE:   1, // 工信部备案网站的全称或简称
+	SIGN_APP:       2, // APP应用的全称或简称
+	SIGN_WECHAT:    3, // 公众号或小程序的全称或简称
+	SIGN_TRADEMARK: 5, // 商标名的全称或简称
+}
+
+// aliyunReviewStatuses 阿里云模板和签名审核状态与标准审核状态的对应关系
+// 查询单个模板或签名时返回数字状态，查询列表时返回字符串状态
+var aliyunReviewStatuses = map[string]ReviewStatus{
+	"0":                    REVIEW_PENDING,
+	"1":                    REVIEW_APPROVED,
+	"2":                    REVIEW_REJECTED,
+	"10":                   REVIEW_CANCELED,
+	"AUDIT_STATE_INIT":     REVIEW_PENDING,
+	"AUDIT_STATE_PASS":     REVIEW_APPROVED,
+	"AUDIT_STATE_NOT_PASS": REVIEW_REJECTED,
+	"AUDIT_STATE_CANCEL":   REVIEW_CANCELED,
+}
+
+// AliyunClient 阿里云短信客户端
+// 封装阿里云短信API调用
+type AliyunClient struct {
+	template string           // 短信模板ID
+	sign     string           // 短信签名
+	core     *dysmsapi.Client // 阿里云SDK客户端
+	endpoint string           // 服务端点（为空时使用SDK默认端点）
+}
+
+// AliyunSmsUp 阿里云上行短信（HTTP批量推送格式）
+type AliyunSmsUp struct {
+	PhoneNumber string      `json:"phone_number"` // 发送方号码
+	SendTime    string      `json:"send_time"`    // 发送时间
+	Content     string      `json:"content"`      // 短信内容
+	SignName    string      `json:"sign_name"`    // 短信签名
+	DestCode    string      `json:"dest_code"`    // 扩展码
+	SequenceId  json.Number `json:"sequence_id"`  // 序列号
+}
+
+// AliyunResult 阿里云短信发送结果
+type AliyunResult struct {
+	RequestId string // 请求ID
+	Message   string // 响应消息
+}
+
+// init 注册阿里云短信服务
+func init() {
+	Register(SMS_ALIYUN, func(config ProviderConfig) (SmsProvider, error) {
+		return GetAliyunClient(config.AccessId, config.AccessKey, config.Sign, config.Template)
+	}, "AccessId", "AccessKey", "Sign", "Template")
+}
+
+// GetAliyunClient 创建阿里云短信客户端
+// 参数:
+//   - accessId: 阿里云访问ID
+//   - accessKey: 阿里云访问密钥
+//   - sign: 短信签名
+//   - template: 短信模板ID
+//
+// 返回:
+//   - *AliyunClient: 阿里云短信客户端实例
+//   - error: 错误信息
+func GetAliyunClient(accessId string, accessKey string, sign string, template string) (*AliyunClient, error) {
+	region := "cn-hangzhou"
+	client, err := dysmsapi.NewClientWithAccessKey(region, accessId, accessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aliyunClient := &AliyunClient{
+		template: template,
+		core:     client,
+		sign:     sign,
+	}
+
+	return aliyunClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 阿里云SDK仅使用HTTP客户端的Transport和Timeout（作为读取超时）
+// 参数:
+//   - client: HTTP客户端
+func (c *AliyunClient) SetHTTPClient(client *http.Client) {
+	if client.Transport != nil {
+		c.core.SetTransport(client.Transport)
+	}
+	if client.Timeout > 0 {
+		c.core.SetReadTimeout(client.Timeout)
+	}
+}
+
+// SetEndpoint 设置服务端点
+// 阿里云SDK仅使用服务端点的协议和主机
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *AliyunClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - error: 错误信息
+func (c *AliyunClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *AliyunClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	requestParam, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := aliyunNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	request := dysmsapi.CreateSendSmsRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.PhoneNumbers = strings.Join(phoneNumbers, ",")
+	request.TemplateCode = c.template
+	request.TemplateParam = string(requestParam)
+	request.SignName = c.sign
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.SendSmsResponse, error) {
+		return c.core.SendSms(request)
+	})
+	if err != nil {
+		return nil, aliyunSdkError(err)
+	}
+
+	result := newSendResult(SMS_ALIYUN)
+	result.RequestId = response.RequestId
+	result.Raw = response.GetHttpContentString()
+
+	if response.Code != "OK" {
+		for _, phoneNumber := range targetPhoneNumber {
+			result.add(&RecipientResult{
+				PhoneNumber: phoneNumber,
+				Code:        response.Code,
+				Message:     response.Message,
+			})
+		}
+
+		// 优先使用原始响应中的错误信息，解析失败或为空时使用SDK解析的结果
+		message := response.Message
+		aliyunResult := AliyunResult{}
+		if err = json.Unmarshal(response.GetHttpContentBytes(), &aliyunResult); err == nil && aliyunResult.Message != "" {
+			message = aliyunResult.Message
+		}
+
+		return result, newSmsError(SMS_ALIYUN, response.Code, message, aliyunErrorCodes)
+	}
+
+	// 阿里云一次请求只返回一个回执ID，同一批次的号码共用
+	result.acceptAll(response.BizId, targetPhoneNumber)
+
+	return result, nil
+}
+
+// QueryStatus 查询短信送达状态
+// 通过QuerySendDetails接口按号码、回执ID和发送日期查询，查询条件必须包含PhoneNumber
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询条件列表
+//
+// 返回:
+//   - []*DeliveryReport: 与查询条件一一对应的状态报告
+//   - error: 错误信息
+func (c *AliyunClient) QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	reports := make([]*DeliveryReport, 0, len(queries))
+	for _, query := range queries {
+		if query.PhoneNumber == "" {
+			return reports, fmt.Errorf("missing parameter: phoneNumber")
+		}
+		phoneNumber, err := aliyunNumberFormat.format(query.PhoneNumber)
+		if err != nil {
+			return reports, err
+		}
+
+		sentAt := query.SentAt
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+
+		request := dysmsapi.CreateQuerySendDetailsRequest()
+		c.prepareRequest(request.RpcRequest)
+		request.PhoneNumber = phoneNumber
+		request.BizId = query.MessageId
+		request.SendDate = sentAt.In(aliyunLocation).Format("20060102")
+		request.PageSize = requests.NewInteger(10)
+		request.CurrentPage = requests.NewInteger(1)
+
+		response, err := callWithContext(ctx, func() (*dysmsapi.QuerySendDetailsResponse, error) {
+			return c.core.QuerySendDetails(request)
+		})
+		if err != nil {
+			return reports, aliyunSdkError(err)
+		}
+		if response.Code != "OK" {
+			return reports, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+		}
+
+		report := newDeliveryReport(SMS_ALIYUN, query)
+		report.Raw = response.GetHttpContentString()
+		if details := response.SmsSendDetailDTOs.SmsSendDetailDTO; len(details) > 0 {
+			detail := details[0]
+			report.Code = detail.ErrCode
+			report.SentAt = parseTime(time.DateTime, detail.SendDate, aliyunLocation)
+			report.DeliveredAt = parseTime(time.DateTime, detail.ReceiveDate, aliyunLocation)
+			switch detail.SendStatus {
+			case ALIYUN_SEND_WAITING:
+				report.Status = DELIVERY_SENT
+			case ALIYUN_SEND_FAILED:
+				report.Status = DELIVERY_FAILED
+			case ALIYUN_SEND_DELIVERED:
+				report.Status = DELIVERY_DELIVERED
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// CreateTemplate 创建短信模板并提交审核
+// 通过AddSmsTemplate接口创建，模板ID为返回的模板CODE
+// 参数:
+//   - ctx: 上下文
+//   - template: 创建短信模板的请求
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息
+func (c *AliyunClient) CreateTemplate(ctx context.Context, template TemplateRequest) (*Template, error) {
+	templateType := ALIYUN_TEMPLATE_NOTIFICATION
+	switch {
+	case template.International:
+		templateType = ALIYUN_TEMPLATE_INTERNATIONAL
+	case template.Type == TEMPLATE_VERIFICATION:
+		templateType = ALIYUN_TEMPLATE_VERIFICATION
+	case template.Type == TEMPLATE_PROMOTION:
+		templateType = ALIYUN_TEMPLATE_PROMOTION
+	}
+
+	request := dysmsapi.CreateAddSmsTemplateRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.TemplateType = requests.NewInteger(templateType)
+	request.TemplateName = template.Name
+	request.TemplateContent = template.Content
+	request.Remark = template.Remark
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.AddSmsTemplateResponse, error) {
+		return c.core.AddSmsTemplate(request)
+	})
+	if err != nil {
+		return nil, aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return nil, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+
+	return &Template{
+		Provider:      SMS_ALIYUN,
+		TemplateId:    response.TemplateCode,
+		Name:          template.Name,
+		Content:       template.Content,
+		Type:          template.Type,
+		International: template.International,
+		Status:        REVIEW_PENDING,
+		Raw:           response.GetHttpContentString(),
+	}, nil
+}
+
+// ListTemplates 查询全部短信模板
+// 通过QuerySmsTemplateList接口分页查询
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*Template: 短信模板列表
+//   - error: 错误信息
+func (c *AliyunClient) ListTemplates(ctx context.Context) ([]*Template, error) {
+	templates := make([]*Template, 0)
+	for page := 1; ; page++ {
+		request := dysmsapi.CreateQuerySmsTemplateListRequest()
+		c.prepareRequest(request.RpcRequest)
+		request.PageIndex = requests.NewInteger(page)
+		request.PageSize = requests.NewInteger(ALIYUN_LIST_PAGE_SIZE)
+
+		response, err := callWithContext(ctx, func() (*dysmsapi.QuerySmsTemplateListResponse, error) {
+			return c.core.QuerySmsTemplateList(request)
+		})
+		if err != nil {
+			return templates, aliyunSdkError(err)
+		}
+		if response.Code != "OK" {
+			return templates, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+		}
+
+		for _, item := range response.SmsTemplateList {
+			template := aliyunTemplate(item.TemplateCode, item.TemplateName, item.TemplateContent, item.TemplateType, item.AuditStatus, item.CreateDate)
+			template.Reason = item.Reason.RejectInfo
+			if raw, err := json.Marshal(item); err == nil {
+				template.Raw = string(raw)
+			}
+			templates = append(templates, template)
+		}
+		if len(response.SmsTemplateList) < ALIYUN_LIST_PAGE_SIZE {
+			return templates, nil
+		}
+	}
+}
+
+// GetTemplate 查询短信模板及其审核状态
+// 通过QuerySmsTemplate接口查询
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板CODE
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息
+func (c *AliyunClient) GetTemplate(ctx context.Context, templateId string) (*Template, error) {
+	request := dysmsapi.CreateQuerySmsTemplateRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.TemplateCode = templateId
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.QuerySmsTemplateResponse, error) {
+		return c.core.QuerySmsTemplate(request)
+	})
+	if err != nil {
+		return nil, aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return nil, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+
+	template := aliyunTemplate(response.TemplateCode, response.TemplateName, response.TemplateContent, response.TemplateType, strconv.Itoa(response.TemplateStatus), response.CreateDate)
+	template.Reason = response.Reason
+	template.Raw = response.GetHttpContentString()
+	return template, nil
+}
+
+// DeleteTemplate 删除短信模板
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板CODE
+//
+// 返回:
+//   - error: 错误信息
+func (c *AliyunClient) DeleteTemplate(ctx context.Context, templateId string) error {
+	request := dysmsapi.CreateDeleteSmsTemplateRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.TemplateCode = templateId
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.DeleteSmsTemplateResponse, error) {
+		return c.core.DeleteSmsTemplate(request)
+	})
+	if err != nil {
+		return aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+	return nil
+}
+
+// CreateSign 创建短信签名并提交审核
+// 通过AddSmsSign接口创建，签名ID为签名名称；阿里云不区分国内和国际签名，忽略International
+// 参数:
+//   - ctx: 上下文
+//   - sign: 创建短信签名的请求
+//
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息
+func (c *AliyunClient) CreateSign(ctx context.Context, sign SignRequest) (*Sign, error) {
+	source, ok := aliyunSignSources[sign.Source]
+	if !ok {
+		return nil, fmt.Errorf("bad parameter: unsupported sign source %q", sign.Source)
+	}
+
+	request := dysmsapi.CreateAddSmsSignRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.SignName = sign.Name
+	request.SignSource = requests.NewInteger(source)
+	request.Remark = sign.Remark
+	if len(sign.Proof) > 0 {
+		request.SignFileList = &[]dysmsapi.AddSmsSignSignFileList{{
+			FileContents: base64.StdEncoding.EncodeToString(sign.Proof),
+			FileSuffix:   sign.ProofFormat,
+		}}
+	}
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.AddSmsSignResponse, error) {
+		return c.core.AddSmsSign(request)
+	})
+	if err != nil {
+		return nil, aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return nil, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+
+	return &Sign{
+		Provider: SMS_ALIYUN,
+		SignId:   response.SignName,
+		Name:     response.SignName,
+		Status:   REVIEW_PENDING,
+		Raw:      response.GetHttpContentString(),
+	}, nil
+}
+
+// ListSigns 查询全部短信签名
+// 通过QuerySmsSignList接口分页查询
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*Sign: 短信签名列表
+//   - error: 错误信息
+func (c *AliyunClient) ListSigns(ctx context.Context) ([]*Sign, error) {
+	signs := make([]*Sign, 0)
+	for page := 1; ; page++ {
+		request := dysmsapi.CreateQuerySmsSignListRequest()
+		c.prepareRequest(request.RpcRequest)
+		request.PageIndex = requests.NewInteger(page)
+		request.PageSize = requests.NewInteger(ALIYUN_LIST_PAGE_SIZE)
+
+		response, err := callWithContext(ctx, func() (*dysmsapi.QuerySmsSignListResponse, error) {
+			return c.core.QuerySmsSignList(request)
+		})
+		if err != nil {
+			return signs, aliyunSdkError(err)
+		}
+		if response.Code != "OK" {
+			return signs, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+		}
+
+		for _, item := range response.SmsSignList {
+			sign := aliyunSign(item.SignName, item.AuditStatus, item.CreateDate)
+			sign.Reason = item.Reason.RejectInfo
+			if raw, err := json.Marshal(item); err == nil {
+				sign.Raw = string(raw)
+			}
+			signs = append(signs, sign)
+		}
+		if len(response.SmsSignList) < ALIYUN_LIST_PAGE_SIZE {
+			return signs, nil
+		}
+	}
+}
+
+// GetSign 查询短信签名及其审核状态
+// 通过QuerySmsSign接口查询
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名名称
+//
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息
+func (c *AliyunClient) GetSign(ctx context.Context, signId string) (*Sign, error) {
+	request := dysmsapi.CreateQuerySmsSignRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.SignName = signId
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.QuerySmsSignResponse, error) {
+		return c.core.QuerySmsSign(request)
+	})
+	if err != nil {
+		return nil, aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return nil, newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+
+	sign := aliyunSign(response.SignName, strconv.Itoa(response.SignStatus), response.CreateDate)
+	sign.Reason = response.Reason
+	sign.Raw = response.GetHttpContentString()
+	return sign, nil
+}
+
+// DeleteSign 删除短信签名
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名名称
+//
+// 返回:
+//   - error: 错误信息
+func (c *AliyunClient) DeleteSign(ctx context.Context, signId string) error {
+	request := dysmsapi.CreateDeleteSmsSignRequest()
+	c.prepareRequest(request.RpcRequest)
+	request.SignName = signId
+
+	response, err := callWithContext(ctx, func() (*dysmsapi.DeleteSmsSignResponse, error) {
+		return c.core.DeleteSmsSign(request)
+	})
+	if err != nil {
+		return aliyunSdkError(err)
+	}
+	if response.Code != "OK" {
+		return newSmsError(SMS_ALIYUN, response.Code, response.Message, aliyunErrorCodes)
+	}
+	return nil
+}
+
+// prepareRequest 设置阿里云API请求的协议和服务端点
+// 参数:
+//   - request: 阿里云API请求
+func (c *AliyunClient) prepareRequest(request *requests.RpcRequest) {
+	request.Scheme = "https"
+	if c.endpoint != "" {
+		request.Scheme, request.Domain = splitEndpoint(c.endpoint)
+	}
+}
+
+// aliyunTemplate 将阿里云模板信息转换为短信模板
+// 参数:
+//   - code: 模板CODE
+//   - name: 模板名称
+//   - content: 模板内容
+//   - templateType: 阿里云模板类型
+//   - status: 阿里云审核状态
+//   - createDate: 创建时间
+//
+// 返回:
+//   - *Template: 短信模板
+func aliyunTemplate(code string, name string, content string, templateType int, status string, createDate string) *Template {
+	template := &Template{
+		Provider:   SMS_ALIYUN,
+		TemplateId: code,
+		Name:       name,
+		Content:    content,
+		Status:     REVIEW_UNKNOWN,
+		CreatedAt:  parseTime(time.DateTime, createDate, aliyunLocation),
+	}
+	if reviewStatus, ok := aliyunReviewStatuses[status]; ok {
+		template.Status = reviewStatus
+	}
+	switch templateType {
+	case ALIYUN_TEMPLATE_VERIFICATION:
+		template.Type = TEMPLATE_VERIFICATION
+	case ALIYUN_TEMPLATE_NOTIFICATION:
+		template.Type = TEMPLATE_NOTIFICATION
+	case ALIYUN_TEMPLATE_PROMOTION:
+		template.Type = TEMPLATE_PROMOTION
+	case ALIYUN_TEMPLATE_INTERNATIONAL:
+		template.International = true
+	}
+	return template
+}
+
+// aliyunSign 将阿里云签名信息转换为短信签名
+// 参数:
+//   - name: 签名名称
+//   - status: 阿里云审核状态
+//   - createDate: 创建时间
+//
+// 返回:
+//   - *Sign: 短信签名
+func aliyunSign(name string, status string, createDate string) *Sign {
+	sign := &Sign{
+		Provider:  SMS_ALIYUN,
+		SignId:    name,
+		Name:      name,
+		Status:    REVIEW_UNKNOWN,
+		CreatedAt: parseTime(time.DateTime, createDate, aliyunLocation),
+	}
+	if reviewStatus, ok := aliyunReviewStatuses[status]; ok {
+		sign.Status = reviewStatus
+	}
+	return sign
+}
+
+// aliyunSdkError 将阿里云SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+//
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func aliyunSdkError(err error) error {
+	var serverErr *aliyunerr.ServerError
+	if errors.As(err, &serverErr) {
+		return newSmsError(SMS_ALIYUN, serverErr.ErrorCode(), serverErr.Message(), aliyunErrorCodes).withStatus(serverErr.HttpStatus())
+	}
+
+	var clientErr *aliyunerr.ClientError
+	if errors.As(err, &clientErr) {
+		smsErr := newSmsError(SMS_ALIYUN, clientErr.ErrorCode(), clientErr.Message(), aliyunErrorCodes)
+		if smsErr.Err == nil {
+			smsErr.Err = clientErr.OriginError()
+		}
+		return smsErr
+	}
+
+	return err
+}
+
+// parseAliyunInbound 解析阿里云上行短信推送请求
+// 需在阿里云控制台将上行短信的接收方式配置为HTTP批量推送
+// 参数:
+//   - r: HTTP请求
+//
+// 返回:
+//   - []*InboundMessage: 上行短信列表
+//   - error: 错误信息
+func parseAliyunInbound(r *http.Request) ([]*InboundMessage, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []AliyunSmsUp
+	if err = json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("bad callback body: %w", err)
+	}
+
+	messages := make([]*InboundMessage, 0, len(items))
+	for _, item := range items {
+		message := &InboundMessage{
+			MessageId:  item.SequenceId.String(),
+			From:       item.PhoneNumber,
+			To:         item.DestCode,
+			Text:       item.Content,
+			ReceivedAt: parseTime(time.DateTime, item.SendTime, aliyunLocation),
+		}
+		if raw, err := json.Marshal(item); err == nil {
+			message.Raw = string(raw)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -1,91 +1,404 @@
-// Package sms 亚马逊SNS短信服务实现
-package sms
-
-import (
-	"fmt"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/sns"
-	"github.com/aws/aws-sdk-go/service/sns/snsiface"
-)
-
-// AmazonSNSClient 亚马逊SNS短信客户端
-// 封装亚马逊SNS短信API调用
-type AmazonSNSClient struct {
-	svc      snsiface.SNSAPI // SNS服务接口
-	template string          // 短信模板
-}
-
-// GetAmazonSNSClient 创建亚马逊SNS短信客户端
-// 参数:
-//   - accessKeyId: AWS访问密钥ID
-//   - secretAccessKey: AWS访问密钥
-//   - template: 短信模板
-//   - region: AWS区域列表
-// 返回:
-//   - *AmazonSNSClient: 亚马逊SNS短信客户端实例
-//   - error: 错误信息
-func GetAmazonSNSClient(accessKeyId string, secretAccessKey string, template string, region []string) (*AmazonSNSClient, error) {
-	if len(region) == 0 {
-		return nil, fmt.Errorf("missing parameter: region")
-	}
-
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region[0]),
-		Credentials: credentials.NewStaticCredentials(accessKeyId, secretAccessKey, ""),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	svc := sns.New(sess)
-
-	snsClient := &AmazonSNSClient{
-		svc:      svc,
-		template: template,
-	}
-
-	return snsClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (a *AmazonSNSClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	bodyContent := fmt.Sprintf(a.template, code)
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	messageAttributes := make(map[string]*sns.MessageAttributeValue)
-	for k, v := range param {
-		messageAttributes[k] = &sns.MessageAttributeValue{
-			DataType:    aws.String("String"),
-			StringValue: aws.String(v),
-		}
-	}
-
-	for i := 0; i < len(targetPhoneNumber); i++ {
-		_, err := a.svc.Publish(&sns.PublishInput{
-			Message:           &bodyContent,
-			PhoneNumber:       &targetPhoneNumber[i],
-			MessageAttributes: messageAttributes,
-		})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
+// Package sms 亚马逊SNS短信服务实现
+package sms
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// awsNumberFormat 亚马逊SNS要求的号码格式（E.164）
+var awsNumberFormat = numberFormat{domestic: phone.FORMAT_E164}
+
+// awsErrorCodes 亚马逊SNS错误码与标准错误的对应关系
+var awsErrorCodes = map[string]error{
+	sns.ErrCodeAuthorizationErrorException: ErrAuthFailed,
+	sns.ErrCodeInvalidParameterException:   ErrInvalidParameter,
+	sns.ErrCodeThrottledException:          ErrRateLimited,
+	sns.ErrCodeInternalErrorException:      ErrServiceUnavailable,
+	"InvalidClientTokenId":                 ErrAuthFailed,
+	"SignatureDoesNotMatch":                ErrAuthFailed,
+	"UnrecognizedClientException":          ErrAuthFailed,
+	"ExpiredToken":                         ErrAuthFailed,
+	"Throttling":                           ErrRateLimited,
+	"ServiceUnavailable":                   ErrServiceUnavailable,
+}
+
+// AmazonSNSClient 亚马逊SNS短信客户端
+// 封装亚马逊SNS短信API调用
+type AmazonSNSClient struct {
+	svc          snsiface.SNSAPI // SNS服务接口
+	template     string          // 短信模板
+	segmentLimit SegmentLimit    // 拆分条数限制
+	renderMode   RenderMode      // 短信模板的渲染方式
+}
+
+// SnsMessage 亚马逊SNS推送到HTTP(S)订阅的消息
+type SnsMessage struct {
+	Type             string `json:"Type"`             // 消息类型（Notification、SubscriptionConfirmation、UnsubscribeConfirmation）
+	MessageId        string `json:"MessageId"`        // 消息ID
+	Token            string `json:"Token"`            // 确认订阅的令牌
+	TopicArn         string `json:"TopicArn"`         // 主题ARN
+	Subject          string `json:"Subject"`          // 消息主题
+	Message          string `json:"Message"`          // 消息内容
+	Timestamp        string `json:"Timestamp"`        // 发布时间
+	SignatureVersion string `json:"SignatureVersion"` // 签名版本（1为SHA1，2为SHA256）
+	Signature        string `json:"Signature"`        // 签名
+	SigningCertURL   string `json:"SigningCertURL"`   // 签名证书地址
+	SubscribeURL     string `json:"SubscribeURL"`     // 确认订阅的地址
+}
+
+// snsCertHost SNS签名证书的合法主机
+var snsCertHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SnsVerifier 亚马逊SNS消息签名校验器
+// 从SigningCertURL下载（并缓存）SNS的签名证书校验消息签名，证书地址必须为HTTPS的SNS域名
+// 参考文档: https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+type SnsVerifier struct {
+	topicArns  []string                     // 允许的主题ARN，为空时不限制
+	httpClient *http.Client                 // 下载签名证书的HTTP客户端
+	mu         sync.Mutex                   // 证书缓存锁
+	certs      map[string]*x509.Certificate // 按证书地址缓存的签名证书
+}
+
+// 确保SnsVerifier实现了WebhookVerifier接口
+var _ WebhookVerifier = &SnsVerifier{}
+
+// init 注册亚马逊SNS短信服务
+func init() {
+	Register(SMS_AMAZON, func(config ProviderConfig) (SmsProvider, error) {
+		return GetAmazonSNSClient(config.AccessId, config.AccessKey, config.Template, config.otherArgs(config.Region))
+	}, "AccessId", "AccessKey", "Template", "Region")
+}
+
+// GetAmazonSNSClient 创建亚马逊SNS短信客户端
+// 参数:
+//   - accessKeyId: AWS访问密钥ID
+//   - secretAccessKey: AWS访问密钥
+//   - template: 短信模板
+//   - region: AWS区域列表
+// 返回:
+//   - *AmazonSNSClient: 亚马逊SNS短信客户端实例
+//   - error: 错误信息
+func GetAmazonSNSClient(accessKeyId string, secretAccessKey string, template string, region []string) (*AmazonSNSClient, error) {
+	if len(region) == 0 {
+		return nil, fmt.Errorf("missing parameter: region")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region[0]),
+		Credentials: credentials.NewStaticCredentials(accessKeyId, secretAccessKey, ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	svc := sns.New(sess)
+
+	snsClient := &AmazonSNSClient{
+		svc:      svc,
+		template: template,
+	}
+
+	return snsClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *AmazonSNSClient) SetHTTPClient(client *http.Client) {
+	if svc, ok := c.svc.(*sns.SNS); ok {
+		svc.Client.Config.HTTPClient = client
+	}
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *AmazonSNSClient) SetEndpoint(endpoint string) {
+	if svc, ok := c.svc.(*sns.SNS); ok {
+		svc.Client.Endpoint = endpoint
+	}
+}
+
+// SetSegmentLimit 设置短信拆分条数限制
+// 参数:
+//   - limit: 拆分条数限制
+func (c *AmazonSNSClient) SetSegmentLimit(limit SegmentLimit) {
+	c.segmentLimit = limit
+}
+
+// SetMessageTemplate 设置短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (c *AmazonSNSClient) SetMessageTemplate(text string, mode RenderMode) {
+	c.template = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (a *AmazonSNSClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := a.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (a *AmazonSNSClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	bodyContent, err := RenderMessage(a.template, a.renderMode, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	if err = a.segmentLimit.check(bodyContent); err != nil {
+		return nil, err
+	}
+
+	phoneNumbers, err := awsNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	messageAttributes := make(map[string]*sns.MessageAttributeValue)
+	for k, v := range param {
+		messageAttributes[k] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	result := newSendResult(SMS_AMAZON)
+	for i := 0; i < len(targetPhoneNumber); i++ {
+		output, err := a.svc.PublishWithContext(ctx, &sns.PublishInput{
+			Message:           &bodyContent,
+			PhoneNumber:       &phoneNumbers[i],
+			MessageAttributes: messageAttributes,
+		})
+		if err != nil {
+			return result, awsSdkError(err, targetPhoneNumber[i])
+		}
+
+		result.add(&RecipientResult{
+			PhoneNumber: targetPhoneNumber[i],
+			MessageId:   aws.StringValue(output.MessageId),
+			Accepted:    true,
+			Raw:         output.String(),
+		})
+	}
+
+	return result, nil
+}
+
+// awsSdkError 将亚马逊SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+//   - phoneNumber: 接收方号码
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func awsSdkError(err error, phoneNumber string) error {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return err
+	}
+
+	smsErr := newSmsError(SMS_AMAZON, awsErr.Code(), awsErr.Message(), awsErrorCodes).forNumber(phoneNumber)
+
+	// SNS对号码格式错误返回InvalidParameter，错误信息中包含PhoneNumber
+	if awsErr.Code() == sns.ErrCodeInvalidParameterException && strings.Contains(awsErr.Message(), "PhoneNumber") {
+		smsErr.Err = ErrInvalidNumber
+	}
+
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) {
+		smsErr.withStatus(requestErr.StatusCode())
+	}
+
+	// 网络错误和上下文取消保留原始错误
+	if smsErr.Err == nil {
+		smsErr.Err = awsErr.OrigErr()
+	}
+
+	return smsErr
+}
+
+// NewSnsVerifier 创建亚马逊SNS消息签名校验器
+// 参数:
+//   - topicArn: 允许的主题ARN列表，为空时接受任意主题的消息
+// 返回:
+//   - *SnsVerifier: SNS消息签名校验器
+func NewSnsVerifier(topicArn ...string) *SnsVerifier {
+	return &SnsVerifier{
+		topicArns:  topicArn,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		certs:      make(map[string]*x509.Certificate),
+	}
+}
+
+// SetHTTPClient 设置下载签名证书的HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (v *SnsVerifier) SetHTTPClient(client *http.Client) {
+	v.httpClient = client
+}
+
+// Verify 校验SNS消息签名
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体（SNS消息JSON）
+// 返回:
+//   - error: 签名不匹配或主题不在允许列表中时返回包含ErrInvalidSignature的错误
+func (v *SnsVerifier) Verify(r *http.Request, body []byte) error {
+	var message SnsMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if len(v.topicArns) > 0 && !slices.Contains(v.topicArns, message.TopicArn) {
+		return fmt.Errorf("%w: unexpected topic %s", ErrInvalidSignature, message.TopicArn)
+	}
+
+	var hash crypto.Hash
+	switch message.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrInvalidSignature, message.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	cert, err := v.certificate(r.Context(), message.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: unexpected public key type %T", ErrInvalidSignature, cert.PublicKey)
+	}
+
+	h := hash.New()
+	h.Write([]byte(message.signingString()))
+	if err = rsa.VerifyPKCS1v15(publicKey, hash, h.Sum(nil), signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// certificate 获取SNS签名证书
+// 参数:
+//   - ctx: 上下文
+//   - certUrl: 签名证书地址
+// 返回:
+//   - *x509.Certificate: 签名证书
+//   - error: 错误信息
+func (v *SnsVerifier) certificate(ctx context.Context, certUrl string) (*x509.Certificate, error) {
+	u, err := url.Parse(certUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" || !snsCertHost.MatchString(u.Hostname()) {
+		return nil, fmt.Errorf("untrusted signing cert url: %s", certUrl)
+	}
+
+	v.mu.Lock()
+	cert, ok := v.certs[certUrl]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", certUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MAX_WEBHOOK_BODY))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download signing cert: http status %d", resp.StatusCode)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("bad signing cert: no pem data")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	v.certs[certUrl] = cert
+	v.mu.Unlock()
+	return cert, nil
+}
+
+// signingString 构造SNS消息的待签名字符串
+// 返回:
+//   - string: 待签名字符串
+func (m SnsMessage) signingString() string {
+	fields := []string{"Message", m.Message, "MessageId", m.MessageId}
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			fields = append(fields, "Subject", m.Subject)
+		}
+	} else {
+		fields = append(fields, "SubscribeURL", m.SubscribeURL)
+	}
+	fields = append(fields, "Timestamp", m.Timestamp)
+	if m.Type != "Notification" {
+		fields = append(fields, "Token", m.Token)
+	}
+	fields = append(fields, "TopicArn", m.TopicArn, "Type", m.Type)
+
+	return strings.Join(fields, "\n") + "\n"
+}
@@ -1,100 +1,371 @@
-// Package sms 微软Azure通信服务短信实现
-package sms
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/http"
-)
-
-// ACSClient Azure通信服务短信客户端
-// 封装Azure通信服务短信API调用
-type ACSClient struct {
-	AccessToken string // 访问令牌
-	Endpoint    string // 服务端点
-	Message     string // 短信内容
-	Sender      string // 发送方号码
-}
-
-// reqBody 短信发送请求体
-type reqBody struct {
-	From          string         `json:"from"`          // 发送方
-	Message       string         `json:"message"`       // 短信内容
-	SMSRecipients []smsRecipient `json:"smsRecipients"` // 接收方列表
-}
-
-// smsRecipient 短信接收方
-type smsRecipient struct {
-	To string `json:"to"` // 接收方号码
-}
-
-// GetACSClient 创建Azure通信服务短信客户端
-// 参数:
-//   - accessToken: Azure访问令牌
-//   - message: 短信内容
-//   - other: 其他参数（[0]为端点，[1]为发送方号码）
-// 返回:
-//   - *ACSClient: Azure通信服务短信客户端实例
-//   - error: 错误信息
-func GetACSClient(accessToken string, message string, other []string) (*ACSClient, error) {
-	if len(other) < 2 {
-		return nil, fmt.Errorf("missing parameter: endpoint or sender")
-	}
-
-	acsClient := &ACSClient{
-		AccessToken: accessToken,
-		Endpoint:    other[0],
-		Message:     message,
-		Sender:      other[1],
-	}
-
-	return acsClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（当前未使用）
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (a *ACSClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	reqBody := &reqBody{
-		From:          a.Sender,
-		Message:       a.Message,
-		SMSRecipients: make([]smsRecipient, 0),
-	}
-	for _, mobile := range targetPhoneNumber {
-		reqBody.SMSRecipients = append(reqBody.SMSRecipients, smsRecipient{To: mobile})
-	}
-
-	url := fmt.Sprintf("%s/sms?api-version=2021-03-07", a.Endpoint)
-
-	client := &http.Client{}
-
-	requestBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error creating request body: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Add("Authorization", "Bearer "+a.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
-	}
-
-	resp.Body.Close()
-
-	return nil
-}
+// Package sms 微软Azure通信服务短信实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// azureNumberFormat Azure通信服务要求的号码格式（E.164）
+var azureNumberFormat = numberFormat{domestic: phone.FORMAT_E164}
+
+// ACSClient Azure通信服务短信客户端
+// 封装Azure通信服务短信API调用
+type ACSClient struct {
+	AccessToken    string       // 访问令牌
+	Endpoint       string       // 服务端点
+	Message        string       // 短信内容，通过SetMessageTemplate设置时为短信内容模板
+	Sender         string       // 发送方号码
+	httpClient     *http.Client // HTTP客户端
+	deliveryReport bool         // 是否开启送达报告
+	renderMode     RenderMode   // 短信内容模板的渲染方式，为空时原样发送短信内容
+}
+
+// reqBody 短信发送请求体
+type reqBody struct {
+	From           string          `json:"from"`                     // 发送方
+	Message        string          `json:"message"`                  // 短信内容
+	SMSRecipients  []smsRecipient  `json:"smsRecipients"`            // 接收方列表
+	SMSSendOptions *smsSendOptions `json:"smsSendOptions,omitempty"` // 发送选项
+}
+
+// smsSendOptions 短信发送选项
+type smsSendOptions struct {
+	EnableDeliveryReport bool `json:"enableDeliveryReport"` // 是否开启送达报告
+}
+
+// smsRecipient 短信接收方
+type smsRecipient struct {
+	To string `json:"to"` // 接收方号码
+}
+
+// acsRespBody 短信发送响应体
+type acsRespBody struct {
+	Value []acsRespItem `json:"value"` // 各接收方的发送结果
+}
+
+// acsRespItem 单个接收方的发送结果
+type acsRespItem struct {
+	To             string `json:"to"`             // 接收方号码
+	MessageId      string `json:"messageId"`      // 消息ID
+	HttpStatusCode int    `json:"httpStatusCode"` // HTTP状态码
+	Successful     bool   `json:"successful"`     // 是否成功
+	ErrorMessage   string `json:"errorMessage"`   // 错误信息
+}
+
+// Azure Event Grid事件类型
+const (
+	AZURE_EVENT_SUBSCRIPTION_VALIDATION = "Microsoft.EventGrid.SubscriptionValidationEvent"   // 订阅验证事件
+	AZURE_EVENT_DELIVERY_REPORT         = "Microsoft.Communication.SMSDeliveryReportReceived" // 短信送达报告事件
+)
+
+// AzureEvent Azure Event Grid事件
+type AzureEvent struct {
+	Id        string          `json:"id"`        // 事件ID
+	Topic     string          `json:"topic"`     // 事件源
+	Subject   string          `json:"subject"`   // 事件主题
+	EventType string          `json:"eventType"` // 事件类型
+	EventTime string          `json:"eventTime"` // 事件时间
+	Data      json.RawMessage `json:"data"`      // 事件数据
+}
+
+// AzureDeliveryReport Azure短信送达报告事件数据
+type AzureDeliveryReport struct {
+	MessageId             string `json:"messageId"`             // 消息ID
+	From                  string `json:"from"`                  // 发送方号码
+	To                    string `json:"to"`                    // 接收方号码
+	DeliveryStatus        string `json:"deliveryStatus"`        // 送达状态（Delivered、Failed）
+	DeliveryStatusDetails string `json:"deliveryStatusDetails"` // 送达状态详情
+	ReceivedTimestamp     string `json:"receivedTimestamp"`     // 送达报告接收时间
+	DeliveryAttempts      []struct {
+		Timestamp string `json:"timestamp"` // 发送时间
+	} `json:"deliveryAttempts"` // 发送尝试
+}
+
+// init 注册微软Azure通信服务短信
+func init() {
+	Register(SMS_AZURE, func(config ProviderConfig) (SmsProvider, error) {
+		return GetACSClient(config.AccessKey, config.Template, config.otherArgs(config.Endpoint, config.Sender))
+	}, "AccessKey", "Template", "Endpoint", "Sender")
+}
+
+// GetACSClient 创建Azure通信服务短信客户端
+// 参数:
+//   - accessToken: Azure访问令牌
+//   - message: 短信内容
+//   - other: 其他参数（[0]为端点，[1]为发送方号码）
+// 返回:
+//   - *ACSClient: Azure通信服务短信客户端实例
+//   - error: 错误信息
+func GetACSClient(accessToken string, message string, other []string) (*ACSClient, error) {
+	if len(other) < 2 {
+		return nil, fmt.Errorf("missing parameter: endpoint or sender")
+	}
+
+	acsClient := &ACSClient{
+		AccessToken: accessToken,
+		Endpoint:    other[0],
+		Message:     message,
+		Sender:      other[1],
+		httpClient:  &http.Client{},
+	}
+
+	return acsClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (a *ACSClient) SetHTTPClient(client *http.Client) {
+	a.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (a *ACSClient) SetEndpoint(endpoint string) {
+	a.Endpoint = endpoint
+}
+
+// SetDeliveryReport 设置是否开启送达报告
+// 开启后Azure通过Event Grid推送短信送达报告（需在Azure门户中创建Event Grid订阅）
+// 参数:
+//   - enabled: 是否开启送达报告
+func (a *ACSClient) SetDeliveryReport(enabled bool) {
+	a.deliveryReport = enabled
+}
+
+// SetMessageTemplate 设置短信内容模板
+// 未设置时原样发送创建客户端时传入的短信内容
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式，为空时使用RENDER_PLACEHOLDER
+func (a *ACSClient) SetMessageTemplate(text string, mode RenderMode) {
+	if mode == "" {
+		mode = RENDER_PLACEHOLDER
+	}
+	a.Message = text
+	a.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（设置短信内容模板后填充其中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (a *ACSClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := a.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（设置短信内容模板后填充其中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (a *ACSClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	message := a.Message
+	if a.renderMode != "" {
+		rendered, err := RenderMessage(a.Message, a.renderMode, param)
+		if err != nil {
+			return nil, err
+		}
+		message = rendered
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := azureNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody := &reqBody{
+		From:          a.Sender,
+		Message:       message,
+		SMSRecipients: make([]smsRecipient, 0),
+	}
+	for _, mobile := range phoneNumbers {
+		reqBody.SMSRecipients = append(reqBody.SMSRecipients, smsRecipient{To: mobile})
+	}
+	if a.deliveryReport {
+		reqBody.SMSSendOptions = &smsSendOptions{EnableDeliveryReport: true}
+	}
+
+	url := fmt.Sprintf("%s/sms?api-version=2021-03-07", a.Endpoint)
+
+	requestBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Add("Authorization", "Bearer "+a.AccessToken)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	result := newSendResult(SMS_AZURE)
+	result.Raw = string(body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newHttpStatusError(SMS_AZURE, resp.StatusCode, string(body))
+	}
+
+	var respBody acsRespBody
+	if err = json.Unmarshal(body, &respBody); err != nil {
+		return result, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	errs := []error{}
+	for _, item := range respBody.Value {
+		result.add(&RecipientResult{
+			PhoneNumber: item.To,
+			MessageId:   item.MessageId,
+			Accepted:    item.Successful,
+			Code:        strconv.Itoa(item.HttpStatusCode),
+			Message:     item.ErrorMessage,
+		})
+		if !item.Successful {
+			smsErr := newHttpStatusError(SMS_AZURE, item.HttpStatusCode, item.ErrorMessage).forNumber(item.To)
+			// 单个接收方返回400表示号码格式无效
+			if item.HttpStatusCode == http.StatusBadRequest {
+				smsErr.Err = ErrInvalidNumber
+			}
+			errs = append(errs, smsErr)
+		}
+	}
+
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	return result, nil
+}
+
+// parseAzureReport 解析Azure Event Grid推送的短信送达报告
+// 非短信送达报告的事件会被忽略
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseAzureReport(r *http.Request) ([]*DeliveryReport, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []AzureEvent
+	if err = json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("bad callback body: %w", err)
+	}
+
+	reports := make([]*DeliveryReport, 0, len(events))
+	for _, event := range events {
+		if event.EventType != AZURE_EVENT_DELIVERY_REPORT {
+			continue
+		}
+
+		var data AzureDeliveryReport
+		if err = json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("bad callback body: %w", err)
+		}
+
+		report := &DeliveryReport{
+			MessageId:   data.MessageId,
+			PhoneNumber: data.To,
+			Status:      DELIVERY_UNKNOWN,
+			Code:        data.DeliveryStatus,
+			Message:     data.DeliveryStatusDetails,
+			DeliveredAt: parseTime(time.RFC3339Nano, data.ReceivedTimestamp, time.UTC),
+			Raw:         string(event.Data),
+		}
+		if len(data.DeliveryAttempts) > 0 {
+			report.SentAt = parseTime(time.RFC3339Nano, data.DeliveryAttempts[0].Timestamp, time.UTC)
+		}
+		switch data.DeliveryStatus {
+		case "Delivered":
+			report.Status = DELIVERY_DELIVERED
+		case "Failed":
+			report.Status = DELIVERY_FAILED
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// azureEventGridHandshake 处理Azure Event Grid的订阅验证请求
+// 创建Event Grid订阅时，Azure会发送订阅验证事件，需返回其中的validationCode
+// 参数:
+//   - w: HTTP响应
+//   - r: HTTP请求
+// 返回:
+//   - bool: 请求是否为订阅验证请求
+func azureEventGridHandshake(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("aeg-event-type") != "SubscriptionValidation" {
+		return false
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+
+	var events []AzureEvent
+	if err = json.Unmarshal(body, &events); err != nil {
+		http.Error(w, fmt.Sprintf("bad callback body: %v", err), http.StatusBadRequest)
+		return true
+	}
+
+	for _, event := range events {
+		if event.EventType != AZURE_EVENT_SUBSCRIPTION_VALIDATION {
+			continue
+		}
+
+		var data struct {
+			ValidationCode string `json:"validationCode"` // 验证码
+		}
+		if err = json.Unmarshal(event.Data, &data); err != nil || data.ValidationCode == "" {
+			break
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"validationResponse": data.ValidationCode})
+		return true
+	}
+
+	http.Error(w, "bad callback body: missing validationCode", http.StatusBadRequest)
+	return true
+}
@@ -1,81 +1,310 @@
-// Package sms 百度云短信服务实现
-package sms
-
-import (
-	"fmt"
-	"strings"
-
-	"github.com/baidubce/bce-sdk-go/services/sms"
-	"github.com/baidubce/bce-sdk-go/services/sms/api"
-)
-
-// BaiduClient 百度云短信客户端
-// 封装百度云短信API调用
-type BaiduClient struct {
-	sign     string      // 短信签名ID
-	template string      // 短信模板ID
-	core     *sms.Client // 百度云SMS客户端
-}
-
-// GetBceClient 创建百度云短信客户端
-// 参数:
-//   - accessId: 百度云访问ID
-//   - accessKey: 百度云访问密钥
-//   - sign: 短信签名ID
-//   - template: 短信模板ID
-//   - endpoint: 服务端点列表
-// 返回:
-//   - *BaiduClient: 百度云短信客户端实例
-//   - error: 错误信息
-func GetBceClient(accessId, accessKey, sign, template string, endpoint []string) (*BaiduClient, error) {
-	if len(endpoint) == 0 {
-		return nil, fmt.Errorf("missing parameter: endpoint")
-	}
-
-	client, err := sms.NewClient(accessId, accessKey, endpoint[0])
-	if err != nil {
-		return nil, err
-	}
-
-	bceClient := &BaiduClient{
-		sign:     sign,
-		template: template,
-		core:     client,
-	}
-
-	return bceClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *BaiduClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	contentMap := make(map[string]interface{})
-	contentMap["code"] = code
-
-	sendSmsArgs := &api.SendSmsArgs{
-		Mobile:      strings.Join(targetPhoneNumber, ","),
-		SignatureId: c.sign,
-		Template:    c.template,
-		ContentVar:  contentMap,
-	}
-
-	_, err := c.core.SendSms(sendSmsArgs)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+// Package sms 百度云短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+	"github.com/baidubce/bce-sdk-go/services/sms"
+	"github.com/baidubce/bce-sdk-go/services/sms/api"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// baiduSuccessCode 百度云短信发送成功状态码
+const baiduSuccessCode = "1000"
+
+// baiduNumberFormat 百度云要求的号码格式
+// 国内号码不带国家代码，国际/港澳台号码为E.164格式
+var baiduNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL, international: phone.FORMAT_E164}
+
+// baiduErrorCodes 百度云错误码与标准错误的对应关系
+var baiduErrorCodes = map[string]error{
+	"AccessDenied":          ErrAuthFailed,
+	"InvalidAccessKeyId":    ErrAuthFailed,
+	"SignatureDoesNotMatch": ErrAuthFailed,
+	"RequestExpired":        ErrAuthFailed,
+	"InternalError":         ErrServiceUnavailable,
+	"ServiceUnavailable":    ErrServiceUnavailable,
+}
+
+// baiduSignContentTypes 标准签名来源与百度云签名类型的对应关系
+var baiduSignContentTypes = map[SignSource]string{
+	SIGN_COMPANY:   "Enterprise",   // 企业
+	SIGN_APP:       "MobileApp",    // 移动应用
+	SIGN_WEBSITE:   "Web",          // 网站
+	SIGN_WECHAT:    "WeChatPublic", // 微信公众号
+	SIGN_TRADEMARK: "Brand",        // 商标
+}
+
+// baiduReviewStatuses 百度云签名审核状态与标准审核状态的对应关系
+var baiduReviewStatuses = map[string]ReviewStatus{
+	"SUBMITTED": REVIEW_PENDING,
+	"AUDITING":  REVIEW_PENDING,
+	"READY":     REVIEW_APPROVED,
+	"REJECTED":  REVIEW_REJECTED,
+}
+
+// BaiduClient 百度云短信客户端
+// 封装百度云短信API调用
+type BaiduClient struct {
+	sign     string      // 短信签名ID
+	template string      // 短信模板ID
+	core     *sms.Client // 百度云SMS客户端
+}
+
+// init 注册百度云短信服务
+func init() {
+	Register(SMS_BAIdU, func(config ProviderConfig) (SmsProvider, error) {
+		return GetBceClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.Endpoint))
+	}, "AccessId", "AccessKey", "Sign", "Template", "Endpoint")
+}
+
+// GetBceClient 创建百度云短信客户端
+// 参数:
+//   - accessId: 百度云访问ID
+//   - accessKey: 百度云访问密钥
+//   - sign: 短信签名ID
+//   - template: 短信模板ID
+//   - endpoint: 服务端点列表
+// 返回:
+//   - *BaiduClient: 百度云短信客户端实例
+//   - error: 错误信息
+func GetBceClient(accessId, accessKey, sign, template string, endpoint []string) (*BaiduClient, error) {
+	if len(endpoint) == 0 {
+		return nil, fmt.Errorf("missing parameter: endpoint")
+	}
+
+	client, err := sms.NewClient(accessId, accessKey, endpoint[0])
+	if err != nil {
+		return nil, err
+	}
+
+	bceClient := &BaiduClient{
+		sign:     sign,
+		template: template,
+		core:     client,
+	}
+
+	return bceClient, nil
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *BaiduClient) SetEndpoint(endpoint string) {
+	c.core.Config.Endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *BaiduClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *BaiduClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	code, ok := param["code"]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter: code")
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := baiduNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	contentMap := make(map[string]interface{})
+	contentMap["code"] = code
+
+	sendSmsArgs := &api.SendSmsArgs{
+		Mobile:      strings.Join(phoneNumbers, ","),
+		SignatureId: c.sign,
+		Template:    c.template,
+		ContentVar:  contentMap,
+	}
+
+	response, err := callWithContext(ctx, func() (*api.SendSmsResult, error) {
+		return c.core.SendSms(sendSmsArgs)
+	})
+	if err != nil {
+		return nil, baiduSdkError(err)
+	}
+
+	result := newSendResult(SMS_BAIdU)
+	result.RequestId = response.RequestId
+	if raw, err := json.Marshal(response); err == nil {
+		result.Raw = string(raw)
+	}
+
+	errs := []error{}
+	for _, item := range response.Data {
+		result.add(&RecipientResult{
+			PhoneNumber: item.Mobile,
+			MessageId:   item.MessageId,
+			Accepted:    item.Code == baiduSuccessCode,
+			Code:        item.Code,
+			Message:     item.Message,
+		})
+		if item.Code != baiduSuccessCode {
+			errs = append(errs, newSmsError(SMS_BAIdU, item.Code, item.Message, baiduErrorCodes).forNumber(item.Mobile))
+		}
+	}
+
+	if response.Code != baiduSuccessCode && len(errs) == 0 {
+		errs = append(errs, newSmsError(SMS_BAIdU, response.Code, response.Message, baiduErrorCodes))
+	}
+
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	return result, nil
+}
+
+// CreateSign 创建短信签名并提交审核
+// 通过CreateSignature接口创建
+// 参数:
+//   - ctx: 上下文
+//   - sign: 创建短信签名的请求
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息
+func (c *BaiduClient) CreateSign(ctx context.Context, sign SignRequest) (*Sign, error) {
+	contentType, ok := baiduSignContentTypes[sign.Source]
+	if !ok {
+		return nil, fmt.Errorf("bad parameter: unsupported sign source %q", sign.Source)
+	}
+	countryType := "DOMESTIC"
+	if sign.International {
+		countryType = "INTERNATIONAL"
+	}
+
+	args := &api.CreateSignatureArgs{
+		Content:     sign.Name,
+		ContentType: contentType,
+		Description: sign.Remark,
+		CountryType: countryType,
+	}
+	if len(sign.Proof) > 0 {
+		args.SignatureFileBase64 = base64.StdEncoding.EncodeToString(sign.Proof)
+		args.SignatureFileFormat = sign.ProofFormat
+	}
+
+	response, err := callWithContext(ctx, func() (*api.CreateSignatureResult, error) {
+		return c.core.CreateSignature(args)
+	})
+	if err != nil {
+		return nil, baiduSdkError(err)
+	}
+
+	created := &Sign{
+		Provider:      SMS_BAIdU,
+		SignId:        response.SignatureId,
+		Name:          sign.Name,
+		International: sign.International,
+		Status:        REVIEW_PENDING,
+	}
+	if reviewStatus, ok := baiduReviewStatuses[response.Status]; ok {
+		created.Status = reviewStatus
+	}
+	if raw, err := json.Marshal(response); err == nil {
+		created.Raw = string(raw)
+	}
+	return created, nil
+}
+
+// ListSigns 查询全部短信签名
+// 百度云SDK未提供查询签名列表的接口
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - []*Sign: 始终为空
+//   - error: errors.ErrUnsupported
+func (c *BaiduClient) ListSigns(ctx context.Context) ([]*Sign, error) {
+	return nil, fmt.Errorf("%w: baidu cloud can only query signs by id", errors.ErrUnsupported)
+}
+
+// GetSign 查询短信签名及其审核状态
+// 通过GetSignature接口查询
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名ID
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息
+func (c *BaiduClient) GetSign(ctx context.Context, signId string) (*Sign, error) {
+	response, err := callWithContext(ctx, func() (*api.GetSignatureResult, error) {
+		return c.core.GetSignature(&api.GetSignatureArgs{SignatureId: signId})
+	})
+	if err != nil {
+		return nil, baiduSdkError(err)
+	}
+
+	sign := &Sign{
+		Provider:      SMS_BAIdU,
+		SignId:        response.SignatureId,
+		Name:          response.Content,
+		International: response.CountryType != "DOMESTIC",
+		Status:        REVIEW_UNKNOWN,
+		Reason:        response.Review,
+	}
+	if reviewStatus, ok := baiduReviewStatuses[response.Status]; ok {
+		sign.Status = reviewStatus
+	}
+	if raw, err := json.Marshal(response); err == nil {
+		sign.Raw = string(raw)
+	}
+	return sign, nil
+}
+
+// DeleteSign 删除短信签名
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名ID
+// 返回:
+//   - error: 错误信息
+func (c *BaiduClient) DeleteSign(ctx context.Context, signId string) error {
+	_, err := callWithContext(ctx, func() (struct{}, error) {
+		return struct{}{}, c.core.DeleteSignature(&api.DeleteSignatureArgs{SignatureId: signId})
+	})
+	if err != nil {
+		return baiduSdkError(err)
+	}
+	return nil
+}
+
+// baiduSdkError 将百度云SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func baiduSdkError(err error) error {
+	var serviceErr *bce.BceServiceError
+	if errors.As(err, &serviceErr) {
+		return newSmsError(SMS_BAIdU, serviceErr.Code, serviceErr.Message, baiduErrorCodes).withStatus(serviceErr.StatusCode)
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+// Package sms 账户余额查询
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DEFAULT_BALANCE_INTERVAL 默认余额检查间隔
+const DEFAULT_BALANCE_INTERVAL = 10 * time.Minute
+
+// 余额单位常量定义，以货币计价的余额使用ISO 4217货币代码（如"TJS"）
+const (
+	BALANCE_UNIT_MESSAGES = "messages" // 剩余短信条数
+	BALANCE_UNIT_CREDITS  = "credits"  // 服务商积分
+)
+
+// Balance 标准化的账户余额
+type Balance struct {
+	Provider string  // 服务提供商类型
+	Amount   float64 // 余额
+	Unit     string  // 余额单位（BALANCE_UNIT_*常量或货币代码）
+	Raw      string  // 服务商原始响应
+}
+
+// BalanceCallback 余额告警回调函数
+// 在检查余额的goroutine中同步调用，耗时操作应自行异步处理
+type BalanceCallback func(balance *Balance)
+
+// BalanceChecker 支持查询账户余额的短信服务提供商
+// 目前支持短信宝、互亿无线、SUBMAIL、Msg91、Netgsm和OSON
+type BalanceChecker interface {
+	// QueryBalance 查询账户余额
+	// 参数:
+	//   - ctx: 上下文
+	// 返回:
+	//   - *Balance: 账户余额
+	//   - error: 错误信息
+	QueryBalance(ctx context.Context) (*Balance, error)
+}
+
+// QueryBalance 查询短信服务提供商的账户余额
+// 参数:
+//   - ctx: 上下文
+//   - provider: 短信服务提供商实例
+// 返回:
+//   - *Balance: 账户余额
+//   - error: 错误信息，服务商不支持查询余额时返回错误
+func QueryBalance(ctx context.Context, provider SmsProvider) (*Balance, error) {
+	checker, ok := provider.(BalanceChecker)
+	if !ok {
+		return nil, fmt.Errorf("provider does not support balance query: %T", provider)
+	}
+
+	return checker.QueryBalance(ctx)
+}
+
+// WatchBalance 定期检查账户余额，余额低于阈值时调用回调函数，直到上下文结束
+// 余额降至阈值以下时只告警一次，回升到阈值及以上后重新开始检查
+// 查询返回可重试的错误（见IsRetryable）时在下一个周期重试，其他错误直接返回
+// 参数:
+//   - ctx: 上下文
+//   - provider: 短信服务提供商实例
+//   - interval: 检查间隔，小于等于0时使用DEFAULT_BALANCE_INTERVAL
+//   - threshold: 告警阈值，单位与服务商返回的余额单位相同
+//   - callback: 余额告警回调函数
+// 返回:
+//   - error: 上下文结束时返回上下文错误，服务商不支持查询或查询失败时返回错误
+func WatchBalance(ctx context.Context, provider SmsProvider, interval time.Duration, threshold float64, callback BalanceCallback) error {
+	checker, ok := provider.(BalanceChecker)
+	if !ok {
+		return fmt.Errorf("provider does not support balance query: %T", provider)
+	}
+	if callback == nil {
+		return fmt.Errorf("missing parameter: callback")
+	}
+	if interval <= 0 {
+		interval = DEFAULT_BALANCE_INTERVAL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	alerted := false
+	for {
+		balance, err := checker.QueryBalance(ctx)
+		if err != nil && ctx.Err() == nil && !IsRetryable(err) {
+			return err
+		}
+		if err == nil {
+			if balance.Amount < threshold && !alerted {
+				callback(balance)
+			}
+			alerted = balance.Amount < threshold
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package sms 加权负载均衡短信服务提供商
+package sms
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// 负载均衡健康检查默认值
+const (
+	DEFAULT_MAX_FAILURES = 3                // 连续失败多少次后暂时摘除后端
+	DEFAULT_COOLDOWN     = 30 * time.Second // 后端被摘除的时长
+)
+
+// WeightedProvider 带权重的短信服务提供商
+type WeightedProvider struct {
+	Provider SmsProvider // 短信服务提供商
+	Weight   int         // 权重，按权重比例分配发送流量
+}
+
+// BackendStatus 负载均衡后端状态
+type BackendStatus struct {
+	Provider  SmsProvider // 短信服务提供商
+	Weight    int         // 权重
+	Healthy   bool        // 是否健康
+	Failures  int         // 连续失败次数
+	DownUntil time.Time   // 摘除截止时间，健康时为零值
+}
+
+// LoadBalancer 加权负载均衡短信服务提供商
+// 按权重随机选择一个后端发送短信；后端连续返回可重试错误（见IsRetryable）达到阈值后，
+// 在冷却时间内不再被选择，冷却结束后重新参与选择，发送成功即恢复健康
+type LoadBalancer struct {
+	mu          sync.Mutex    // 后端状态锁
+	backends    []*lbBackend  // 后端列表
+	maxFailures int           // 连续失败阈值
+	cooldown    time.Duration // 冷却时间
+}
+
+// lbBackend 负载均衡后端
+type lbBackend struct {
+	provider  SmsProvider // 短信服务提供商
+	weight    int         // 权重
+	failures  int         // 连续失败次数
+	downUntil time.Time   // 摘除截止时间
+}
+
+// 确保LoadBalancer实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &LoadBalancer{}
+
+// NewLoadBalancer 创建加权负载均衡短信服务提供商
+// 参数:
+//   - providers: 带权重的服务提供商列表，权重必须大于0
+// 返回:
+//   - *LoadBalancer: 负载均衡短信服务提供商实例
+//   - error: 错误信息
+func NewLoadBalancer(providers ...WeightedProvider) (*LoadBalancer, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("missing parameter: providers")
+	}
+
+	backends := make([]*lbBackend, 0, len(providers))
+	for i, provider := range providers {
+		if provider.Provider == nil {
+			return nil, fmt.Errorf("bad parameter: providers[%d] is nil", i)
+		}
+		if provider.Weight <= 0 {
+			return nil, fmt.Errorf("bad parameter: providers[%d] weight must be positive", i)
+		}
+		backends = append(backends, &lbBackend{
+			provider: provider.Provider,
+			weight:   provider.Weight,
+		})
+	}
+
+	return &LoadBalancer{
+		backends:    backends,
+		maxFailures: DEFAULT_MAX_FAILURES,
+		cooldown:    DEFAULT_COOLDOWN,
+	}, nil
+}
+
+// SetHealthCheck 设置健康检查参数
+// 参数:
+//   - maxFailures: 连续失败多少次后摘除后端，小于等于0时不摘除
+//   - cooldown: 后端被摘除的时长
+func (b *LoadBalancer) SetHealthCheck(maxFailures int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maxFailures = maxFailures
+	b.cooldown = cooldown
+}
+
+// Status 获取各后端的状态
+// 返回:
+//   - []BackendStatus: 按创建顺序排列的后端状态
+func (b *LoadBalancer) Status() []BackendStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	status := make([]BackendStatus, 0, len(b.backends))
+	for _, backend := range b.backends {
+		item := BackendStatus{
+			Provider: backend.provider,
+			Weight:   backend.weight,
+			Healthy:  backend.healthy(now),
+			Failures: backend.failures,
+		}
+		if !item.Healthy {
+			item.DownUntil = backend.downUntil
+		}
+		status = append(status, item)
+	}
+	return status
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (b *LoadBalancer) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := b.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 所有后端均被摘除时，仍按权重从全部后端中选择
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果，Provider为实际发送的服务商
+//   - error: 错误信息
+func (b *LoadBalancer) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	backend := b.pick()
+
+	result, err := SendMessageContext(ctx, backend.provider, param, targetPhoneNumber...)
+	b.report(ctx, backend, err)
+
+	return result, err
+}
+
+// pick 按权重随机选择后端
+// 返回:
+//   - *lbBackend: 选中的后端
+func (b *LoadBalancer) pick() *lbBackend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]*lbBackend, 0, len(b.backends))
+	total := 0
+	for _, backend := range b.backends {
+		if backend.healthy(now) {
+			candidates = append(candidates, backend)
+			total += backend.weight
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = b.backends
+		for _, backend := range candidates {
+			total += backend.weight
+		}
+	}
+
+	n := rand.IntN(total)
+	for _, backend := range candidates {
+		if n < backend.weight {
+			return backend
+		}
+		n -= backend.weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+// report 记录后端的发送结果
+// 只有可重试的错误计入失败次数，号码无效等业务错误不影响后端健康状态；
+// 调用方取消或超时导致的失败不说明后端不可用，不计入
+// 参数:
+//   - ctx: 发送时的上下文
+//   - backend: 后端
+//   - err: 发送错误
+func (b *LoadBalancer) report(ctx context.Context, backend *lbBackend, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		backend.failures = 0
+		backend.downUntil = time.Time{}
+		return
+	}
+	if ctx.Err() != nil || !IsRetryable(err) {
+		return
+	}
+
+	backend.failures++
+	if b.maxFailures > 0 && backend.failures >= b.maxFailures {
+		backend.downUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// healthy 判断后端是否健康
+// 服务提供商实现了HealthChecker时（如CircuitBreaker），同时参考其健康状态
+// 参数:
+//   - now: 当前时间
+// 返回:
+//   - bool: 是否健康
+func (b *lbBackend) healthy(now time.Time) bool {
+	if now.Before(b.downUntil) {
+		return false
+	}
+	if checker, ok := b.provider.(HealthChecker); ok {
+		return checker.Healthy()
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package sms
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// checkedProvider 实现了HealthChecker的测试用短信服务提供商
+type checkedProvider struct {
+	stubProvider
+	healthy bool // 是否健康
+}
+
+// Healthy 判断服务提供商是否健康
+func (p *checkedProvider) Healthy() bool {
+	return p.healthy
+}
+
+func TestLoadBalancerWeights(t *testing.T) {
+	heavy, light := &stubProvider{}, &stubProvider{}
+	balancer, err := NewLoadBalancer(WeightedProvider{Provider: heavy, Weight: 3}, WeightedProvider{Provider: light, Weight: 1})
+	if err != nil {
+		t.Fatalf("NewLoadBalancer() error = %v", err)
+	}
+
+	const sends = 4000
+	for i := 0; i < sends; i++ {
+		if err := balancer.SendMessage(nil, "+8613800138000"); err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+	if ratio := float64(heavy.calls) / sends; ratio < 0.7 || ratio > 0.8 {
+		t.Errorf("heavy backend got %.2f of sends, want about 0.75", ratio)
+	}
+}
+
+func TestLoadBalancerHealth(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		ctx         func() context.Context
+		wantHealthy bool
+	}{
+		{"retryable errors remove backend", ErrServiceUnavailable, context.Background, false},
+		{"non-retryable errors keep backend", ErrInvalidNumber, context.Background, true},
+		{"client-side rate limit keeps backend", &RateLimitError{PhoneNumber: "+8613800138000"}, context.Background, true},
+		{"caller cancellation keeps backend", nil, func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing, healthy := &stubProvider{err: tt.err}, &stubProvider{}
+			balancer, err := NewLoadBalancer(WeightedProvider{Provider: failing, Weight: 1}, WeightedProvider{Provider: healthy, Weight: 1})
+			if err != nil {
+				t.Fatalf("NewLoadBalancer() error = %v", err)
+			}
+			balancer.SetHealthCheck(2, time.Hour)
+
+			for i := 0; i < 100; i++ {
+				balancer.SendMessageContext(tt.ctx(), nil, "+8613800138000")
+			}
+			if got := balancer.Status()[0].Healthy; got != tt.wantHealthy {
+				t.Errorf("Healthy = %v, want %v", got, tt.wantHealthy)
+			}
+			if !tt.wantHealthy && failing.calls != 2 {
+				t.Errorf("failing backend called %d times, want 2 before removal", failing.calls)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerRecovery(t *testing.T) {
+	failing := &stubProvider{err: ErrServiceUnavailable}
+	balancer, err := NewLoadBalancer(WeightedProvider{Provider: failing, Weight: 1})
+	if err != nil {
+		t.Fatalf("NewLoadBalancer() error = %v", err)
+	}
+	balancer.SetHealthCheck(1, time.Millisecond)
+
+	balancer.SendMessage(nil, "+8613800138000")
+	if balancer.Status()[0].Healthy {
+		t.Fatal("Healthy = true after failure, want false")
+	}
+
+	// 所有后端都不健康时仍然发送
+	balancer.SendMessage(nil, "+8613800138000")
+	if failing.calls != 2 {
+		t.Errorf("backend called %d times, want 2", failing.calls)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	failing.err = nil
+	if err := balancer.SendMessage(nil, "+8613800138000"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if status := balancer.Status()[0]; !status.Healthy || status.Failures != 0 {
+		t.Errorf("Status() = %+v, want healthy with no failures", status)
+	}
+}
+
+func TestLoadBalancerHealthChecker(t *testing.T) {
+	open := &checkedProvider{healthy: false}
+	closed := &checkedProvider{healthy: true}
+	balancer, err := NewLoadBalancer(WeightedProvider{Provider: open, Weight: 100}, WeightedProvider{Provider: closed, Weight: 1})
+	if err != nil {
+		t.Fatalf("NewLoadBalancer() error = %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		balancer.SendMessage(nil, "+8613800138000")
+	}
+	if open.calls != 0 || closed.calls != 20 {
+		t.Errorf("calls = %d, %d, want 0, 20", open.calls, closed.calls)
+	}
+}
+
+func TestNewLoadBalancer(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []WeightedProvider
+	}{
+		{"no providers", nil},
+		{"nil provider", []WeightedProvider{{Weight: 1}}},
+		{"zero weight", []WeightedProvider{{Provider: &stubProvider{}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLoadBalancer(tt.providers...); err == nil {
+				t.Error("NewLoadBalancer() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// Package sms 短信服务提供商熔断器
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+// 熔断器状态常量定义
+const (
+	CIRCUIT_CLOSED    CircuitState = iota // 关闭：正常发送
+	CIRCUIT_OPEN                          // 打开：直接返回ErrCircuitOpen
+	CIRCUIT_HALF_OPEN                     // 半开：允许少量试探发送
+)
+
+// 熔断器默认值
+const (
+	DEFAULT_CIRCUIT_FAILURE_RATIO = 0.5              // 默认失败比例阈值
+	DEFAULT_CIRCUIT_MIN_REQUESTS  = 10               // 默认计算失败比例所需的最少请求数
+	DEFAULT_CIRCUIT_WINDOW        = time.Minute      // 默认统计窗口
+	DEFAULT_CIRCUIT_OPEN_TIMEOUT  = 30 * time.Second // 默认打开状态持续时间
+	DEFAULT_CIRCUIT_HALF_OPEN     = 1                // 默认半开状态的试探发送次数
+)
+
+// ErrCircuitOpen 熔断器打开时返回的错误
+// 同时满足errors.Is(err, ErrServiceUnavailable)，可被IsRetryable识别为可重试错误
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrServiceUnavailable)
+
+// HealthChecker 可报告健康状态的短信服务提供商
+// LoadBalancer选择后端时会跳过不健康的服务提供商
+type HealthChecker interface {
+	// Healthy 判断服务提供商是否健康
+	// 返回:
+	//   - bool: 是否健康
+	Healthy() bool
+}
+
+// CircuitBreakerPolicy 熔断策略
+type CircuitBreakerPolicy struct {
+	FailureRatio     float64       // 统计窗口内失败比例达到该值时打开熔断器，为0时使用默认值
+	MinRequests      int           // 统计窗口内请求数达到该值才计算失败比例，为0时使用默认值
+	Window           time.Duration // 统计窗口，为0时使用默认值
+	OpenTimeout      time.Duration // 打开状态持续时间，之后进入半开状态，为0时使用默认值
+	HalfOpenRequests int           // 半开状态允许的试探发送次数，全部成功后关闭熔断器，为0时使用默认值
+}
+
+// CircuitBreaker 带熔断器的短信服务提供商
+// 只有可重试的错误（见IsRetryable）计为失败；打开状态下直接返回ErrCircuitOpen，不再请求服务商
+type CircuitBreaker struct {
+	provider      SmsProvider                 // 被包装的服务提供商
+	policy        CircuitBreakerPolicy        // 熔断策略
+	mu            sync.Mutex                  // 熔断器状态锁
+	state         CircuitState                // 当前状态
+	windowStart   time.Time                   // 统计窗口开始时间
+	requests      int                         // 统计窗口内的请求数
+	failures      int                         // 统计窗口内的失败数
+	openedAt      time.Time                   // 打开时间
+	trials        int                         // 半开状态已放行的试探发送数
+	successes     int                         // 半开状态试探发送的成功数
+	onStateChange func(from, to CircuitState) // 状态变化回调
+}
+
+// 确保CircuitBreaker实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &CircuitBreaker{}
+
+// String 获取状态名称
+// 返回:
+//   - string: 状态名称
+func (s CircuitState) String() string {
+	switch s {
+	case CIRCUIT_CLOSED:
+		return "closed"
+	case CIRCUIT_OPEN:
+		return "open"
+	case CIRCUIT_HALF_OPEN:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// WithCircuitBreaker 为短信服务提供商添加熔断器
+// 参数:
+//   - provider: 短信服务提供商
+//   - policy: 熔断策略，未设置的字段使用默认值
+// 返回:
+//   - *CircuitBreaker: 带熔断器的短信服务提供商
+func WithCircuitBreaker(provider SmsProvider, policy CircuitBreakerPolicy) *CircuitBreaker {
+	if policy.FailureRatio <= 0 || policy.FailureRatio > 1 {
+		policy.FailureRatio = DEFAULT_CIRCUIT_FAILURE_RATIO
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = DEFAULT_CIRCUIT_MIN_REQUESTS
+	}
+	if policy.Window <= 0 {
+		policy.Window = DEFAULT_CIRCUIT_WINDOW
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = DEFAULT_CIRCUIT_OPEN_TIMEOUT
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = DEFAULT_CIRCUIT_HALF_OPEN
+	}
+
+	return &CircuitBreaker{
+		provider:    provider,
+		policy:      policy,
+		state:       CIRCUIT_CLOSED,
+		windowStart: time.Now(),
+	}
+}
+
+// SetStateChangeHandler 设置状态变化回调
+// 回调在持有熔断器锁时同步调用，不应执行耗时操作或调用熔断器的方法
+// 参数:
+//   - handler: 状态变化回调
+func (b *CircuitBreaker) SetStateChangeHandler(handler func(from, to CircuitState)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onStateChange = handler
+}
+
+// State 获取熔断器当前状态
+// 返回:
+//   - CircuitState: 当前状态
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(time.Now())
+	return b.state
+}
+
+// Healthy 判断服务提供商是否健康
+// 返回:
+//   - bool: 熔断器未打开时返回true
+func (b *CircuitBreaker) Healthy() bool {
+	return b.State() != CIRCUIT_OPEN
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (b *CircuitBreaker) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := b.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果，熔断器打开时为nil
+//   - error: 错误信息，熔断器打开时返回ErrCircuitOpen
+func (b *CircuitBreaker) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := SendMessageContext(ctx, b.provider, param, targetPhoneNumber...)
+	b.report(ctx, err)
+
+	return result, err
+}
+
+// allow 判断是否允许发送
+// 返回:
+//   - error: 不允许发送时返回ErrCircuitOpen
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.refresh(now)
+
+	switch b.state {
+	case CIRCUIT_OPEN:
+		return ErrCircuitOpen
+	case CIRCUIT_HALF_OPEN:
+		if b.trials >= b.policy.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.trials++
+	default:
+		if now.Sub(b.windowStart) >= b.policy.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+	}
+	return nil
+}
+
+// report 记录发送结果
+// 号码无效等不可重试的错误说明服务商可用，计为成功；调用方取消或超时导致失败的发送不计入
+// 参数:
+//   - ctx: 发送时的上下文
+//   - err: 发送错误
+func (b *CircuitBreaker) report(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		if b.state == CIRCUIT_HALF_OPEN && b.trials > b.successes {
+			b.trials--
+		}
+		return
+	}
+
+	failed := IsRetryable(err)
+	now := time.Now()
+
+	switch b.state {
+	case CIRCUIT_HALF_OPEN:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.policy.HalfOpenRequests {
+			b.setState(CIRCUIT_CLOSED)
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+	case CIRCUIT_CLOSED:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.policy.MinRequests && float64(b.failures) >= b.policy.FailureRatio*float64(b.requests) {
+			b.open(now)
+		}
+	}
+}
+
+// refresh 打开状态超时后进入半开状态
+// 参数:
+//   - now: 当前时间
+func (b *CircuitBreaker) refresh(now time.Time) {
+	if b.state == CIRCUIT_OPEN && now.Sub(b.openedAt) >= b.policy.OpenTimeout {
+		b.setState(CIRCUIT_HALF_OPEN)
+		b.trials = 0
+		b.successes = 0
+	}
+}
+
+// open 打开熔断器
+// 参数:
+//   - now: 当前时间
+func (b *CircuitBreaker) open(now time.Time) {
+	b.setState(CIRCUIT_OPEN)
+	b.openedAt = now
+}
+
+// setState 切换熔断器状态并触发回调
+// 参数:
+//   - state: 新状态
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
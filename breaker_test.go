@@ -0,0 +1,113 @@
+package sms
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// stubProvider 返回预设结果的测试用短信服务提供商
+type stubProvider struct {
+	err    error      // 发送返回的错误
+	accept []string   // 返回错误时仍受理的号码
+	calls  int        // 发送次数
+	sent   [][]string // 每次发送的号码
+}
+
+// SendMessage 发送短信
+func (s *stubProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := s.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 上下文已结束时返回上下文错误；无错误时所有号码标记为已受理，否则只受理accept中的号码并返回err
+func (s *stubProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	s.calls++
+	s.sent = append(s.sent, targetPhoneNumber)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := newSendResult(SMS_MOCK)
+	if s.err == nil {
+		result.acceptAll("", targetPhoneNumber)
+		return result, nil
+	}
+	for _, phoneNumber := range targetPhoneNumber {
+		result.add(&RecipientResult{PhoneNumber: phoneNumber, Accepted: slices.Contains(s.accept, phoneNumber)})
+	}
+	return result, s.err
+}
+
+func TestCircuitBreakerFailureAccounting(t *testing.T) {
+	expired := func() (context.Context, context.CancelFunc) {
+		return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	}
+	canceled := func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx, cancel
+	}
+	background := func() (context.Context, context.CancelFunc) {
+		return context.Background(), func() {}
+	}
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		err  error
+		want CircuitState
+	}{
+		{"service unavailable", background, ErrServiceUnavailable, CIRCUIT_OPEN},
+		{"rate limited by provider", background, ErrRateLimited, CIRCUIT_OPEN},
+		{"network error", background, &net.OpError{Op: "dial", Err: context.DeadlineExceeded}, CIRCUIT_OPEN},
+		{"invalid number", background, ErrInvalidNumber, CIRCUIT_CLOSED},
+		{"success", background, nil, CIRCUIT_CLOSED},
+		{"caller deadline exceeded", expired, nil, CIRCUIT_CLOSED},
+		{"caller canceled", canceled, nil, CIRCUIT_CLOSED},
+		{"client-side rate limit", background, &RateLimitError{PhoneNumber: "+8613800138000", RetryAfter: time.Minute}, CIRCUIT_CLOSED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := WithCircuitBreaker(&stubProvider{err: tt.err}, CircuitBreakerPolicy{MinRequests: 3})
+			for i := 0; i < 3; i++ {
+				ctx, cancel := tt.ctx()
+				breaker.SendMessageContext(ctx, nil, "+8613800138000")
+				cancel()
+			}
+			if got := breaker.State(); got != tt.want {
+				t.Errorf("State() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	provider := &stubProvider{err: ErrServiceUnavailable}
+	breaker := WithCircuitBreaker(provider, CircuitBreakerPolicy{MinRequests: 1, OpenTimeout: time.Millisecond})
+
+	breaker.SendMessage(nil, "+8613800138000")
+	if got := breaker.State(); got != CIRCUIT_OPEN {
+		t.Fatalf("State() = %v, want %v", got, CIRCUIT_OPEN)
+	}
+	if _, err := breaker.SendMessageContext(context.Background(), nil, "+8613800138000"); err != ErrCircuitOpen {
+		t.Fatalf("SendMessageContext() error = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if got := breaker.State(); got != CIRCUIT_HALF_OPEN {
+		t.Fatalf("State() = %v, want %v", got, CIRCUIT_HALF_OPEN)
+	}
+
+	provider.err = nil
+	if err := breaker.SendMessage(nil, "+8613800138000"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if got := breaker.State(); got != CIRCUIT_CLOSED {
+		t.Errorf("State() = %v, want %v", got, CIRCUIT_CLOSED)
+	}
+}
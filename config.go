@@ -0,0 +1,263 @@
+// Package sms 短信服务提供商配置
+package sms
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ProviderConfig 短信服务提供商配置
+// 用具名字段代替NewSmsProvider中按位置传入的other参数，各字段的适用服务商见字段说明
+type ProviderConfig struct {
+	Provider  string `json:"provider" yaml:"provider"`   // 服务提供商类型（SMS_*常量）
+	AccessId  string `json:"accessId" yaml:"accessId"`   // 访问ID（Twilio为账户SID，Azure不使用）
+	AccessKey string `json:"accessKey" yaml:"accessKey"` // 访问密钥（Azure为访问令牌）
+	Sign      string `json:"sign" yaml:"sign"`           // 短信签名
+	Template  string `json:"template" yaml:"template"`   // 短信模板ID或模板内容
+
+	Region     string `json:"region" yaml:"region"`         // 服务区域（亚马逊SNS）
+	Endpoint   string `json:"endpoint" yaml:"endpoint"`     // 服务端点（Azure、华为云、百度云、Infobip必填，其他服务商可选）
+	Sender     string `json:"sender" yaml:"sender"`         // 发送方号码（Azure、华为云、Twilio）
+	AppId      string `json:"appId" yaml:"appId"`           // 应用ID（腾讯云）
+	SmsAccount string `json:"smsAccount" yaml:"smsAccount"` // 短信账户（火山引擎）
+	ProjectId  string `json:"projectId" yaml:"projectId"`   // 项目ID（UCloud）
+	GoodsId    string `json:"goodsId" yaml:"goodsId"`       // 商品ID（短信宝，可选）
+
+	StatusCallback string `json:"statusCallback" yaml:"statusCallback"` // 状态报告回调地址（华为云、Twilio、Infobip，可选）
+
+	HTTPClient *http.Client `json:"-" yaml:"-"` // 自定义HTTP客户端（可选，不支持的服务商创建时返回错误）
+
+	other []string // NewSmsProvider按位置传入的other参数，原样传给内置客户端的构造函数
+}
+
+// ConfigError 服务提供商配置错误
+// 指明缺少的配置字段
+type ConfigError struct {
+	Provider string // 服务提供商类型
+	Field    string // 缺少的配置字段名
+}
+
+// Error 获取错误信息
+// 返回:
+//   - string: 错误信息
+func (e *ConfigError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("missing parameter: %s", e.Field)
+	}
+	return fmt.Sprintf("%s: missing parameter: %s", e.Provider, e.Field)
+}
+
+// Unwrap 获取标准错误
+// 返回:
+//   - error: 标准错误ErrInvalidParameter
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidParameter
+}
+
+// Validate 校验配置
+// 检查服务提供商是否已注册以及注册时声明的必填字段是否齐全
+// 返回:
+//   - error: 错误信息，缺少字段时返回*ConfigError
+func (c ProviderConfig) Validate() error {
+	if c.Provider == "" {
+		return &ConfigError{Field: "Provider"}
+	}
+
+	entry, err := lookupProvider(c.Provider)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range entry.requiredFields {
+		if c.fieldValue(field) == "" {
+			return &ConfigError{Provider: c.Provider, Field: field}
+		}
+	}
+
+	return nil
+}
+
+// fieldValue 根据字段名获取配置值
+// 参数:
+//   - field: 字段名
+// 返回:
+//   - string: 配置值
+func (c ProviderConfig) fieldValue(field string) string {
+	switch field {
+	case "Provider":
+		return c.Provider
+	case "AccessId":
+		return c.AccessId
+	case "AccessKey":
+		return c.AccessKey
+	case "Sign":
+		return c.Sign
+	case "Template":
+		return c.Template
+	case "Region":
+		return c.Region
+	case "Endpoint":
+		return c.Endpoint
+	case "Sender":
+		return c.Sender
+	case "AppId":
+		return c.AppId
+	case "SmsAccount":
+		return c.SmsAccount
+	case "ProjectId":
+		return c.ProjectId
+	case "GoodsId":
+		return c.GoodsId
+	case "StatusCallback":
+		return c.StatusCallback
+	default:
+		return ""
+	}
+}
+
+// setFieldValue 根据字段名设置配置值
+// 参数:
+//   - field: 字段名
+//   - value: 配置值
+func (c *ProviderConfig) setFieldValue(field string, value string) {
+	switch field {
+	case "Provider":
+		c.Provider = value
+	case "AccessId":
+		c.AccessId = value
+	case "AccessKey":
+		c.AccessKey = value
+	case "Sign":
+		c.Sign = value
+	case "Template":
+		c.Template = value
+	case "Region":
+		c.Region = value
+	case "Endpoint":
+		c.Endpoint = value
+	case "Sender":
+		c.Sender = value
+	case "AppId":
+		c.AppId = value
+	case "SmsAccount":
+		c.SmsAccount = value
+	case "ProjectId":
+		c.ProjectId = value
+	case "GoodsId":
+		c.GoodsId = value
+	case "StatusCallback":
+		c.StatusCallback = value
+	}
+}
+
+// newLegacyConfig 将NewSmsProvider的位置参数转换为配置
+// other参数按各内置服务商约定的顺序映射到具名字段，自定义服务商不使用other参数
+// 参数:
+//   - provider: 服务提供商类型
+//   - accessId: 访问ID
+//   - accessKey: 访问密钥
+//   - sign: 短信签名
+//   - template: 短信模板
+//   - other: 其他参数
+// 返回:
+//   - ProviderConfig: 服务提供商配置
+func newLegacyConfig(provider string, accessId string, accessKey string, sign string, template string, other []string) ProviderConfig {
+	config := ProviderConfig{
+		Provider:  provider,
+		AccessId:  accessId,
+		AccessKey: accessKey,
+		Sign:      sign,
+		Template:  template,
+		other:     other,
+	}
+
+	value := func(index int) string {
+		if index < len(other) {
+			return other[index]
+		}
+		return ""
+	}
+
+	switch provider {
+	case SMS_AMAZON:
+		config.Region = value(0)
+	case SMS_AZURE, SMS_HUAWEI:
+		config.Endpoint = value(0)
+		config.Sender = value(1)
+	case SMS_TWILIO:
+		config.Sender = value(0)
+	case SMS_INFOBIP, SMS_BAIdU:
+		config.Endpoint = value(0)
+	case SMS_SMSBAO:
+		config.GoodsId = value(0)
+	case SMS_TENCENT:
+		config.AppId = value(0)
+	case SMS_VOCL:
+		config.SmsAccount = value(0)
+	case SMS_UCloud:
+		config.ProjectId = value(0)
+	}
+
+	return config
+}
+
+// otherArgs 构造内置客户端构造函数所需的other参数
+// 通过NewSmsProvider创建时原样使用按位置传入的other参数（包括空值），保持与原有调用方式一致；
+// 通过配置创建时按具名字段构造，末尾的空值会被忽略，以便构造函数按参数个数报告缺少的参数
+// 参数:
+//   - values: 按构造函数约定顺序排列的参数值
+// 返回:
+//   - []string: other参数
+func (c ProviderConfig) otherArgs(values ...string) []string {
+	if c.other != nil {
+		return c.other
+	}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// NewSmsProviderFromConfig 根据配置创建短信服务提供商实例
+// 创建前会校验配置，缺少必填字段时返回*ConfigError
+// 设置了Endpoint、StatusCallback或HTTPClient时，创建后通过SetEndpoint、SetStatusCallback、SetHTTPClient应用到服务商实例
+// 参数:
+//   - config: 服务提供商配置
+// 返回:
+//   - SmsProvider: 短信服务提供商实例
+//   - error: 错误信息
+func NewSmsProviderFromConfig(config ProviderConfig) (SmsProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	entry, err := lookupProvider(config.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := entry.factory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Endpoint != "" {
+		if err = SetEndpoint(provider, config.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.StatusCallback != "" {
+		if err = SetStatusCallback(provider, config.StatusCallback); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.HTTPClient != nil {
+		if err = SetHTTPClient(provider, config.HTTPClient); err != nil {
+			return nil, err
+		}
+	}
+
+	return provider, nil
+}
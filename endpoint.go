@@ -0,0 +1,66 @@
+// Package sms 短信服务提供商服务端点设置
+package sms
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// EndpointSetter 支持自定义服务端点的短信服务提供商
+// 可用于切换区域或备用端点，以及在集成测试中指向本地模拟服务
+type EndpointSetter interface {
+	// SetEndpoint 设置服务端点
+	// 参数:
+	//   - endpoint: 服务端点基础URL（如"https://api.example.com"），请求路径由客户端拼接
+	SetEndpoint(endpoint string)
+}
+
+// SetEndpoint 为短信服务提供商设置服务端点
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - endpoint: 服务端点基础URL
+// 返回:
+//   - error: 错误信息，服务商不支持自定义服务端点时返回错误
+func SetEndpoint(provider SmsProvider, endpoint string) error {
+	if endpoint == "" {
+		return fmt.Errorf("missing parameter: endpoint")
+	}
+
+	setter, ok := provider.(EndpointSetter)
+	if !ok {
+		return fmt.Errorf("provider does not support custom endpoint: %T", provider)
+	}
+
+	setter.SetEndpoint(endpoint)
+	return nil
+}
+
+// joinEndpoint 拼接服务端点和请求路径
+// 参数:
+//   - endpoint: 服务端点基础URL
+//   - path: 请求路径（以"/"开头）
+// 返回:
+//   - string: 请求URL
+func joinEndpoint(endpoint string, path string) string {
+	return strings.TrimSuffix(endpoint, "/") + path
+}
+
+// splitEndpoint 拆分服务端点的协议和主机
+// 用于只能按协议和主机设置端点的SDK，未指定协议时使用https
+// 参数:
+//   - endpoint: 服务端点（如"https://api.example.com"或"api.example.com"）
+// 返回:
+//   - string: 协议
+//   - string: 主机（包含端口）
+func splitEndpoint(endpoint string) (string, string) {
+	if !strings.Contains(endpoint, "://") {
+		return "https", strings.TrimSuffix(endpoint, "/")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "https", strings.TrimSuffix(endpoint, "/")
+	}
+	return u.Scheme, u.Host
+}
@@ -0,0 +1,187 @@
+// Package sms 短信服务错误定义
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// 标准错误定义
+// 各服务商的错误码会被归类为以下错误，调用方可通过errors.Is判断错误类型
+var (
+	ErrInvalidNumber       = phone.ErrInvalidNumber              // 手机号码无效
+	ErrInvalidParameter    = errors.New("invalid parameter")     // 请求参数无效
+	ErrAuthFailed          = errors.New("authentication failed") // 鉴权失败
+	ErrRateLimited         = errors.New("rate limited")          // 发送频率超限
+	ErrInsufficientBalance = errors.New("insufficient balance")  // 余额不足
+	ErrTemplateRejected    = errors.New("template rejected")     // 模板不可用或未审核通过
+	ErrSignRejected        = errors.New("sign rejected")         // 签名不可用或未审核通过
+	ErrContentBlocked      = errors.New("content blocked")       // 短信内容被拦截
+	ErrServiceUnavailable  = errors.New("service unavailable")   // 服务商暂时不可用
+)
+
+// SmsError 短信服务商错误
+// 保留服务商原始错误码和错误信息，并通过Err归类为标准错误
+type SmsError struct {
+	Provider    string // 服务提供商类型
+	Code        string // 服务商原始错误码
+	Message     string // 服务商原始错误信息
+	PhoneNumber string // 出错的接收方号码（按号码返回错误时）
+	Err         error  // 归类后的标准错误或底层网络错误，无法归类时为nil
+}
+
+// Error 获取错误信息
+// 返回:
+//   - string: 错误信息
+func (e *SmsError) Error() string {
+	message := e.Message
+	if e.PhoneNumber != "" {
+		message = fmt.Sprintf("%s, %s", e.PhoneNumber, message)
+	}
+
+	if e.Code == "" {
+		return fmt.Sprintf("%s: %s", e.Provider, message)
+	}
+	return fmt.Sprintf("%s: [%s] %s", e.Provider, e.Code, message)
+}
+
+// Unwrap 获取归类后的标准错误
+// 返回:
+//   - error: 标准错误
+func (e *SmsError) Unwrap() error {
+	return e.Err
+}
+
+// newSmsError 创建短信服务商错误
+// 参数:
+//   - provider: 服务提供商类型
+//   - code: 服务商原始错误码
+//   - message: 服务商原始错误信息
+//   - codes: 服务商错误码与标准错误的对应关系
+//
+// 返回:
+//   - *SmsError: 短信服务商错误
+func newSmsError(provider string, code string, message string, codes map[string]error) *SmsError {
+	return &SmsError{
+		Provider: provider,
+		Code:     code,
+		Message:  message,
+		Err:      codes[code],
+	}
+}
+
+// forNumber 设置出错的接收方号码
+// 参数:
+//   - phoneNumber: 接收方号码
+//
+// 返回:
+//   - *SmsError: 短信服务商错误
+func (e *SmsError) forNumber(phoneNumber string) *SmsError {
+	e.PhoneNumber = phoneNumber
+	return e
+}
+
+// withStatus 根据HTTP状态码补充错误归类
+// 错误码无法归类时使用HTTP状态码进行归类
+// 参数:
+//   - statusCode: HTTP状态码
+//
+// 返回:
+//   - *SmsError: 短信服务商错误
+func (e *SmsError) withStatus(statusCode int) *SmsError {
+	if e.Err == nil {
+		e.Err = httpStatusError(statusCode)
+	}
+	return e
+}
+
+// newHttpStatusError 根据HTTP状态码创建短信服务商错误
+// 参数:
+//   - provider: 服务提供商类型
+//   - statusCode: HTTP状态码
+//   - body: 响应内容
+//
+// 返回:
+//   - *SmsError: 短信服务商错误
+func newHttpStatusError(provider string, statusCode int, body string) *SmsError {
+	return &SmsError{
+		Provider: provider,
+		Code:     strconv.Itoa(statusCode),
+		Message:  body,
+		Err:      httpStatusError(statusCode),
+	}
+}
+
+// httpStatusError 将HTTP状态码归类为标准错误
+// 参数:
+//   - statusCode: HTTP状态码
+//
+// 返回:
+//   - error: 标准错误，无法归类时返回nil
+func httpStatusError(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuthFailed
+	case statusCode == http.StatusPaymentRequired:
+		return ErrInsufficientBalance
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= http.StatusInternalServerError:
+		return ErrServiceUnavailable
+	case statusCode >= http.StatusBadRequest:
+		return ErrInvalidParameter
+	default:
+		return nil
+	}
+}
+
+// IsRetryable 判断错误是否为临时性错误
+// 网络错误、超时、服务商限流和服务不可用属于临时性错误，稍后重试可能成功；
+// 上下文取消以及号码、鉴权、余额、模板等错误重试无意义。
+// 客户端限流返回的*RateLimitError虽然满足errors.Is(err, ErrRateLimited)，但立即重试或换服务商发送都会绕过限制，因此不可重试；
+// 多个错误合并（errors.Join）时，任一错误可重试即可重试
+// 参数:
+//   - err: 错误信息
+//
+// 返回:
+//   - bool: 是否可以重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if IsRetryable(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var limitErr *RateLimitError
+	if errors.As(err, &limitErr) {
+		return false
+	}
+
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServiceUnavailable) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,139 @@
+// Package sms 故障转移短信服务提供商
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FailoverProvider 故障转移短信服务提供商
+// 按顺序使用多个服务提供商发送短信，某个服务商返回可重试错误（见IsRetryable）时，
+// 将尚未受理的号码转交下一个服务商发送
+type FailoverProvider struct {
+	providers []SmsProvider // 按优先级排列的服务提供商
+}
+
+// 确保FailoverProvider实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &FailoverProvider{}
+
+// NewFailoverProvider 创建故障转移短信服务提供商
+// 参数:
+//   - providers: 按优先级排列的服务提供商
+// 返回:
+//   - *FailoverProvider: 故障转移短信服务提供商实例
+//   - error: 错误信息
+func NewFailoverProvider(providers ...SmsProvider) (*FailoverProvider, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("missing parameter: providers")
+	}
+	for i, provider := range providers {
+		if provider == nil {
+			return nil, fmt.Errorf("bad parameter: providers[%d] is nil", i)
+		}
+	}
+
+	return &FailoverProvider{
+		providers: append([]SmsProvider(nil), providers...),
+	}, nil
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (f *FailoverProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := f.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 已被某个服务商受理的号码不会重复发送；遇到不可重试的错误或上下文结束时立即返回
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果，Provider为最后一次发送的服务商，Attempts包含各服务商的发送结果
+//   - error: 错误信息，所有服务商均失败时包含各服务商的错误
+func (f *FailoverProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	result := newSendResult("")
+	defer result.sortRecipients(targetPhoneNumber)
+	pending := targetPhoneNumber
+
+	var errs []error
+	for i, provider := range f.providers {
+		attempt, err := SendMessageContext(ctx, provider, param, pending...)
+		if attempt != nil {
+			result.Provider = attempt.Provider
+			result.RequestId = attempt.RequestId
+			result.Raw = attempt.Raw
+			result.Attempts = append(result.Attempts, attempt)
+		}
+
+		last := err == nil || !IsRetryable(err) || ctx.Err() != nil || i == len(f.providers)-1
+		pending = result.merge(attempt, pending, last)
+		if err == nil {
+			return result, nil
+		}
+
+		errs = append(errs, err)
+		if last {
+			break
+		}
+		if len(pending) == 0 {
+			return result, nil
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// merge 合并单个服务商的发送结果
+// 参数:
+//   - attempt: 服务商的发送结果，可能为nil
+//   - pending: 本次发送的号码列表
+//   - final: 是否为最后一次发送，是则同时保留未受理号码的结果
+// 返回:
+//   - []string: 尚未受理的号码列表
+func (r *SendResult) merge(attempt *SendResult, pending []string, final bool) []string {
+	if attempt == nil {
+		return pending
+	}
+
+	remaining := make([]string, 0, len(pending))
+	for _, phoneNumber := range pending {
+		recipient := attempt.Recipient(phoneNumber)
+		if recipient != nil && recipient.Accepted {
+			r.add(recipient)
+			continue
+		}
+
+		remaining = append(remaining, phoneNumber)
+		if final && recipient != nil {
+			r.add(recipient)
+		}
+	}
+	return remaining
+}
+
+// sortRecipients 按号码列表的顺序排列接收方发送结果
+// 号码重复出现时依次对应该号码的各条结果
+// 参数:
+//   - phoneNumbers: 号码列表
+func (r *SendResult) sortRecipients(phoneNumbers []string) {
+	used := make([]bool, len(r.Recipients))
+	recipients := make([]*RecipientResult, 0, len(r.Recipients))
+	for _, phoneNumber := range phoneNumbers {
+		for i, recipient := range r.Recipients {
+			if !used[i] && recipient.PhoneNumber == phoneNumber {
+				used[i] = true
+				recipients = append(recipients, recipient)
+				break
+			}
+		}
+	}
+	r.Recipients = recipients
+}
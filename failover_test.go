@@ -0,0 +1,106 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFailoverProvider(t *testing.T) {
+	numbers := []string{"+8613800138000", "+8613800138001"}
+
+	tests := []struct {
+		name          string
+		primary       *stubProvider
+		secondary     *stubProvider
+		wantSecondary [][]string
+		wantAccepted  []string
+		wantErr       error
+	}{
+		{
+			name:         "primary succeeds",
+			primary:      &stubProvider{},
+			secondary:    &stubProvider{},
+			wantAccepted: numbers,
+		},
+		{
+			name:          "unaccepted numbers fail over",
+			primary:       &stubProvider{err: ErrServiceUnavailable, accept: numbers[:1]},
+			secondary:     &stubProvider{},
+			wantSecondary: [][]string{numbers[1:]},
+			wantAccepted:  numbers,
+		},
+		{
+			name:          "all numbers fail over",
+			primary:       &stubProvider{err: ErrRateLimited},
+			secondary:     &stubProvider{},
+			wantSecondary: [][]string{numbers},
+			wantAccepted:  numbers,
+		},
+		{
+			name:         "non-retryable error stops",
+			primary:      &stubProvider{err: ErrInvalidNumber, accept: numbers[:1]},
+			secondary:    &stubProvider{},
+			wantAccepted: numbers[:1],
+			wantErr:      ErrInvalidNumber,
+		},
+		{
+			name:          "all providers fail",
+			primary:       &stubProvider{err: ErrServiceUnavailable},
+			secondary:     &stubProvider{err: ErrServiceUnavailable, accept: numbers[1:]},
+			wantSecondary: [][]string{numbers},
+			wantAccepted:  numbers[1:],
+			wantErr:       ErrServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failover, err := NewFailoverProvider(tt.primary, tt.secondary)
+			if err != nil {
+				t.Fatalf("NewFailoverProvider() error = %v", err)
+			}
+
+			result, err := failover.SendMessageContext(context.Background(), nil, numbers...)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("SendMessageContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(tt.secondary.sent) != fmt.Sprint(tt.wantSecondary) {
+				t.Errorf("secondary sent %v, want %v", tt.secondary.sent, tt.wantSecondary)
+			}
+			if got := result.Accepted(); fmt.Sprint(got) != fmt.Sprint(tt.wantAccepted) {
+				t.Errorf("Accepted() = %v, want %v", got, tt.wantAccepted)
+			}
+			if len(result.Recipients) != len(numbers) {
+				t.Errorf("got %d recipients, want %d", len(result.Recipients), len(numbers))
+			}
+		})
+	}
+}
+
+func TestFailoverProviderCanceled(t *testing.T) {
+	primary, secondary := &stubProvider{}, &stubProvider{}
+	failover, err := NewFailoverProvider(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewFailoverProvider() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := failover.SendMessageContext(ctx, nil, "+8613800138000"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendMessageContext() error = %v, want context.Canceled", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secondary called %d times, want 0", secondary.calls)
+	}
+}
+
+func TestNewFailoverProvider(t *testing.T) {
+	if _, err := NewFailoverProvider(); err == nil {
+		t.Error("NewFailoverProvider() error = nil, want missing providers")
+	}
+	if _, err := NewFailoverProvider(&stubProvider{}, nil); err == nil {
+		t.Error("NewFailoverProvider(nil provider) error = nil, want error")
+	}
+}
@@ -1,142 +1,213 @@
-// Package sms GCCPAY短信服务实现
-package sms
-
-import (
-	"bytes"
-	"crypto/md5"
-	"crypto/rand"
-	"encoding/base64"
-	"encoding/hex"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-	"time"
-)
-
-// GCCPAYClient GCCPAY短信客户端
-// 封装GCCPAY短信API调用
-type GCCPAYClient struct {
-	clientname string // 客户端名称
-	secret     string // 客户端密钥
-	template   string // 短信模板
-}
-
-// params 短信发送参数结构体
-type params struct {
-	Mobile         string            `json:"mobile"`          // 手机号码
-	TemplateCode   string            `json:"template_code"`   // 模板代码
-	TemplateParams map[string]string `json:"template_params"` // 模板参数
-}
-
-// GetGCCPAYClient 创建GCCPAY短信客户端
-// 参数:
-//   - clientname: 客户端名称
-//   - secret: 客户端密钥
-//   - template: 短信模板
-// 返回:
-//   - *GCCPAYClient: GCCPAY短信客户端实例
-//   - error: 错误信息
-func GetGCCPAYClient(clientname string, secret string, template string) (*GCCPAYClient, error) {
-	gccPayClient := &GCCPAYClient{
-		clientname: clientname,
-		secret:     secret,
-		template:   template,
-	}
-
-	return gccPayClient, nil
-}
-
-// RandStringBytesCrypto 生成指定长度的随机字符串
-// 参数:
-//   - n: 字节长度
-// 返回:
-//   - string: Base64编码的随机字符串
-//   - error: 错误信息
-func RandStringBytesCrypto(n int) (string, error) {
-	b := make([]byte, n)
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(b), nil
-}
-
-// Md5 计算字符串的MD5哈希值
-// 参数:
-//   - str: 待计算的字符串
-// 返回:
-//   - string: MD5哈希值的十六进制字符串
-func Md5(str string) string {
-	h := md5.New()
-	h.Write([]byte(str))
-	return hex.EncodeToString(h.Sum(nil))
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *GCCPAYClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	_, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	reqParams := make(map[string]params)
-
-	for _, mobile := range targetPhoneNumber {
-		if strings.HasPrefix(mobile, "+") {
-			mobile = mobile[1:]
-		}
-		randomString, err := RandStringBytesCrypto(16)
-		if err != nil {
-			return fmt.Errorf("SMS key generation failed")
-		}
-
-		reqParams[randomString] = params{
-			Mobile:         mobile,
-			TemplateCode:   c.template,
-			TemplateParams: param,
-		}
-	}
-
-	requestBody := new(bytes.Buffer)
-	err := json.NewEncoder(requestBody).Encode(reqParams)
-	if err != nil {
-		return fmt.Errorf("SMS sending failed")
-	}
-
-	// 生成签名
-	timestamp := time.Now().Unix()
-
-	sign := Md5(fmt.Sprintf("%s%d%s", c.clientname, timestamp, c.secret))
-
-	reqUrl := "https://smscenter.sgate.sa/api/v1/client/sendSms"
-
-	// 发送请求
-	req, _ := http.NewRequest("POST", reqUrl, requestBody)
-	req.Header.Set("clientname", c.clientname)
-	req.Header.Set("timestamp", fmt.Sprintf("%d", timestamp))
-	req.Header.Set("sign", sign)
-	req.Header.Set("content-type", "application/json;")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	err = resp.Body.Close()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+// Package sms GCCPAY短信服务实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// GCCPAY_ENDPOINT GCCPAY默认服务端点
+const GCCPAY_ENDPOINT = "https://smscenter.sgate.sa"
+
+// gccpayNumberFormat GCCPAY要求的号码格式，国家代码加号码，不带"+"
+// 未包含国家代码的号码按沙特阿拉伯号码处理
+var gccpayNumberFormat = numberFormat{region: "SA", domestic: phone.FORMAT_DIGITS, international: phone.FORMAT_DIGITS}
+
+// GCCPAYClient GCCPAY短信客户端
+// 封装GCCPAY短信API调用
+type GCCPAYClient struct {
+	clientname string       // 客户端名称
+	secret     string       // 客户端密钥
+	template   string       // 短信模板
+	endpoint   string       // 服务端点
+	httpClient *http.Client // HTTP客户端
+}
+
+// params 短信发送参数结构体
+type params struct {
+	Mobile         string            `json:"mobile"`          // 手机号码
+	TemplateCode   string            `json:"template_code"`   // 模板代码
+	TemplateParams map[string]string `json:"template_params"` // 模板参数
+}
+
+// init 注册GCCPAY短信服务
+func init() {
+	Register(SMS_GCCPAY, func(config ProviderConfig) (SmsProvider, error) {
+		return GetGCCPAYClient(config.AccessId, config.AccessKey, config.Template)
+	}, "AccessId", "AccessKey", "Template")
+}
+
+// GetGCCPAYClient 创建GCCPAY短信客户端
+// 参数:
+//   - clientname: 客户端名称
+//   - secret: 客户端密钥
+//   - template: 短信模板
+// 返回:
+//   - *GCCPAYClient: GCCPAY短信客户端实例
+//   - error: 错误信息
+func GetGCCPAYClient(clientname string, secret string, template string) (*GCCPAYClient, error) {
+	gccPayClient := &GCCPAYClient{
+		clientname: clientname,
+		secret:     secret,
+		template:   template,
+		endpoint:   GCCPAY_ENDPOINT,
+		httpClient: &http.Client{},
+	}
+
+	return gccPayClient, nil
+}
+
+// RandStringBytesCrypto 生成指定长度的随机字符串
+// 参数:
+//   - n: 字节长度
+// 返回:
+//   - string: Base64编码的随机字符串
+//   - error: 错误信息
+func RandStringBytesCrypto(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Md5 计算字符串的MD5哈希值
+// 参数:
+//   - str: 待计算的字符串
+// 返回:
+//   - string: MD5哈希值的十六进制字符串
+func Md5(str string) string {
+	h := md5.New()
+	h.Write([]byte(str))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *GCCPAYClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为GCCPAY_ENDPOINT
+func (c *GCCPAYClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *GCCPAYClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *GCCPAYClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	_, ok := param["code"]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter: code")
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	reqParams := make(map[string]params)
+	// 请求键由客户端生成，作为每条短信的消息ID，按号码顺序保存，同一号码出现多次时各自对应一条短信
+	messageIds := make([]string, 0, len(targetPhoneNumber))
+
+	for _, phoneNumber := range targetPhoneNumber {
+		mobile, err := gccpayNumberFormat.format(phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		randomString, err := RandStringBytesCrypto(16)
+		if err != nil {
+			return nil, fmt.Errorf("SMS key generation failed")
+		}
+
+		reqParams[randomString] = params{
+			Mobile:         mobile,
+			TemplateCode:   c.template,
+			TemplateParams: param,
+		}
+		messageIds = append(messageIds, randomString)
+	}
+
+	requestBody := new(bytes.Buffer)
+	err := json.NewEncoder(requestBody).Encode(reqParams)
+	if err != nil {
+		return nil, fmt.Errorf("SMS sending failed")
+	}
+
+	// 生成签名
+	timestamp := time.Now().Unix()
+
+	sign := Md5(fmt.Sprintf("%s%d%s", c.clientname, timestamp, c.secret))
+
+	reqUrl := joinEndpoint(c.endpoint, "/api/v1/client/sendSms")
+
+	// 发送请求
+	req, err := http.NewRequestWithContext(ctx, "POST", reqUrl, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("clientname", c.clientname)
+	req.Header.Set("timestamp", fmt.Sprintf("%d", timestamp))
+	req.Header.Set("sign", sign)
+	req.Header.Set("content-type", "application/json;")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSendResult(SMS_GCCPAY)
+	result.Raw = string(body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newHttpStatusError(SMS_GCCPAY, resp.StatusCode, string(body))
+	}
+
+	for i, phoneNumber := range targetPhoneNumber {
+		result.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			MessageId:   messageIds[i],
+			Accepted:    true,
+		})
+	}
+
+	return result, nil
+}
@@ -13,6 +13,7 @@ require (
 	github.com/twilio/twilio-go v1.23.6
 	github.com/ucloud/ucloud-sdk-go v0.22.31
 	github.com/volcengine/volc-sdk-golang v1.0.186
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
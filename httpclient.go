@@ -0,0 +1,52 @@
+// Package sms 短信服务提供商HTTP客户端设置
+package sms
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPClientSetter 支持自定义HTTP客户端的短信服务提供商
+// 通过自定义HTTP客户端可以设置代理、超时以及复用连接
+type HTTPClientSetter interface {
+	// SetHTTPClient 设置HTTP客户端
+	// 参数:
+	//   - client: HTTP客户端，不能为空
+	SetHTTPClient(client *http.Client)
+}
+
+// SetHTTPClient 为短信服务提供商设置HTTP客户端
+// 基于SDK的服务商仅使用SDK允许设置的部分（如Transport和Timeout）
+// 百度云和UniSMS的SDK不支持自定义HTTP客户端
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - client: HTTP客户端
+// 返回:
+//   - error: 错误信息，服务商不支持自定义HTTP客户端时返回错误
+func SetHTTPClient(provider SmsProvider, client *http.Client) error {
+	if client == nil {
+		return fmt.Errorf("missing parameter: client")
+	}
+
+	setter, ok := provider.(HTTPClientSetter)
+	if !ok {
+		return fmt.Errorf("provider does not support custom http client: %T", provider)
+	}
+
+	setter.SetHTTPClient(client)
+	return nil
+}
+
+// SetTransport 为短信服务提供商设置HTTP传输层
+// 等同于使用仅包含该传输层的HTTP客户端调用SetHTTPClient
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - transport: HTTP传输层
+// 返回:
+//   - error: 错误信息，服务商不支持自定义HTTP客户端时返回错误
+func SetTransport(provider SmsProvider, transport http.RoundTripper) error {
+	if transport == nil {
+		return fmt.Errorf("missing parameter: transport")
+	}
+	return SetHTTPClient(provider, &http.Client{Transport: transport})
+}
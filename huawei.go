@@ -1,177 +1,763 @@
-// Package sms 华为云短信服务实现
-package sms
-
-import (
-	"bytes"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/base64"
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
-	"time"
-
-	"github.com/google/uuid"
-)
-
-// 华为云短信API相关常量
-const (
-	WSSE_HEADER_FORMAT = "UsernameToken Username=\"%s\",PasswordDigest=\"%s\",Nonce=\"%s\",Created=\"%s\"" // WSSE头格式
-	AUTH_HEADER_VALUE  = "WSSE realm=\"SDP\",profile=\"UsernameToken\",type=\"Appkey\""                    // 认证头值
-)
-
-// HuaweiClient 华为云短信客户端
-// 封装华为云短信API调用
-type HuaweiClient struct {
-	accessId   string // 访问ID
-	accessKey  string // 访问密钥
-	sign       string // 短信签名
-	template   string // 短信模板ID
-	apiAddress string // API地址
-	sender     string // 发送方号码
-}
-
-// GetHuaweiClient 创建华为云短信客户端
-// 参数:
-//   - accessId: 华为云访问ID
-//   - accessKey: 华为云访问密钥
-//   - sign: 短信签名
-//   - template: 短信模板ID
-//   - other: 其他参数（[0]为API地址，[1]为发送方号码）
-//
-// 返回:
-//   - *HuaweiClient: 华为云短信客户端实例
-//   - error: 错误信息
-func GetHuaweiClient(accessId string, accessKey string, sign string, template string, other []string) (*HuaweiClient, error) {
-	if len(other) < 2 {
-		return nil, fmt.Errorf("missing parameter: apiAddress or sender")
-	}
-
-	apiAddress := fmt.Sprintf("%s/sms/batchSendSms/v1", other[0])
-
-	huaweiClient := &HuaweiClient{
-		accessId:   accessId,
-		accessKey:  accessKey,
-		sign:       sign,
-		template:   template,
-		apiAddress: apiAddress,
-		sender:     other[1],
-	}
-
-	return huaweiClient, nil
-}
-
-// SendMessage 发送短信
-// 参考文档: https://support.huaweicloud.com/intl/zh-cn/devg-msgsms/sms_04_0012.html
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 目标手机号码列表
-//
-// 返回:
-//   - error: 错误信息
-func (c *HuaweiClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	phoneNumbers := strings.Join(targetPhoneNumber, ",")
-	templateParas := fmt.Sprintf("[\"%s\"]", code)
-
-	body := buildRequestBody(c.sender, phoneNumbers, c.template, templateParas, "", c.sign)
-	headers := make(map[string]string)
-	headers["Content-Type"] = "application/x-www-form-urlencoded"
-	headers["Authorization"] = AUTH_HEADER_VALUE
-	headers["X-WSSE"] = buildWsseHeader(c.accessId, c.accessKey)
-
-	_, err := post(c.apiAddress, []byte(body), headers)
-	return err
-}
-
-// buildRequestBody 构建请求体
-// 参数:
-//   - sender: 发送方号码
-//   - receiver: 接收方号码
-//   - templateId: 模板ID
-//   - templateParas: 模板参数
-//   - statusCallBack: 状态回调地址
-//   - signature: 签名
-//
-// 返回:
-//   - string: 构建的请求体
-func buildRequestBody(sender, receiver, templateId, templateParas, statusCallBack, signature string) string {
-	param := "from=" + url.QueryEscape(sender) + "&to=" + url.QueryEscape(receiver) + "&templateId=" + url.QueryEscape(templateId)
-	if templateParas != "" {
-		param += "&templateParas=" + url.QueryEscape(templateParas)
-	}
-	if statusCallBack != "" {
-		param += "&statusCallback=" + url.QueryEscape(statusCallBack)
-	}
-	if signature != "" {
-		param += "&signature=" + url.QueryEscape(signature)
-	}
-	return param
-}
-
-// buildWsseHeader 构建WSSE认证头
-// 参数:
-//   - appKey: 应用密钥
-//   - appSecret: 应用秘密
-//
-// 返回:
-//   - string: WSSE认证头
-func buildWsseHeader(appKey, appSecret string) string {
-	cTime := time.Now().Format("2006-01-02T15:04:05Z")
-	nonce := uuid.NewString()
-	nonce = strings.ReplaceAll(nonce, "-", "")
-
-	h := sha256.New()
-	h.Write([]byte(nonce + cTime + appSecret))
-	passwordDigestBase64Str := base64.StdEncoding.EncodeToString(h.Sum(nil))
-
-	return fmt.Sprintf(WSSE_HEADER_FORMAT, appKey, passwordDigestBase64Str, nonce, cTime)
-}
-
-// post 发送POST请求
-// 参数:
-//   - url: 请求URL
-//   - param: 请求参数
-//   - headers: 请求头
-//
-// 返回:
-//   - string: 响应内容
-//   - error: 错误信息
-func post(url string, param []byte, headers map[string]string) (string, error) {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(param))
-	if err != nil {
-		return "", err
-	}
-
-	for key, header := range headers {
-		req.Header.Set(key, header)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	return string(body), nil
-}
+// Package sms 华为云短信服务实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// 华为云短信API相关常量
+const (
+	WSSE_HEADER_FORMAT = "UsernameToken Username=\"%s\",PasswordDigest=\"%s\",Nonce=\"%s\",Created=\"%s\"" // WSSE头格式
+	AUTH_HEADER_VALUE  = "WSSE realm=\"SDP\",profile=\"UsernameToken\",type=\"Appkey\""                    // 认证头值
+)
+
+// huaweiNumberFormat 华为云要求的号码格式（E.164），未包含国家代码的号码按中国大陆号码处理
+var huaweiNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_E164, international: phone.FORMAT_E164}
+
+// huaweiSuccessCode 华为云短信发送成功状态码
+const huaweiSuccessCode = "000000"
+
+// huaweiErrorCodes 华为云错误码与标准错误的对应关系
+var huaweiErrorCodes = map[string]error{
+	"E000101": ErrAuthFailed,       // 鉴权失败
+	"E000102": ErrAuthFailed,       // app_key无效
+	"E000103": ErrAuthFailed,       // app_key不可用
+	"E000104": ErrAuthFailed,       // app_secret无效
+	"E000105": ErrAuthFailed,       // access_token无效
+	"E000106": ErrAuthFailed,       // app_key没有调用本API的权限
+	"E000109": ErrAuthFailed,       // 用户状态未激活
+	"E000110": ErrAuthFailed,       // 时间超出限制
+	"E000111": ErrAuthFailed,       // 用户名或密码错误
+	"E000112": ErrAuthFailed,       // 用户状态已冻结
+	"E000620": ErrAuthFailed,       // 对端app IP不在白名单列表中
+	"E000623": ErrRateLimited,      // SP短信发送量达到限额
+	"E200015": ErrInvalidParameter, // 待发送短信数量太大
+	"E200028": ErrTemplateRejected, // 模板变量校验失败
+	"E200029": ErrTemplateRejected, // 模板类型校验失败
+	"E200030": ErrTemplateRejected, // 模板未激活
+	"E200033": ErrTemplateRejected, // 模板类型不正确
+	"E200041": ErrInvalidNumber,    // 同一短信内容接收号码重复
+}
+
+// HuaweiClient 华为云短信客户端
+// 封装华为云短信API调用
+type HuaweiClient struct {
+	accessId       string       // 访问ID
+	accessKey      string       // 访问密钥
+	sign           string       // 短信签名
+	template       string       // 短信模板ID
+	apiAddress     string       // API地址
+	sender         string       // 发送方号码
+	statusCallback string       // 状态报告回调地址
+	httpClient     *http.Client // HTTP客户端
+
+	iam            *HuaweiIAMConfig // IAM认证配置，用于模板管理接口
+	tokenMu        sync.Mutex       // IAM令牌锁
+	token          string           // IAM令牌
+	projectId      string           // IAM令牌所属的项目ID
+	tokenExpiresAt time.Time        // IAM令牌过期时间
+}
+
+// HuaweiIAMConfig 华为云IAM认证配置
+// 模板管理接口使用IAM令牌认证，与发送短信使用的APP_Key认证不同
+type HuaweiIAMConfig struct {
+	Region      string // 区域，如"cn-north-4"
+	DomainName  string // 账号名
+	UserName    string // IAM用户名
+	Password    string // IAM用户密码
+	AppId       string // 短信应用ID，创建和查询模板时使用
+	IAMEndpoint string // IAM服务地址，为空时使用https://iam.{Region}.myhuaweicloud.com
+	Endpoint    string // 短信模板管理服务地址，为空时使用https://msgsms.{Region}.myhuaweicloud.com
+}
+
+// HuaweiResponse 华为云短信发送响应结构体
+type HuaweiResponse struct {
+	Code        string            `json:"code"`        // 响应代码
+	Description string            `json:"description"` // 响应描述
+	Result      []HuaweiSmsResult `json:"result"`      // 各接收方的发送结果
+}
+
+// HuaweiSmsResult 华为云单个接收方的发送结果
+type HuaweiSmsResult struct {
+	OriginTo   string `json:"originTo"`   // 接收方号码
+	CreateTime string `json:"createTime"` // 短信资源创建时间
+	From       string `json:"from"`       // 发送方号码
+	SmsMsgId   string `json:"smsMsgId"`   // 短信唯一标识
+	Status     string `json:"status"`     // 短信状态码
+	CountryId  string `json:"countryId"`  // 国家码
+	Total      int    `json:"total"`      // 拆分条数
+}
+
+// 华为云状态报告状态码
+const (
+	HUAWEI_REPORT_DELIVERED = "DELIVRD" // 用户已成功收到短信
+	HUAWEI_REPORT_ACCEPTED  = "ACCEPTD" // 短信已提交运营商，等待状态报告
+	HUAWEI_REPORT_UNKNOWN   = "UNKNOWN" // 状态未知
+)
+
+// 华为云短信模板类型
+const (
+	HUAWEI_TEMPLATE_VERIFICATION = "VERIFICATION" // 验证码
+	HUAWEI_TEMPLATE_NOTIFICATION = "NOTIFICATION" // 短信通知
+	HUAWEI_TEMPLATE_PROMOTION    = "PROMOTION"    // 推广短信
+)
+
+// 华为云短信模板适用地区
+const (
+	HUAWEI_AREA_DOMESTIC      = 1 // 中国大陆
+	HUAWEI_AREA_INTERNATIONAL = 2 // 国际/港澳台
+)
+
+// HUAWEI_LIST_PAGE_SIZE 华为云查询短信模板列表的每页数量
+const HUAWEI_LIST_PAGE_SIZE = 100
+
+// HUAWEI_TOKEN_REFRESH 华为云IAM令牌在过期前多久重新获取
+const HUAWEI_TOKEN_REFRESH = 5 * time.Minute
+
+// huaweiReviewStatuses 华为云模板审核状态与标准审核状态的对应关系
+var huaweiReviewStatuses = map[string]ReviewStatus{
+	"0": REVIEW_PENDING,  // 待审核
+	"1": REVIEW_APPROVED, // 审核通过
+	"2": REVIEW_REJECTED, // 审核未通过
+	"3": REVIEW_CANCELED, // 已撤回
+}
+
+// HuaweiTemplate 华为云短信模板
+type HuaweiTemplate struct {
+	TemplateId      string `json:"template_id"`      // 模板ID
+	TemplateName    string `json:"template_name"`    // 模板名称
+	TemplateContent string `json:"template_content"` // 模板内容
+	TemplateType    string `json:"template_type"`    // 模板类型
+	Area            int    `json:"area"`             // 适用地区
+	Status          string `json:"status"`           // 审核状态
+	Reason          string `json:"reason"`           // 审核意见
+	CreateTime      string `json:"create_time"`      // 创建时间
+}
+
+// HuaweiTemplateList 华为云短信模板列表
+type HuaweiTemplateList struct {
+	Total   int              `json:"total"`   // 模板总数
+	Results []HuaweiTemplate `json:"results"` // 当前页的模板
+}
+
+// HuaweiErrorResponse 华为云管理接口错误响应
+type HuaweiErrorResponse struct {
+	ErrorCode string `json:"error_code"` // 错误码
+	ErrorMsg  string `json:"error_msg"`  // 错误信息
+}
+
+// init 注册华为云短信服务
+func init() {
+	Register(SMS_HUAWEI, func(config ProviderConfig) (SmsProvider, error) {
+		return GetHuaweiClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.Endpoint, config.Sender))
+	}, "AccessId", "AccessKey", "Sign", "Template", "Endpoint", "Sender")
+}
+
+// GetHuaweiClient 创建华为云短信客户端
+// 参数:
+//   - accessId: 华为云访问ID
+//   - accessKey: 华为云访问密钥
+//   - sign: 短信签名
+//   - template: 短信模板ID
+//   - other: 其他参数（[0]为API地址，[1]为发送方号码）
+//
+// 返回:
+//   - *HuaweiClient: 华为云短信客户端实例
+//   - error: 错误信息
+func GetHuaweiClient(accessId string, accessKey string, sign string, template string, other []string) (*HuaweiClient, error) {
+	if len(other) < 2 {
+		return nil, fmt.Errorf("missing parameter: apiAddress or sender")
+	}
+
+	apiAddress := fmt.Sprintf("%s/sms/batchSendSms/v1", other[0])
+
+	huaweiClient := &HuaweiClient{
+		accessId:   accessId,
+		accessKey:  accessKey,
+		sign:       sign,
+		template:   template,
+		apiAddress: apiAddress,
+		sender:     other[1],
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+
+	return huaweiClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *HuaweiClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *HuaweiClient) SetEndpoint(endpoint string) {
+	c.apiAddress = fmt.Sprintf("%s/sms/batchSendSms/v1", endpoint)
+}
+
+// SetStatusCallback 设置状态报告回调地址
+// 参数:
+//   - callbackUrl: 状态报告回调地址，华为云以表单格式推送状态报告
+func (c *HuaweiClient) SetStatusCallback(callbackUrl string) {
+	c.statusCallback = callbackUrl
+}
+
+// SendMessage 发送短信
+// 参考文档: https://support.huaweicloud.com/intl/zh-cn/devg-msgsms/sms_04_0012.html
+// 参数:
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - error: 错误信息
+func (c *HuaweiClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参考文档: https://support.huaweicloud.com/intl/zh-cn/devg-msgsms/sms_04_0012.html
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *HuaweiClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	code, ok := param["code"]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter: code")
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	formatted, err := huaweiNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneNumbers := strings.Join(formatted, ",")
+	templateParas := fmt.Sprintf("[\"%s\"]", code)
+
+	body := buildRequestBody(c.sender, phoneNumbers, c.template, templateParas, c.statusCallback, c.sign)
+	headers := make(map[string]string)
+	headers["Content-Type"] = "application/x-www-form-urlencoded"
+	headers["Authorization"] = AUTH_HEADER_VALUE
+	headers["X-WSSE"] = buildWsseHeader(c.accessId, c.accessKey)
+
+	respBody, err := post(ctx, c.httpClient, c.apiAddress, []byte(body), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSendResult(SMS_HUAWEI)
+	result.Raw = respBody
+
+	var huaweiResponse HuaweiResponse
+	if err = json.Unmarshal([]byte(respBody), &huaweiResponse); err != nil {
+		return result, err
+	}
+
+	errs := []error{}
+	for _, item := range huaweiResponse.Result {
+		result.add(&RecipientResult{
+			PhoneNumber: item.OriginTo,
+			MessageId:   item.SmsMsgId,
+			Accepted:    item.Status == huaweiSuccessCode,
+			Code:        item.Status,
+			Segments:    item.Total,
+		})
+		if item.Status != huaweiSuccessCode {
+			errs = append(errs, newSmsError(SMS_HUAWEI, item.Status, huaweiResponse.Description, huaweiErrorCodes).forNumber(item.OriginTo))
+		}
+	}
+
+	if huaweiResponse.Code != huaweiSuccessCode && len(errs) == 0 {
+		errs = append(errs, newSmsError(SMS_HUAWEI, huaweiResponse.Code, huaweiResponse.Description, huaweiErrorCodes))
+	}
+
+	result.restoreNumbers(formatted, targetPhoneNumber)
+
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	return result, nil
+}
+
+// SetIAMConfig 设置IAM认证配置
+// 设置后可以通过TemplateManager管理短信模板
+// 参数:
+//   - config: IAM认证配置
+//
+// 返回:
+//   - error: 错误信息
+func (c *HuaweiClient) SetIAMConfig(config HuaweiIAMConfig) error {
+	if config.Region == "" || config.DomainName == "" || config.UserName == "" || config.Password == "" {
+		return fmt.Errorf("missing parameter: region, domainName, userName or password")
+	}
+	if config.IAMEndpoint == "" {
+		config.IAMEndpoint = fmt.Sprintf("https://iam.%s.myhuaweicloud.com", config.Region)
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://msgsms.%s.myhuaweicloud.com", config.Region)
+	}
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.iam = &config
+	c.token = ""
+	return nil
+}
+
+// CreateTemplate 创建短信模板并提交审核
+// 需要先通过SetIAMConfig设置IAM认证配置
+// 参数:
+//   - ctx: 上下文
+//   - template: 创建短信模板的请求
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息
+func (c *HuaweiClient) CreateTemplate(ctx context.Context, template TemplateRequest) (*Template, error) {
+	templateType := HUAWEI_TEMPLATE_NOTIFICATION
+	switch template.Type {
+	case TEMPLATE_VERIFICATION:
+		templateType = HUAWEI_TEMPLATE_VERIFICATION
+	case TEMPLATE_PROMOTION:
+		templateType = HUAWEI_TEMPLATE_PROMOTION
+	}
+	area := HUAWEI_AREA_DOMESTIC
+	if template.International {
+		area = HUAWEI_AREA_INTERNATIONAL
+	}
+
+	request := map[string]any{
+		"template_name":    template.Name,
+		"template_content": template.Content,
+		"template_type":    templateType,
+		"template_desc":    template.Remark,
+		"area":             area,
+	}
+	if c.iam != nil && c.iam.AppId != "" {
+		request["app_id"] = c.iam.AppId
+	}
+
+	var response struct {
+		TemplateId string `json:"template_id"` // 模板ID
+	}
+	raw, err := c.templateRequest(ctx, "POST", "", request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		Provider:      SMS_HUAWEI,
+		TemplateId:    response.TemplateId,
+		Name:          template.Name,
+		Content:       template.Content,
+		Type:          template.Type,
+		International: template.International,
+		Status:        REVIEW_PENDING,
+		Raw:           raw,
+	}, nil
+}
+
+// ListTemplates 查询全部短信模板
+// 分页查询，设置了AppId时只查询该应用的模板
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*Template: 短信模板列表
+//   - error: 错误信息
+func (c *HuaweiClient) ListTemplates(ctx context.Context) ([]*Template, error) {
+	templates := make([]*Template, 0)
+	for offset := 0; ; offset += HUAWEI_LIST_PAGE_SIZE {
+		query := url.Values{}
+		query.Set("offset", fmt.Sprint(offset))
+		query.Set("limit", fmt.Sprint(HUAWEI_LIST_PAGE_SIZE))
+		if c.iam != nil && c.iam.AppId != "" {
+			query.Set("app_id", c.iam.AppId)
+		}
+
+		var response HuaweiTemplateList
+		if _, err := c.templateRequest(ctx, "GET", "?"+query.Encode(), nil, &response); err != nil {
+			return templates, err
+		}
+
+		for _, item := range response.Results {
+			templates = append(templates, huaweiTemplate(item))
+		}
+		if len(response.Results) < HUAWEI_LIST_PAGE_SIZE {
+			return templates, nil
+		}
+	}
+}
+
+// GetTemplate 查询短信模板及其审核状态
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板ID
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息
+func (c *HuaweiClient) GetTemplate(ctx context.Context, templateId string) (*Template, error) {
+	var response HuaweiTemplate
+	if _, err := c.templateRequest(ctx, "GET", "/"+url.PathEscape(templateId), nil, &response); err != nil {
+		return nil, err
+	}
+	return huaweiTemplate(response), nil
+}
+
+// DeleteTemplate 删除短信模板
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板ID
+//
+// 返回:
+//   - error: 错误信息
+func (c *HuaweiClient) DeleteTemplate(ctx context.Context, templateId string) error {
+	_, err := c.templateRequest(ctx, "DELETE", "/"+url.PathEscape(templateId), nil, nil)
+	return err
+}
+
+// templateRequest 调用华为云短信模板管理接口
+// 参数:
+//   - ctx: 上下文
+//   - method: HTTP方法
+//   - path: 模板接口路径后缀（模板ID或查询参数）
+//   - request: 请求体，为nil时不发送请求体
+//   - response: 响应体，为nil时不解析响应
+//
+// 返回:
+//   - string: 响应内容
+//   - error: 错误信息
+func (c *HuaweiClient) templateRequest(ctx context.Context, method string, path string, request any, response any) (string, error) {
+	token, projectId, endpoint, err := c.iamToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	if request != nil {
+		requestBody, err := json.Marshal(request)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(requestBody)
+	}
+
+	reqUrl := joinEndpoint(endpoint, "/v2/"+projectId+"/msgsms/templates"+path)
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	req.Header.Set("X-Auth-Token", token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			c.resetToken(token)
+		}
+		var errorResponse HuaweiErrorResponse
+		if json.Unmarshal(respBody, &errorResponse) == nil && errorResponse.ErrorCode != "" {
+			return string(respBody), newSmsError(SMS_HUAWEI, errorResponse.ErrorCode, errorResponse.ErrorMsg, huaweiErrorCodes).withStatus(resp.StatusCode)
+		}
+		return string(respBody), newHttpStatusError(SMS_HUAWEI, resp.StatusCode, string(respBody))
+	}
+
+	if response != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, response); err != nil {
+			return string(respBody), err
+		}
+	}
+	return string(respBody), nil
+}
+
+// iamToken 获取IAM令牌
+// 令牌在过期前HUAWEI_TOKEN_REFRESH内重新获取
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - string: IAM令牌
+//   - string: 令牌所属的项目ID
+//   - string: 短信模板管理服务地址
+//   - error: 错误信息，未设置IAM认证配置时返回错误
+func (c *HuaweiClient) iamToken(ctx context.Context) (string, string, string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.iam == nil {
+		return "", "", "", fmt.Errorf("missing parameter: iam config, call SetIAMConfig first")
+	}
+	if c.token != "" && time.Until(c.tokenExpiresAt) > HUAWEI_TOKEN_REFRESH {
+		return c.token, c.projectId, c.iam.Endpoint, nil
+	}
+
+	request := map[string]any{
+		"auth": map[string]any{
+			"identity": map[string]any{
+				"methods": []string{"password"},
+				"password": map[string]any{
+					"user": map[string]any{
+						"name":     c.iam.UserName,
+						"password": c.iam.Password,
+						"domain":   map[string]string{"name": c.iam.DomainName},
+					},
+				},
+			},
+			"scope": map[string]any{
+				"project": map[string]string{"name": c.iam.Region},
+			},
+		},
+	}
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", joinEndpoint(c.iam.IAMEndpoint, "/v3/auth/tokens"), bytes.NewReader(requestBody))
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", "", "", newHttpStatusError(SMS_HUAWEI, resp.StatusCode, string(respBody))
+	}
+
+	var response struct {
+		Token struct {
+			ExpiresAt string `json:"expires_at"` // 过期时间
+			Project   struct {
+				Id string `json:"id"` // 项目ID
+			} `json:"project"` // 令牌所属的项目
+		} `json:"token"` // 令牌信息
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", "", "", err
+	}
+	token := resp.Header.Get("X-Subject-Token")
+	if token == "" || response.Token.Project.Id == "" {
+		return "", "", "", fmt.Errorf("bad response: missing iam token or project id")
+	}
+
+	c.token = token
+	c.projectId = response.Token.Project.Id
+	c.tokenExpiresAt = parseTime(time.RFC3339, response.Token.ExpiresAt, time.UTC)
+	return c.token, c.projectId, c.iam.Endpoint, nil
+}
+
+// resetToken 丢弃已失效的IAM令牌，下次调用时重新获取
+// 参数:
+//   - token: 已失效的IAM令牌
+func (c *HuaweiClient) resetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+// parseHuaweiReport 解析华为云状态报告回调请求
+// 参数:
+//   - r: HTTP请求
+//
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseHuaweiReport(r *http.Request) ([]*DeliveryReport, error) {
+	form, err := readForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	smsMsgId := form.Get("smsMsgId")
+	if smsMsgId == "" {
+		return nil, fmt.Errorf("bad callback: missing smsMsgId")
+	}
+
+	report := &DeliveryReport{
+		MessageId:   smsMsgId,
+		PhoneNumber: form.Get("to"),
+		Status:      DELIVERY_FAILED,
+		Code:        form.Get("status"),
+		Message:     form.Get("orgCode"),
+		DeliveredAt: parseTime(time.RFC3339, form.Get("updateTime"), time.UTC),
+		Raw:         form.Encode(),
+	}
+	switch report.Code {
+	case HUAWEI_REPORT_DELIVERED:
+		report.Status = DELIVERY_DELIVERED
+	case HUAWEI_REPORT_ACCEPTED:
+		report.Status = DELIVERY_SENT
+	case HUAWEI_REPORT_UNKNOWN, "":
+		report.Status = DELIVERY_UNKNOWN
+	}
+
+	return []*DeliveryReport{report}, nil
+}
+
+// huaweiTemplate 将华为云模板信息转换为短信模板
+// 参数:
+//   - item: 华为云短信模板
+//
+// 返回:
+//   - *Template: 短信模板
+func huaweiTemplate(item HuaweiTemplate) *Template {
+	template := &Template{
+		Provider:      SMS_HUAWEI,
+		TemplateId:    item.TemplateId,
+		Name:          item.TemplateName,
+		Content:       item.TemplateContent,
+		International: item.Area == HUAWEI_AREA_INTERNATIONAL,
+		Status:        REVIEW_UNKNOWN,
+		Reason:        item.Reason,
+		CreatedAt:     parseTime(time.RFC3339, item.CreateTime, time.UTC),
+	}
+	if reviewStatus, ok := huaweiReviewStatuses[item.Status]; ok {
+		template.Status = reviewStatus
+	}
+	switch item.TemplateType {
+	case HUAWEI_TEMPLATE_VERIFICATION:
+		template.Type = TEMPLATE_VERIFICATION
+	case HUAWEI_TEMPLATE_NOTIFICATION:
+		template.Type = TEMPLATE_NOTIFICATION
+	case HUAWEI_TEMPLATE_PROMOTION:
+		template.Type = TEMPLATE_PROMOTION
+	}
+	if raw, err := json.Marshal(item); err == nil {
+		template.Raw = string(raw)
+	}
+	return template
+}
+
+// buildRequestBody 构建请求体
+// 参数:
+//   - sender: 发送方号码
+//   - receiver: 接收方号码
+//   - templateId: 模板ID
+//   - templateParas: 模板参数
+//   - statusCallBack: 状态回调地址
+//   - signature: 签名
+//
+// 返回:
+//   - string: 构建的请求体
+func buildRequestBody(sender, receiver, templateId, templateParas, statusCallBack, signature string) string {
+	param := "from=" + url.QueryEscape(sender) + "&to=" + url.QueryEscape(receiver) + "&templateId=" + url.QueryEscape(templateId)
+	if templateParas != "" {
+		param += "&templateParas=" + url.QueryEscape(templateParas)
+	}
+	if statusCallBack != "" {
+		param += "&statusCallback=" + url.QueryEscape(statusCallBack)
+	}
+	if signature != "" {
+		param += "&signature=" + url.QueryEscape(signature)
+	}
+	return param
+}
+
+// buildWsseHeader 构建WSSE认证头
+// 参数:
+//   - appKey: 应用密钥
+//   - appSecret: 应用秘密
+//
+// 返回:
+//   - string: WSSE认证头
+func buildWsseHeader(appKey, appSecret string) string {
+	cTime := time.Now().Format("2006-01-02T15:04:05Z")
+	nonce := uuid.NewString()
+	nonce = strings.ReplaceAll(nonce, "-", "")
+
+	h := sha256.New()
+	h.Write([]byte(nonce + cTime + appSecret))
+	passwordDigestBase64Str := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return fmt.Sprintf(WSSE_HEADER_FORMAT, appKey, passwordDigestBase64Str, nonce, cTime)
+}
+
+// post 发送POST请求
+// 参数:
+//   - ctx: 上下文
+//   - client: HTTP客户端
+//   - url: 请求URL
+//   - param: 请求参数
+//   - headers: 请求头
+//
+// 返回:
+//   - string: 响应内容
+//   - error: 错误信息
+func post(ctx context.Context, client *http.Client, url string, param []byte, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(param))
+	if err != nil {
+		return "", err
+	}
+
+	for key, header := range headers {
+		req.Header.Set(key, header)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
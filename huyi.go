@@ -1,97 +1,279 @@
-// Package sms 互亿无线短信服务实现
-package sms
-
-import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strconv"
-	"strings"
-	"time"
-)
-
-// HuyiClient 互亿无线短信客户端
-// 封装互亿无线短信API调用
-type HuyiClient struct {
-	appId    string // 应用ID
-	appKey   string // 应用密钥
-	template string // 短信模板
-}
-
-// GetHuyiClient 创建互亿无线短信客户端
-// 参数:
-//   - appId: 应用ID
-//   - appKey: 应用密钥
-//   - template: 短信模板
-// 返回:
-//   - *HuyiClient: 互亿无线短信客户端实例
-//   - error: 错误信息
-func GetHuyiClient(appId string, appKey string, template string) (*HuyiClient, error) {
-	return &HuyiClient{
-		appId:    appId,
-		appKey:   appKey,
-		template: template,
-	}, nil
-}
-
-// GetMd5String 计算字符串的MD5哈希值
-// 参数:
-//   - s: 待计算的字符串
-// 返回:
-//   - string: MD5哈希值的十六进制字符串
-func GetMd5String(s string) string {
-	h := md5.New()
-	h.Write([]byte(s))
-	return hex.EncodeToString(h.Sum(nil))
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (hc *HuyiClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missin parer: trgetPhoneNumber")
-	}
-
-	_now := strconv.FormatInt(time.Now().Unix(), 10)
-	smsContent := fmt.Sprintf(hc.template, code)
-	v := url.Values{}
-	v.Set("account", hc.appId)
-	v.Set("content", smsContent)
-	v.Set("time", _now)
-	passwordStr := hc.appId + hc.appKey + "%s" + smsContent + _now
-	for _, mobile := range targetPhoneNumber {
-		password := fmt.Sprintf(passwordStr, mobile)
-		v.Set("password", GetMd5String(password))
-		v.Set("mobile", mobile)
-
-		body := strings.NewReader(v.Encode()) // 编码表单数据
-		client := &http.Client{}
-		req, _ := http.NewRequest("POST", "http://106.ihuyi.com/webservice/sms.php?method=Submit&format=json", body)
-
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
-
-		resp, err := client.Do(req) // 发送远程请求
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close() // 关闭ReadCloser
-		_, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
+// Package sms 互亿无线短信服务实现
+package sms
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// HUYI_ENDPOINT 互亿无线默认服务端点
+const HUYI_ENDPOINT = "http://106.ihuyi.com"
+
+// huyiNumberFormat 互亿无线要求的号码格式，仅支持不带国家代码的中国大陆号码
+var huyiNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL}
+
+// HuyiClient 互亿无线短信客户端
+// 封装互亿无线短信API调用
+type HuyiClient struct {
+	appId        string       // 应用ID
+	appKey       string       // 应用密钥
+	template     string       // 短信模板
+	endpoint     string       // 服务端点
+	httpClient   *http.Client // HTTP客户端
+	segmentLimit SegmentLimit // 拆分条数限制
+	renderMode   RenderMode   // 短信模板的渲染方式
+}
+
+// huyiSuccessCode 互亿无线提交成功状态码
+const huyiSuccessCode = 2
+
+// huyiErrorCodes 互亿无线状态码与标准错误的对应关系
+var huyiErrorCodes = map[string]error{
+	"400":  ErrAuthFailed,          // 非法IP访问
+	"403":  ErrInvalidNumber,       // 手机号码不能为空
+	"405":  ErrAuthFailed,          // API ID或API KEY不正确
+	"4050": ErrAuthFailed,          // 账号被冻结
+	"4051": ErrInsufficientBalance, // 剩余条数不足
+	"4052": ErrAuthFailed,          // 访问IP与备案IP不符
+	"406":  ErrInvalidNumber,       // 手机格式不正确
+	"407":  ErrContentBlocked,      // 短信内容含有敏感字符
+	"4072": ErrTemplateRejected,    // 短信内容与模板不匹配
+	"4080": ErrRateLimited,         // 同一手机号码同一秒钟之内发送频率超限
+	"4081": ErrRateLimited,         // 同一手机号码一分钟之内发送频率超限
+	"4082": ErrRateLimited,         // 同一手机号码一天之内发送频率超限
+	"4085": ErrRateLimited,         // 同一手机号码验证码短信发送超出限制
+	"4086": ErrRateLimited,         // 同一手机号码发送频率太频繁
+}
+
+// HuyiResponse 互亿无线响应结构体
+type HuyiResponse struct {
+	Code  int    `json:"code"`  // 状态码（2为提交成功）
+	Msg   string `json:"msg"`   // 状态描述
+	SmsId string `json:"smsid"` // 短信流水号
+}
+
+// HuyiBalanceResponse 互亿无线余额查询响应结构体
+type HuyiBalanceResponse struct {
+	Code int    `json:"code"` // 状态码（2为查询成功）
+	Msg  string `json:"msg"`  // 状态描述
+	Num  int    `json:"num"`  // 剩余短信条数
+}
+
+// init 注册互亿无线短信服务
+func init() {
+	Register(SMS_HUYI, func(config ProviderConfig) (SmsProvider, error) {
+		return GetHuyiClient(config.AccessId, config.AccessKey, config.Template)
+	}, "AccessId", "AccessKey", "Template")
+}
+
+// GetHuyiClient 创建互亿无线短信客户端
+// 参数:
+//   - appId: 应用ID
+//   - appKey: 应用密钥
+//   - template: 短信模板
+// 返回:
+//   - *HuyiClient: 互亿无线短信客户端实例
+//   - error: 错误信息
+func GetHuyiClient(appId string, appKey string, template string) (*HuyiClient, error) {
+	return &HuyiClient{
+		appId:      appId,
+		appKey:     appKey,
+		template:   template,
+		endpoint:   HUYI_ENDPOINT,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// GetMd5String 计算字符串的MD5哈希值
+// 参数:
+//   - s: 待计算的字符串
+// 返回:
+//   - string: MD5哈希值的十六进制字符串
+func GetMd5String(s string) string {
+	h := md5.New()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (hc *HuyiClient) SetHTTPClient(client *http.Client) {
+	hc.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为HUYI_ENDPOINT
+func (hc *HuyiClient) SetEndpoint(endpoint string) {
+	hc.endpoint = endpoint
+}
+
+// SetSegmentLimit 设置短信拆分条数限制
+// 参数:
+//   - limit: 拆分条数限制
+func (hc *HuyiClient) SetSegmentLimit(limit SegmentLimit) {
+	hc.segmentLimit = limit
+}
+
+// SetMessageTemplate 设置短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (hc *HuyiClient) SetMessageTemplate(text string, mode RenderMode) {
+	hc.template = text
+	hc.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (hc *HuyiClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := hc.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (hc *HuyiClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	smsContent, err := RenderMessage(hc.template, hc.renderMode, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := huyiNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	_now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err = hc.segmentLimit.check(smsContent); err != nil {
+		return nil, err
+	}
+	v := url.Values{}
+	v.Set("account", hc.appId)
+	v.Set("content", smsContent)
+	v.Set("time", _now)
+	result := newSendResult(SMS_HUYI)
+	for i, mobile := range phoneNumbers {
+		// 短信内容可能包含"%"，因此直接拼接而不使用fmt格式化
+		password := hc.appId + hc.appKey + mobile + smsContent + _now
+		v.Set("password", GetMd5String(password))
+		v.Set("mobile", mobile)
+
+		body := strings.NewReader(v.Encode()) // 编码表单数据
+		req, err := http.NewRequestWithContext(ctx, "POST", joinEndpoint(hc.endpoint, "/webservice/sms.php?method=Submit&format=json"), body)
+		if err != nil {
+			return result, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
+
+		resp, err := hc.httpClient.Do(req) // 发送远程请求
+		if err != nil {
+			return result, err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close() // 关闭ReadCloser
+		if err != nil {
+			return result, err
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return result, newHttpStatusError(SMS_HUYI, resp.StatusCode, string(respBody))
+		}
+
+		var huyiResponse HuyiResponse
+		if err = json.Unmarshal(respBody, &huyiResponse); err != nil {
+			return result, err
+		}
+
+		result.add(&RecipientResult{
+			PhoneNumber: targetPhoneNumber[i],
+			MessageId:   huyiResponse.SmsId,
+			Accepted:    huyiResponse.Code == huyiSuccessCode,
+			Code:        strconv.Itoa(huyiResponse.Code),
+			Message:     huyiResponse.Msg,
+			Raw:         string(respBody),
+		})
+		if huyiResponse.Code != huyiSuccessCode {
+			return result, newSmsError(SMS_HUYI, strconv.Itoa(huyiResponse.Code), huyiResponse.Msg, huyiErrorCodes).forNumber(targetPhoneNumber[i])
+		}
+	}
+
+	return result, nil
+}
+
+// QueryBalance 查询账户余额
+// 通过GetNum接口查询剩余短信条数
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为短信条数
+//   - error: 错误信息
+func (hc *HuyiClient) QueryBalance(ctx context.Context) (*Balance, error) {
+	_now := strconv.FormatInt(time.Now().Unix(), 10)
+	v := url.Values{}
+	v.Set("account", hc.appId)
+	v.Set("password", GetMd5String(hc.appId+hc.appKey+_now))
+	v.Set("time", _now)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", joinEndpoint(hc.endpoint, "/webservice/sms.php?method=GetNum&format=json"), strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := hc.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_HUYI, resp.StatusCode, string(respBody))
+	}
+
+	var balanceResponse HuyiBalanceResponse
+	if err = json.Unmarshal(respBody, &balanceResponse); err != nil {
+		return nil, err
+	}
+	if balanceResponse.Code != huyiSuccessCode {
+		return nil, newSmsError(SMS_HUYI, strconv.Itoa(balanceResponse.Code), balanceResponse.Msg, huyiErrorCodes)
+	}
+
+	return &Balance{
+		Provider: SMS_HUYI,
+		Amount:   float64(balanceResponse.Num),
+		Unit:     BALANCE_UNIT_MESSAGES,
+		Raw:      string(respBody),
+	}, nil
+}
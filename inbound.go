@@ -0,0 +1,183 @@
+// Package sms 上行短信接收
+package sms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DEFAULT_POLL_INTERVAL 默认上行短信拉取间隔
+const DEFAULT_POLL_INTERVAL = 10 * time.Second
+
+// InboundMessage 上行短信（用户回复的短信）
+type InboundMessage struct {
+	Provider   string    // 服务提供商类型
+	MessageId  string    // 服务商消息ID（服务商未返回时为空）
+	From       string    // 发送方号码，即回复短信的用户
+	To         string    // 接收方号码或扩展码（服务商未返回时为空）
+	Text       string    // 短信内容
+	ReceivedAt time.Time // 接收时间（服务商未返回时为零值）
+	Raw        string    // 服务商原始数据
+}
+
+// InboundCallback 上行短信回调函数
+// 在处理回调请求或拉取上行短信的goroutine中同步调用，耗时操作应自行异步处理
+type InboundCallback func(message *InboundMessage)
+
+// ReplyPuller 支持主动拉取上行短信的短信服务提供商
+// 目前支持腾讯云
+type ReplyPuller interface {
+	// PullReplies 拉取上行短信
+	// 已拉取的上行短信不会再次返回，出错时仍返回出错前已拉取的上行短信
+	// 参数:
+	//   - ctx: 上下文
+	// 返回:
+	//   - []*InboundMessage: 上行短信列表
+	//   - error: 错误信息
+	PullReplies(ctx context.Context) ([]*InboundMessage, error)
+}
+
+// inboundParser 将服务商的上行短信推送请求解析为上行短信
+type inboundParser func(r *http.Request) ([]*InboundMessage, error)
+
+// inboundResponse 服务商要求的推送请求响应
+type inboundResponse struct {
+	contentType string // 响应类型
+	body        string // 响应内容
+}
+
+// inboundParsers 支持上行短信推送的服务商及其解析函数
+var inboundParsers = map[string]inboundParser{
+	SMS_ALIYUN:  parseAliyunInbound,
+	SMS_TWILIO:  parseTwilioInbound,
+	SMS_INFOBIP: parseInfobipInbound,
+}
+
+// inboundResponses 对推送请求的响应内容有要求的服务商
+var inboundResponses = map[string]inboundResponse{
+	SMS_ALIYUN: {contentType: "application/json", body: `{"code":0,"msg":"成功"}`},
+	SMS_TWILIO: {contentType: "text/xml", body: `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`},
+}
+
+// InboundHandler 上行短信推送处理器
+// 将服务商推送的上行短信解析为InboundMessage后交给回调函数，请求格式错误时返回400
+type InboundHandler struct {
+	provider string          // 服务提供商类型
+	parse    inboundParser   // 推送请求解析函数
+	response inboundResponse // 推送请求响应
+	callback InboundCallback // 上行短信回调函数
+}
+
+// 确保InboundHandler实现了http.Handler接口
+var _ http.Handler = &InboundHandler{}
+
+// NewInboundHandler 创建上行短信推送处理器
+// 支持阿里云（HTTP批量推送）、Twilio和Infobip，腾讯云请使用PollReplies
+// 参数:
+//   - provider: 服务提供商类型（SMS_*常量）
+//   - callback: 上行短信回调函数
+// 返回:
+//   - *InboundHandler: 上行短信推送处理器
+//   - error: 错误信息，服务商不支持上行短信推送时返回错误
+func NewInboundHandler(provider string, callback InboundCallback) (*InboundHandler, error) {
+	if callback == nil {
+		return nil, fmt.Errorf("missing parameter: callback")
+	}
+
+	parse, ok := inboundParsers[provider]
+	if !ok {
+		return nil, fmt.Errorf("provider does not support inbound message callback: %s", provider)
+	}
+
+	return &InboundHandler{
+		provider: provider,
+		parse:    parse,
+		response: inboundResponses[provider],
+		callback: callback,
+	}, nil
+}
+
+// ServeHTTP 处理上行短信推送请求
+// 参数:
+//   - w: HTTP响应
+//   - r: HTTP请求
+func (h *InboundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)
+	messages, err := h.parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, message := range messages {
+		message.Provider = h.provider
+		h.callback(message)
+	}
+
+	if h.response.contentType != "" {
+		w.Header().Set("Content-Type", h.response.contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, h.response.body)
+}
+
+// PollReplies 定期拉取上行短信并交给回调函数，直到上下文结束
+// 拉取返回可重试的错误（见IsRetryable）时在下一个周期重试，其他错误在交付已拉取的上行短信后返回
+// 参数:
+//   - ctx: 上下文
+//   - provider: 短信服务提供商实例
+//   - interval: 拉取间隔，小于等于0时使用DEFAULT_POLL_INTERVAL
+//   - callback: 上行短信回调函数
+// 返回:
+//   - error: 上下文结束时返回上下文错误，服务商不支持拉取或拉取失败时返回错误
+func PollReplies(ctx context.Context, provider SmsProvider, interval time.Duration, callback InboundCallback) error {
+	puller, ok := provider.(ReplyPuller)
+	if !ok {
+		return fmt.Errorf("provider does not support pulling replies: %T", provider)
+	}
+	if callback == nil {
+		return fmt.Errorf("missing parameter: callback")
+	}
+	if interval <= 0 {
+		interval = DEFAULT_POLL_INTERVAL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		messages, err := puller.PullReplies(ctx)
+		for _, message := range messages {
+			callback(message)
+		}
+		if err != nil && ctx.Err() == nil && !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// InboundChannel 创建将上行短信发送到通道的回调函数
+// 通道已满时回调函数阻塞，直到通道可写
+// 参数:
+//   - ch: 上行短信通道
+// 返回:
+//   - InboundCallback: 上行短信回调函数
+func InboundChannel(ch chan<- *InboundMessage) InboundCallback {
+	return func(message *InboundMessage) {
+		ch <- message
+	}
+}
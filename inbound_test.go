@@ -0,0 +1,61 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubPuller 按顺序返回预设结果的上行短信拉取器
+type stubPuller struct {
+	stubProvider
+	pulls []stubPull // 每次拉取的结果
+}
+
+// stubPull 单次拉取的结果
+type stubPull struct {
+	messages []*InboundMessage // 上行短信列表
+	err      error             // 错误信息
+}
+
+// PullReplies 拉取上行短信
+func (s *stubPuller) PullReplies(ctx context.Context) ([]*InboundMessage, error) {
+	if len(s.pulls) == 0 {
+		return nil, nil
+	}
+	pull := s.pulls[0]
+	s.pulls = s.pulls[1:]
+	return pull.messages, pull.err
+}
+
+func TestPollReplies(t *testing.T) {
+	first := &InboundMessage{From: "+8613800138000", Text: "1"}
+	second := &InboundMessage{From: "+8613800138001", Text: "2"}
+
+	tests := []struct {
+		name      string
+		pulls     []stubPull
+		wantErr   error
+		wantCount int
+	}{
+		{"non-retryable error after pulling", []stubPull{{[]*InboundMessage{first, second}, ErrAuthFailed}}, ErrAuthFailed, 2},
+		{"retryable error then non-retryable", []stubPull{{[]*InboundMessage{first}, ErrServiceUnavailable}, {[]*InboundMessage{second}, ErrAuthFailed}}, ErrAuthFailed, 2},
+		{"non-retryable error without messages", []stubPull{{nil, ErrAuthFailed}}, ErrAuthFailed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []*InboundMessage
+			err := PollReplies(context.Background(), &stubPuller{pulls: tt.pulls}, time.Millisecond, func(message *InboundMessage) {
+				received = append(received, message)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PollReplies() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(received) != tt.wantCount {
+				t.Errorf("received %d messages, want %d", len(received), tt.wantCount)
+			}
+		})
+	}
+}
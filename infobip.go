@@ -1,134 +1,500 @@
-// Package sms Infobip短信服务实现
-package sms
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-)
-
-// InfobipClient Infobip短信客户端
-// 封装Infobip短信API调用
-type InfobipClient struct {
-	baseUrl  string // API基础URL
-	sender   string // 发送方标识
-	apiKey   string // API密钥
-	template string // 短信模板
-}
-
-// InfobipConfigService Infobip配置服务
-type InfobipConfigService struct {
-	baseUrl string // API基础URL
-	sender  string // 发送方标识
-	apiKey  string // API密钥
-}
-
-// SmsService 短信服务
-type SmsService struct {
-	configService InfobipConfigService // 配置服务
-}
-
-// MessageData 消息数据结构体
-type MessageData struct {
-	Messages []Message `json:"messages"` // 消息列表
-}
-
-// Message 消息结构体
-type Message struct {
-	From         string        `json:"from"`         // 发送方
-	Destinations []Destination `json:"destinations"` // 目标列表
-	Text         string        `json:"text"`         // 消息内容
-}
-
-// Destination 目标结构体
-type Destination struct {
-	To string `json:"to"` // 目标号码
-}
-
-// GetInfobipClient 创建Infobip短信客户端
-// 参数:
-//   - sender: 发送方标识
-//   - apiKey: API密钥
-//   - template: 短信模板
-//   - baseUrl: API基础URL列表
-// 返回:
-//   - *InfobipClient: Infobip短信客户端实例
-//   - error: 错误信息
-func GetInfobipClient(sender string, apiKey string, template string, baseUrl []string) (*InfobipClient, error) {
-	if len(baseUrl) == 0 {
-		return nil, fmt.Errorf("missing parameter: baseUrl")
-	}
-
-	infobipClient := &InfobipClient{
-		baseUrl:  baseUrl[0],
-		sender:   sender,
-		apiKey:   apiKey,
-		template: template,
-	}
-
-	return infobipClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *InfobipClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missin parer: trgetPhoneNumber")
-	}
-
-	mobile := targetPhoneNumber[0]
-
-	if strings.HasPrefix(mobile, "0") {
-		mobile = "886" + mobile[1:]
-	}
-	if strings.HasPrefix(mobile, "+") {
-		mobile = mobile[1:]
-	}
-
-	endpoint := fmt.Sprintf("%s/sms/2/text/advanced", c.baseUrl)
-	text := fmt.Sprintf(c.template, code)
-
-	messageData := MessageData{
-		Messages: []Message{
-			{
-				From: c.sender,
-				Destinations: []Destination{
-					{
-						To: mobile,
-					},
-				},
-				Text: text,
-			},
-		},
-	}
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("App %s", c.apiKey),
-		"Content-Type":  "application/json",
-	}
-
-	messageDataBytes, _ := json.Marshal(messageData)
-	req, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(messageDataBytes))
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	return nil
-}
+// Package sms Infobip短信服务实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// infobipNumberFormat Infobip要求的号码格式，国家代码加号码，不带"+"
+// 未包含国家代码的号码按台湾地区号码处理
+var infobipNumberFormat = numberFormat{region: "TW", domestic: phone.FORMAT_DIGITS, international: phone.FORMAT_DIGITS}
+
+// InfobipClient Infobip短信客户端
+// 封装Infobip短信API调用
+type InfobipClient struct {
+	baseUrl      string       // API基础URL
+	sender       string       // 发送方标识
+	apiKey       string       // API密钥
+	template     string       // 短信模板
+	notifyUrl    string       // 状态报告回调地址
+	httpClient   *http.Client // HTTP客户端
+	segmentLimit SegmentLimit // 拆分条数限制
+	renderMode   RenderMode   // 短信模板的渲染方式
+}
+
+// InfobipConfigService Infobip配置服务
+type InfobipConfigService struct {
+	baseUrl string // API基础URL
+	sender  string // 发送方标识
+	apiKey  string // API密钥
+}
+
+// SmsService 短信服务
+type SmsService struct {
+	configService InfobipConfigService // 配置服务
+}
+
+// MessageData 消息数据结构体
+type MessageData struct {
+	Messages []Message `json:"messages"` // 消息列表
+}
+
+// Message 消息结构体
+type Message struct {
+	From              string        `json:"from"`                        // 发送方
+	Destinations      []Destination `json:"destinations"`                // 目标列表
+	Text              string        `json:"text"`                        // 消息内容
+	NotifyUrl         string        `json:"notifyUrl,omitempty"`         // 状态报告回调地址
+	NotifyContentType string        `json:"notifyContentType,omitempty"` // 状态报告回调格式
+}
+
+// Destination 目标结构体
+type Destination struct {
+	To string `json:"to"` // 目标号码
+}
+
+// InfobipResponse Infobip发送响应结构体
+type InfobipResponse struct {
+	BulkId   string                 `json:"bulkId"`   // 批次ID
+	Messages []InfobipMessageResult `json:"messages"` // 各消息的发送结果
+}
+
+// InfobipMessageResult Infobip单条消息的发送结果
+type InfobipMessageResult struct {
+	MessageId string        `json:"messageId"` // 消息ID
+	To        string        `json:"to"`        // 目标号码
+	Status    InfobipStatus `json:"status"`    // 消息状态
+}
+
+// InfobipStatus Infobip消息状态
+type InfobipStatus struct {
+	GroupId     int    `json:"groupId"`     // 状态分组ID
+	GroupName   string `json:"groupName"`   // 状态分组名称
+	Id          int    `json:"id"`          // 状态ID
+	Name        string `json:"name"`        // 状态名称
+	Description string `json:"description"` // 状态描述
+}
+
+// InfobipReportsResponse Infobip发送日志查询响应和状态报告推送结构体
+type InfobipReportsResponse struct {
+	Results []InfobipReport `json:"results"` // 状态报告列表
+}
+
+// InfobipReport Infobip单条消息的状态报告
+// 发送日志查询接口和状态报告推送使用相同的格式
+type InfobipReport struct {
+	BulkId    string        `json:"bulkId"`    // 批次ID
+	MessageId string        `json:"messageId"` // 消息ID
+	To        string        `json:"to"`        // 目标号码
+	SentAt    string        `json:"sentAt"`    // 发送时间
+	DoneAt    string        `json:"doneAt"`    // 完成时间
+	Status    InfobipStatus `json:"status"`    // 消息状态
+	Error     InfobipStatus `json:"error"`     // 错误信息
+}
+
+// InfobipInboundResponse Infobip上行短信推送结构体
+type InfobipInboundResponse struct {
+	Results []InfobipInbound `json:"results"` // 上行短信列表
+}
+
+// InfobipInbound Infobip单条上行短信
+type InfobipInbound struct {
+	MessageId  string `json:"messageId"`  // 消息ID
+	From       string `json:"from"`       // 发送方号码
+	To         string `json:"to"`         // 接收方号码
+	Text       string `json:"text"`       // 短信内容
+	CleanText  string `json:"cleanText"`  // 去除关键字后的短信内容
+	Keyword    string `json:"keyword"`    // 关键字
+	ReceivedAt string `json:"receivedAt"` // 接收时间
+	SmsCount   int    `json:"smsCount"`   // 短信条数
+}
+
+// INFOBIP_TIME_LAYOUT Infobip接口的时间格式
+const INFOBIP_TIME_LAYOUT = "2006-01-02T15:04:05.000-0700"
+
+// Infobip状态分组
+const (
+	INFOBIP_GROUP_ACCEPTED      = 0 // 已受理
+	INFOBIP_GROUP_PENDING       = 1 // 等待中
+	INFOBIP_GROUP_UNDELIVERABLE = 2 // 无法送达
+	INFOBIP_GROUP_DELIVERED     = 3 // 已送达
+	INFOBIP_GROUP_EXPIRED       = 4 // 已过期
+	INFOBIP_GROUP_REJECTED      = 5 // 已拒绝
+)
+
+// infobipErrorCodes Infobip拒绝状态与标准错误的对应关系
+// 参考文档: https://www.infobip.com/docs/essentials/response-status-and-error-codes
+var infobipErrorCodes = map[string]error{
+	"REJECTED_NOT_ENOUGH_CREDITS":         ErrInsufficientBalance,
+	"REJECTED_PREPAID_PACKAGE_EXPIRED":    ErrInsufficientBalance,
+	"REJECTED_PREFIX_MISSING":             ErrInvalidNumber,
+	"REJECTED_DESTINATION":                ErrInvalidNumber,
+	"REJECTED_DESTINATION_NOT_REGISTERED": ErrInvalidNumber,
+	"REJECTED_NETWORK":                    ErrInvalidNumber,
+	"REJECTED_DND":                        ErrInvalidNumber,
+	"REJECTED_SENDER":                     ErrSignRejected,
+	"REJECTED_SOURCE":                     ErrSignRejected,
+	"REJECTED_FLOODING_FILTER":            ErrRateLimited,
+	"REJECTED_MESSAGE_TOO_LONG":           ErrContentBlocked,
+	"REJECTED_SYSTEM_ERROR":               ErrServiceUnavailable,
+}
+
+// accepted 判断消息是否已被Infobip受理
+// 返回:
+//   - bool: 是否已受理
+func (s InfobipStatus) accepted() bool {
+	return s.GroupId != INFOBIP_GROUP_REJECTED
+}
+
+// init 注册Infobip短信服务
+func init() {
+	Register(SMS_INFOBIP, func(config ProviderConfig) (SmsProvider, error) {
+		return GetInfobipClient(config.AccessId, config.AccessKey, config.Template, config.otherArgs(config.Endpoint))
+	}, "AccessId", "AccessKey", "Template", "Endpoint")
+}
+
+// GetInfobipClient 创建Infobip短信客户端
+// 参数:
+//   - sender: 发送方标识
+//   - apiKey: API密钥
+//   - template: 短信模板
+//   - baseUrl: API基础URL列表
+// 返回:
+//   - *InfobipClient: Infobip短信客户端实例
+//   - error: 错误信息
+func GetInfobipClient(sender string, apiKey string, template string, baseUrl []string) (*InfobipClient, error) {
+	if len(baseUrl) == 0 {
+		return nil, fmt.Errorf("missing parameter: baseUrl")
+	}
+
+	infobipClient := &InfobipClient{
+		baseUrl:    baseUrl[0],
+		sender:     sender,
+		apiKey:     apiKey,
+		template:   template,
+		httpClient: &http.Client{},
+	}
+
+	return infobipClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *InfobipClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *InfobipClient) SetEndpoint(endpoint string) {
+	c.baseUrl = endpoint
+}
+
+// SetStatusCallback 设置状态报告回调地址
+// 参数:
+//   - callbackUrl: 状态报告回调地址（notifyUrl），Infobip以JSON格式推送状态报告
+func (c *InfobipClient) SetStatusCallback(callbackUrl string) {
+	c.notifyUrl = callbackUrl
+}
+
+// SetSegmentLimit 设置短信拆分条数限制
+// 参数:
+//   - limit: 拆分条数限制
+func (c *InfobipClient) SetSegmentLimit(limit SegmentLimit) {
+	c.segmentLimit = limit
+}
+
+// SetMessageTemplate 设置短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (c *InfobipClient) SetMessageTemplate(text string, mode RenderMode) {
+	c.template = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *InfobipClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *InfobipClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	text, err := RenderMessage(c.template, c.renderMode, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := infobipNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/sms/2/text/advanced", c.baseUrl)
+	if err = c.segmentLimit.check(text); err != nil {
+		return nil, err
+	}
+
+	destinations := make([]Destination, 0, len(phoneNumbers))
+	for _, mobile := range phoneNumbers {
+		destinations = append(destinations, Destination{To: mobile})
+	}
+
+	messageData := MessageData{
+		Messages: []Message{
+			{
+				From:         c.sender,
+				Destinations: destinations,
+				Text:         text,
+			},
+		},
+	}
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("App %s", c.apiKey),
+		"Content-Type":  "application/json",
+	}
+
+	if c.notifyUrl != "" {
+		messageData.Messages[0].NotifyUrl = c.notifyUrl
+		messageData.Messages[0].NotifyContentType = "application/json"
+	}
+
+	messageDataBytes, _ := json.Marshal(messageData)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(messageDataBytes))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSendResult(SMS_INFOBIP)
+	result.Raw = string(respBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newHttpStatusError(SMS_INFOBIP, resp.StatusCode, string(respBody))
+	}
+
+	var infobipResponse InfobipResponse
+	if err = json.Unmarshal(respBody, &infobipResponse); err != nil {
+		return result, err
+	}
+
+	result.RequestId = infobipResponse.BulkId
+	for _, message := range infobipResponse.Messages {
+		result.add(&RecipientResult{
+			PhoneNumber: message.To,
+			MessageId:   message.MessageId,
+			Accepted:    message.Status.accepted(),
+			Code:        message.Status.Name,
+			Message:     message.Status.Description,
+		})
+	}
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+
+	errs := []error{}
+	for _, recipient := range result.Recipients {
+		if !recipient.Accepted {
+			errs = append(errs, newSmsError(SMS_INFOBIP, recipient.Code, recipient.Message, infobipErrorCodes).forNumber(recipient.PhoneNumber))
+		}
+	}
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	return result, nil
+}
+
+// QueryStatus 查询短信送达状态
+// 通过发送日志接口按消息ID查询，Infobip只保留最近48小时的发送日志
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询条件列表
+// 返回:
+//   - []*DeliveryReport: 与查询条件一一对应的状态报告
+//   - error: 错误信息
+func (c *InfobipClient) QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	reports := make([]*DeliveryReport, 0, len(queries))
+	for _, query := range queries {
+		if query.MessageId == "" {
+			return reports, fmt.Errorf("missing parameter: messageId")
+		}
+
+		endpoint := fmt.Sprintf("%s/sms/1/logs?messageId=%s", c.baseUrl, url.QueryEscape(query.MessageId))
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return reports, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("App %s", c.apiKey))
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return reports, err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return reports, err
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return reports, newHttpStatusError(SMS_INFOBIP, resp.StatusCode, string(respBody))
+		}
+
+		var reportsResponse InfobipReportsResponse
+		if err = json.Unmarshal(respBody, &reportsResponse); err != nil {
+			return reports, err
+		}
+
+		report := newDeliveryReport(SMS_INFOBIP, query)
+		for _, item := range reportsResponse.Results {
+			if item.MessageId == query.MessageId {
+				report = item.deliveryReport()
+				if report.PhoneNumber == "" {
+					report.PhoneNumber = query.PhoneNumber
+				}
+				break
+			}
+		}
+		report.Raw = string(respBody)
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// parseInfobipReport 解析Infobip状态报告推送请求
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseInfobipReport(r *http.Request) ([]*DeliveryReport, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var reportsResponse InfobipReportsResponse
+	if err = json.Unmarshal(body, &reportsResponse); err != nil {
+		return nil, fmt.Errorf("bad callback body: %w", err)
+	}
+
+	reports := make([]*DeliveryReport, 0, len(reportsResponse.Results))
+	for _, item := range reportsResponse.Results {
+		report := item.deliveryReport()
+		if raw, err := json.Marshal(item); err == nil {
+			report.Raw = string(raw)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// deliveryReport 转换为标准化的送达状态报告
+// 返回:
+//   - *DeliveryReport: 送达状态报告
+func (r InfobipReport) deliveryReport() *DeliveryReport {
+	report := &DeliveryReport{
+		Provider:    SMS_INFOBIP,
+		MessageId:   r.MessageId,
+		PhoneNumber: r.To,
+		Status:      DELIVERY_UNKNOWN,
+		Code:        r.Status.Name,
+		Message:     r.Status.Description,
+		SentAt:      parseTime(INFOBIP_TIME_LAYOUT, r.SentAt, time.UTC),
+		DeliveredAt: parseTime(INFOBIP_TIME_LAYOUT, r.DoneAt, time.UTC),
+	}
+	if r.Error.Id != 0 {
+		report.Code = r.Error.Name
+		report.Message = r.Error.Description
+	}
+
+	switch r.Status.GroupId {
+	case INFOBIP_GROUP_ACCEPTED:
+		report.Status = DELIVERY_QUEUED
+	case INFOBIP_GROUP_PENDING:
+		report.Status = DELIVERY_SENT
+	case INFOBIP_GROUP_DELIVERED:
+		report.Status = DELIVERY_DELIVERED
+	case INFOBIP_GROUP_UNDELIVERABLE, INFOBIP_GROUP_EXPIRED, INFOBIP_GROUP_REJECTED:
+		report.Status = DELIVERY_FAILED
+	}
+	return report
+}
+
+// parseInfobipInbound 解析Infobip上行短信推送请求
+// 需在Infobip控制台为号码配置上行短信的转发地址（JSON格式）
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []*InboundMessage: 上行短信列表
+//   - error: 错误信息
+func parseInfobipInbound(r *http.Request) ([]*InboundMessage, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var inboundResponse InfobipInboundResponse
+	if err = json.Unmarshal(body, &inboundResponse); err != nil {
+		return nil, fmt.Errorf("bad callback body: %w", err)
+	}
+
+	messages := make([]*InboundMessage, 0, len(inboundResponse.Results))
+	for _, item := range inboundResponse.Results {
+		message := &InboundMessage{
+			MessageId:  item.MessageId,
+			From:       item.From,
+			To:         item.To,
+			Text:       item.Text,
+			ReceivedAt: parseTime(INFOBIP_TIME_LAYOUT, item.ReceivedAt, time.UTC),
+		}
+		if raw, err := json.Marshal(item); err == nil {
+			message.Raw = string(raw)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,248 @@
+// Package sms 短信服务提供商配置加载
+package sms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件格式
+const (
+	CONFIG_FORMAT_YAML = "yaml" // YAML格式
+	CONFIG_FORMAT_JSON = "json" // JSON格式
+)
+
+// SECRET_FILE_PREFIX 从文件读取配置值的前缀
+// 配置值形如"file:/run/secrets/aliyun_key"时，使用该文件的内容（去除首尾空白）作为配置值
+const SECRET_FILE_PREFIX = "file:"
+
+// ENV_PREFIX 环境变量前缀
+// 环境变量SMS_<实例名>_<字段名>会覆盖配置文件中对应实例的字段，如SMS_PRIMARY_ACCESS_KEY
+const ENV_PREFIX = "SMS_"
+
+// configFields ProviderConfig中可通过配置文件和环境变量设置的字段
+var configFields = []string{
+	"Provider", "AccessId", "AccessKey", "Sign", "Template",
+	"Region", "Endpoint", "Sender", "AppId", "SmsAccount", "ProjectId", "GoodsId",
+	"StatusCallback",
+}
+
+// ProvidersConfig 多个服务提供商实例的配置
+// 配置文件示例（YAML）:
+//
+//	providers:
+//	  primary:
+//	    provider: Aliyun_SMS
+//	    accessId: your_access_id
+//	    accessKey: file:/run/secrets/aliyun_access_key
+//	    sign: your_sign
+//	    template: SMS_123456
+type ProvidersConfig struct {
+	Providers map[string]ProviderConfig `json:"providers" yaml:"providers"` // 按实例名索引的服务提供商配置
+}
+
+// LoadConfig 从文件加载服务提供商配置
+// 根据文件扩展名（.yaml、.yml、.json）确定格式，加载后依次应用环境变量覆盖和文件引用的密钥
+// 参数:
+//   - path: 配置文件路径
+// 返回:
+//   - *ProvidersConfig: 服务提供商配置
+//   - error: 错误信息
+func LoadConfig(path string) (*ProvidersConfig, error) {
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = CONFIG_FORMAT_YAML
+	case ".json":
+		format = CONFIG_FORMAT_JSON
+	default:
+		return nil, fmt.Errorf("unsupported config file: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data, format)
+}
+
+// ParseConfig 解析服务提供商配置
+// 解析后依次应用环境变量覆盖和文件引用的密钥
+// 参数:
+//   - data: 配置内容
+//   - format: 配置格式（CONFIG_FORMAT_YAML或CONFIG_FORMAT_JSON）
+// 返回:
+//   - *ProvidersConfig: 服务提供商配置
+//   - error: 错误信息
+func ParseConfig(data []byte, format string) (*ProvidersConfig, error) {
+	config := &ProvidersConfig{}
+
+	var err error
+	switch format {
+	case CONFIG_FORMAT_YAML:
+		err = yaml.Unmarshal(data, config)
+	case CONFIG_FORMAT_JSON:
+		err = json.Unmarshal(data, config)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Providers == nil {
+		config.Providers = make(map[string]ProviderConfig)
+	}
+
+	config.applyEnv(os.Environ())
+
+	if err = config.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// NewProviders 根据配置创建所有服务提供商实例
+// 返回:
+//   - map[string]SmsProvider: 按实例名索引的服务提供商实例
+//   - error: 错误信息，任一实例创建失败时返回
+func (c *ProvidersConfig) NewProviders() (map[string]SmsProvider, error) {
+	providers := make(map[string]SmsProvider, len(c.Providers))
+	for _, name := range c.names() {
+		provider, err := NewSmsProviderFromConfig(c.Providers[name])
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		providers[name] = provider
+	}
+	return providers, nil
+}
+
+// LoadProviders 从文件加载配置并创建所有服务提供商实例
+// 参数:
+//   - path: 配置文件路径
+// 返回:
+//   - map[string]SmsProvider: 按实例名索引的服务提供商实例
+//   - error: 错误信息
+func LoadProviders(path string) (map[string]SmsProvider, error) {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewProviders()
+}
+
+// names 获取排序后的实例名
+// 返回:
+//   - []string: 实例名列表
+func (c *ProvidersConfig) names() []string {
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyEnv 应用环境变量覆盖
+// 已配置的实例按SMS_<实例名>_<字段名>覆盖字段；
+// 配置文件中不存在的实例只要设置了SMS_<实例名>_PROVIDER也会被创建，实例名为小写形式
+// 参数:
+//   - environ: 环境变量列表（KEY=VALUE格式）
+func (c *ProvidersConfig) applyEnv(environ []string) {
+	env := make(map[string]string, len(environ))
+	for _, item := range environ {
+		key, value, ok := strings.Cut(item, "=")
+		if ok && strings.HasPrefix(key, ENV_PREFIX) {
+			env[key] = value
+		}
+	}
+
+	providerSuffix := "_" + envName("Provider")
+	for key := range env {
+		if !strings.HasSuffix(key, providerSuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, ENV_PREFIX), providerSuffix)
+		if name != "" && c.lookupEnvName(name) == "" {
+			c.Providers[strings.ToLower(name)] = ProviderConfig{}
+		}
+	}
+
+	for name, config := range c.Providers {
+		for _, field := range configFields {
+			if value, ok := env[ENV_PREFIX+envName(name)+"_"+envName(field)]; ok {
+				config.setFieldValue(field, value)
+			}
+		}
+		c.Providers[name] = config
+	}
+}
+
+// lookupEnvName 根据环境变量中的实例名查找已配置的实例
+// 参数:
+//   - name: 环境变量中的实例名
+// 返回:
+//   - string: 已配置的实例名，未找到时返回空字符串
+func (c *ProvidersConfig) lookupEnvName(name string) string {
+	for configName := range c.Providers {
+		if envName(configName) == name {
+			return configName
+		}
+	}
+	return ""
+}
+
+// resolveSecrets 读取以file:前缀引用的配置值
+// 返回:
+//   - error: 错误信息
+func (c *ProvidersConfig) resolveSecrets() error {
+	for name, config := range c.Providers {
+		for _, field := range configFields {
+			value := config.fieldValue(field)
+			if !strings.HasPrefix(value, SECRET_FILE_PREFIX) {
+				continue
+			}
+
+			secret, err := os.ReadFile(strings.TrimPrefix(value, SECRET_FILE_PREFIX))
+			if err != nil {
+				return fmt.Errorf("provider %s: read %s: %w", name, field, err)
+			}
+			config.setFieldValue(field, strings.TrimSpace(string(secret)))
+		}
+		c.Providers[name] = config
+	}
+	return nil
+}
+
+// envName 将实例名或字段名转换为环境变量名片段
+// 驼峰转为下划线分隔，非字母数字字符替换为下划线，结果为大写，如AccessKey转为ACCESS_KEY
+// 参数:
+//   - name: 实例名或字段名
+// 返回:
+//   - string: 环境变量名片段
+func envName(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			builder.WriteRune('_')
+			builder.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(unicode.ToUpper(r))
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,159 @@
+package sms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testYamlConfig = `
+providers:
+  primary:
+    provider: Aliyun_SMS
+    accessId: file_id
+    accessKey: file_key
+    sign: file_sign
+  backup-cn:
+    provider: Tencent_Cloud_SMS
+    accessKey: backup_key
+`
+
+const testJsonConfig = `{
+  "providers": {
+    "primary": {"provider": "Aliyun_SMS", "accessId": "file_id", "accessKey": "file_key", "sign": "file_sign"},
+    "backup-cn": {"provider": "Tencent_Cloud_SMS", "accessKey": "backup_key"}
+  }
+}`
+
+func TestParseConfigEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "access_key")
+	if err := os.WriteFile(secret, []byte("  secret_key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		instance string
+		field    string
+		want     string
+	}{
+		{"file value without env", nil, "primary", "AccessKey", "file_key"},
+		{"env overrides file value", map[string]string{"SMS_PRIMARY_ACCESS_KEY": "env_key"}, "primary", "AccessKey", "env_key"},
+		{"env sets missing field", map[string]string{"SMS_PRIMARY_TEMPLATE": "SMS_123"}, "primary", "Template", "SMS_123"},
+		{"env overrides with empty value", map[string]string{"SMS_PRIMARY_SIGN": ""}, "primary", "Sign", ""},
+		{"env for other instance", map[string]string{"SMS_BACKUP_CN_ACCESS_KEY": "env_key"}, "primary", "AccessKey", "file_key"},
+		{"instance name with dash", map[string]string{"SMS_BACKUP_CN_ACCESS_KEY": "env_key"}, "backup-cn", "AccessKey", "env_key"},
+		{"env secret file", map[string]string{"SMS_PRIMARY_ACCESS_KEY": "file:" + secret}, "primary", "AccessKey", "secret_key"},
+		{"instance created from env", map[string]string{"SMS_EXTRA_PROVIDER": "Aliyun_SMS", "SMS_EXTRA_ACCESS_ID": "env_id"}, "extra", "AccessId", "env_id"},
+	}
+
+	for _, format := range []string{CONFIG_FORMAT_YAML, CONFIG_FORMAT_JSON} {
+		data := testYamlConfig
+		if format == CONFIG_FORMAT_JSON {
+			data = testJsonConfig
+		}
+
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				for key, value := range tt.env {
+					t.Setenv(key, value)
+				}
+
+				config, err := ParseConfig([]byte(data), format)
+				if err != nil {
+					t.Fatalf("ParseConfig() error = %v", err)
+				}
+				provider, ok := config.Providers[tt.instance]
+				if !ok {
+					t.Fatalf("instance %s not found in %v", tt.instance, config.names())
+				}
+				if got := provider.fieldValue(tt.field); got != tt.want {
+					t.Errorf("%s.%s = %q, want %q", tt.instance, tt.field, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseConfigEnvWithoutProvider(t *testing.T) {
+	t.Setenv("SMS_EXTRA_ACCESS_ID", "env_id")
+
+	config, err := ParseConfig([]byte(testYamlConfig), CONFIG_FORMAT_YAML)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	if _, ok := config.Providers["extra"]; ok {
+		t.Error("instance extra created without SMS_EXTRA_PROVIDER")
+	}
+	if len(config.Providers) != 2 {
+		t.Errorf("got %d instances, want 2", len(config.Providers))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "access_key")
+	if err := os.WriteFile(secret, []byte("secret_key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"yaml", "sms.yaml", testYamlConfig, "file_key", false},
+		{"yml", "sms.yml", testYamlConfig, "file_key", false},
+		{"json", "sms.json", testJsonConfig, "file_key", false},
+		{"secret file", "secret.yaml", "providers:\n  primary:\n    accessKey: file:" + secret + "\n", "secret_key", false},
+		{"missing secret file", "missing.yaml", "providers:\n  primary:\n    accessKey: file:" + filepath.Join(dir, "missing") + "\n", "", true},
+		{"invalid yaml", "invalid.yaml", "providers: [", "", true},
+		{"invalid json", "invalid.json", "{", "", true},
+		{"unsupported extension", "sms.toml", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := config.Providers["primary"].AccessKey; got != tt.want {
+				t.Errorf("primary.AccessKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"AccessKey", "ACCESS_KEY"},
+		{"StatusCallback", "STATUS_CALLBACK"},
+		{"AppId", "APP_ID"},
+		{"primary", "PRIMARY"},
+		{"backup-cn", "BACKUP_CN"},
+		{"sms.v2", "SMS_V2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := envName(tt.name); got != tt.want {
+				t.Errorf("envName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
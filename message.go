@@ -0,0 +1,177 @@
+// Package sms 短信内容编码检测与拆分条数计算
+package sms
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 短信编码常量定义
+const (
+	ENCODING_GSM7 = "GSM-7" // GSM 03.38默认字母表，每个字符7位
+	ENCODING_UCS2 = "UCS-2" // 内容包含GSM-7字母表以外的字符时使用，每个字符16位
+)
+
+// 单条短信和长短信每段的容量，长短信每段需要预留用户数据头（UDH）
+const (
+	GSM7_SINGLE_LENGTH  = 160 // GSM-7单条短信的septet数
+	GSM7_SEGMENT_LENGTH = 153 // GSM-7长短信每段的septet数
+	UCS2_SINGLE_LENGTH  = 70  // UCS-2单条短信的UTF-16码元数
+	UCS2_SEGMENT_LENGTH = 67  // UCS-2长短信每段的UTF-16码元数
+)
+
+// gsm7Basic GSM-7默认字母表（不含转义字符ESC）
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension GSM-7扩展字母表，每个字符需要转义字符和字符本身两个septet
+const gsm7Extension = "\f^{}\\[~]|€"
+
+// MessageInfo 短信内容分析结果
+type MessageInfo struct {
+	Encoding   string // 编码（ENCODING_GSM7或ENCODING_UCS2）
+	Characters int    // 字符数
+	Units      int    // 编码单位数，GSM-7为septet数（扩展字符计2），UCS-2为UTF-16码元数（辅助平面字符如emoji计2）
+	Segments   int    // 拆分条数，内容为空时为0
+	NonGsm     []rune // 导致使用UCS-2编码的字符（去重，按出现顺序）
+}
+
+// AnalyzeMessage 分析短信内容的编码、字符数和拆分条数
+// 长短信按每段容量依次拆分，扩展字符的转义序列和UTF-16代理对不会被拆到两段中
+// 注意: 服务商可能对内容做额外处理（如添加签名、替换字符），实际计费条数以服务商为准
+// 参数:
+//   - text: 短信内容
+// 返回:
+//   - *MessageInfo: 分析结果
+func AnalyzeMessage(text string) *MessageInfo {
+	info := &MessageInfo{Encoding: ENCODING_GSM7}
+
+	sizes := make([]int, 0, len(text))
+	for _, c := range text {
+		info.Characters++
+		switch {
+		case strings.ContainsRune(gsm7Basic, c):
+			sizes = append(sizes, 1)
+		case strings.ContainsRune(gsm7Extension, c):
+			sizes = append(sizes, 2)
+		default:
+			sizes = append(sizes, 0)
+			info.Encoding = ENCODING_UCS2
+			if !strings.ContainsRune(string(info.NonGsm), c) {
+				info.NonGsm = append(info.NonGsm, c)
+			}
+		}
+	}
+
+	single, segment := GSM7_SINGLE_LENGTH, GSM7_SEGMENT_LENGTH
+	if info.Encoding == ENCODING_UCS2 {
+		single, segment = UCS2_SINGLE_LENGTH, UCS2_SEGMENT_LENGTH
+		sizes = sizes[:0]
+		for _, c := range text {
+			if c > 0xFFFF {
+				sizes = append(sizes, 2)
+			} else {
+				sizes = append(sizes, 1)
+			}
+		}
+	}
+
+	for _, size := range sizes {
+		info.Units += size
+	}
+	if info.Units == 0 {
+		return info
+	}
+	if info.Units <= single {
+		info.Segments = 1
+		return info
+	}
+
+	used := 0
+	info.Segments = 1
+	for _, size := range sizes {
+		if used+size > segment {
+			info.Segments++
+			used = 0
+		}
+		used += size
+	}
+	return info
+}
+
+// SegmentLimit 短信拆分条数限制
+// 用于在本地渲染短信内容的服务商（Twilio、亚马逊SNS、Infobip、互亿无线和短信宝），
+// 避免模板参数中的字符使内容切换为UCS-2编码或超出预期条数，导致费用成倍增加
+type SegmentLimit struct {
+	MaxSegments int                     // 最大拆分条数，为0时不限制
+	Reject      bool                    // 超出限制时是否拒绝发送，为false时只调用Warn
+	Warn        func(info *MessageInfo) // 超出限制时的回调函数，可用于记录日志或告警
+}
+
+// SegmentLimitError 短信拆分条数超出限制错误
+// 在请求服务商之前返回，同时满足errors.Is(err, ErrInvalidParameter)
+type SegmentLimitError struct {
+	Info        *MessageInfo // 短信内容分析结果
+	MaxSegments int          // 最大拆分条数
+}
+
+// Error 获取错误信息
+// 返回:
+//   - string: 错误信息
+func (e *SegmentLimitError) Error() string {
+	return fmt.Sprintf("message too long: %d segments (%s, %d characters), limit %d", e.Info.Segments, e.Info.Encoding, e.Info.Characters, e.MaxSegments)
+}
+
+// Unwrap 获取标准错误
+// 返回:
+//   - error: 标准错误ErrInvalidParameter
+func (e *SegmentLimitError) Unwrap() error {
+	return ErrInvalidParameter
+}
+
+// check 检查短信内容的拆分条数
+// 参数:
+//   - text: 短信内容
+// 返回:
+//   - error: 超出限制且设置为拒绝发送时返回*SegmentLimitError
+func (l SegmentLimit) check(text string) error {
+	if l.MaxSegments <= 0 {
+		return nil
+	}
+
+	info := AnalyzeMessage(text)
+	if info.Segments <= l.MaxSegments {
+		return nil
+	}
+	if l.Warn != nil {
+		l.Warn(info)
+	}
+	if l.Reject {
+		return &SegmentLimitError{Info: info, MaxSegments: l.MaxSegments}
+	}
+	return nil
+}
+
+// SegmentLimiter 支持限制短信拆分条数的短信服务提供商
+type SegmentLimiter interface {
+	// SetSegmentLimit 设置短信拆分条数限制
+	// 参数:
+	//   - limit: 拆分条数限制
+	SetSegmentLimit(limit SegmentLimit)
+}
+
+// SetSegmentLimit 为短信服务提供商设置拆分条数限制
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - limit: 拆分条数限制
+// 返回:
+//   - error: 错误信息，服务商不支持限制拆分条数时返回错误
+func SetSegmentLimit(provider SmsProvider, limit SegmentLimit) error {
+	limiter, ok := provider.(SegmentLimiter)
+	if !ok {
+		return fmt.Errorf("provider does not support segment limit: %T", provider)
+	}
+
+	limiter.SetSegmentLimit(limit)
+	return nil
+}
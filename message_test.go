@@ -0,0 +1,96 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantEncoding string
+		wantChars    int
+		wantUnits    int
+		wantSegments int
+	}{
+		{"empty", "", ENCODING_GSM7, 0, 0, 0},
+		{"gsm single", strings.Repeat("a", 160), ENCODING_GSM7, 160, 160, 1},
+		{"gsm one over single", strings.Repeat("a", 161), ENCODING_GSM7, 161, 161, 2},
+		{"gsm two full segments", strings.Repeat("a", 306), ENCODING_GSM7, 306, 306, 2},
+		{"gsm three segments", strings.Repeat("a", 307), ENCODING_GSM7, 307, 307, 3},
+		{"extension counts twice", "{}", ENCODING_GSM7, 2, 4, 1},
+		{"euro pushes over single", strings.Repeat("a", 159) + "€", ENCODING_GSM7, 160, 161, 2},
+		{"euro not split at boundary", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 152), ENCODING_GSM7, 305, 306, 3},
+		{"ucs2 single", strings.Repeat("中", 70), ENCODING_UCS2, 70, 70, 1},
+		{"ucs2 one over single", strings.Repeat("中", 71), ENCODING_UCS2, 71, 71, 2},
+		{"ucs2 two full segments", strings.Repeat("中", 134), ENCODING_UCS2, 134, 134, 2},
+		{"ucs2 three segments", strings.Repeat("中", 135), ENCODING_UCS2, 135, 135, 3},
+		{"gsm text with one chinese character", strings.Repeat("a", 69) + "中", ENCODING_UCS2, 70, 70, 1},
+		{"emoji counts twice", strings.Repeat("😀", 35), ENCODING_UCS2, 35, 70, 1},
+		{"emoji over single", strings.Repeat("😀", 36), ENCODING_UCS2, 36, 72, 2},
+		{"emoji not split at boundary", strings.Repeat("中", 66) + "😀" + strings.Repeat("中", 66), ENCODING_UCS2, 133, 134, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := AnalyzeMessage(tt.text)
+			if info.Encoding != tt.wantEncoding {
+				t.Errorf("Encoding = %s, want %s", info.Encoding, tt.wantEncoding)
+			}
+			if info.Characters != tt.wantChars {
+				t.Errorf("Characters = %d, want %d", info.Characters, tt.wantChars)
+			}
+			if info.Units != tt.wantUnits {
+				t.Errorf("Units = %d, want %d", info.Units, tt.wantUnits)
+			}
+			if info.Segments != tt.wantSegments {
+				t.Errorf("Segments = %d, want %d", info.Segments, tt.wantSegments)
+			}
+		})
+	}
+}
+
+func TestAnalyzeMessageNonGsm(t *testing.T) {
+	info := AnalyzeMessage("中a文中😀€")
+	if got, want := string(info.NonGsm), "中文😀"; got != want {
+		t.Errorf("NonGsm = %q, want %q", got, want)
+	}
+}
+
+func TestSegmentLimit(t *testing.T) {
+	long := strings.Repeat("中", 71)
+
+	tests := []struct {
+		name     string
+		limit    SegmentLimit
+		text     string
+		wantErr  bool
+		wantWarn bool
+	}{
+		{"unlimited", SegmentLimit{}, long, false, false},
+		{"within limit", SegmentLimit{MaxSegments: 2, Reject: true}, long, false, false},
+		{"warn only", SegmentLimit{MaxSegments: 1}, long, false, true},
+		{"reject", SegmentLimit{MaxSegments: 1, Reject: true}, long, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warned := false
+			tt.limit.Warn = func(info *MessageInfo) {
+				warned = true
+			}
+			err := tt.limit.check(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidParameter) {
+				t.Errorf("check() error = %v, want ErrInvalidParameter", err)
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
@@ -1,33 +1,95 @@
-// Package sms 模拟短信服务实现
-package sms
-
-// Mocker 模拟短信客户端
-// 用于测试环境，不实际发送短信
-type Mocker struct{}
-
-// 确保Mocker实现了SmsProvider接口
-var _ SmsProvider = &Mocker{}
-
-// NewMocker 创建模拟短信客户端
-// 参数:
-//   - accessId: 访问ID（模拟用，不实际使用）
-//   - accessKey: 访问密钥（模拟用，不实际使用）
-//   - sign: 短信签名（模拟用，不实际使用）
-//   - templateId: 短信模板ID（模拟用，不实际使用）
-//   - smsAccount: 短信账户（模拟用，不实际使用）
-// 返回:
-//   - *Mocker: 模拟短信客户端实例
-//   - error: 错误信息
-func NewMocker(accessId, accessKey, sign, templateId string, smsAccount []string) (*Mocker, error) {
-	return &Mocker{}, nil
-}
-
-// SendMessage 模拟发送短信
-// 参数:
-//   - param: 短信模板参数（不实际使用）
-//   - targetPhoneNumber: 目标手机号码列表（不实际使用）
-// 返回:
-//   - error: 始终返回nil（模拟成功）
-func (m *Mocker) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	return nil
-}
+// Package sms 模拟短信服务实现
+package sms
+
+import (
+	"context"
+	"net/http"
+)
+
+// Mocker 模拟短信客户端
+// 用于测试环境，不实际发送短信
+type Mocker struct{}
+
+// 确保Mocker实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &Mocker{}
+
+// init 注册模拟短信服务
+func init() {
+	Register(SMS_MOCK, func(config ProviderConfig) (SmsProvider, error) {
+		return NewMocker(config.AccessId, config.AccessKey, config.Sign, config.Template, nil)
+	})
+}
+
+// NewMocker 创建模拟短信客户端
+// 参数:
+//   - accessId: 访问ID（模拟用，不实际使用）
+//   - accessKey: 访问密钥（模拟用，不实际使用）
+//   - sign: 短信签名（模拟用，不实际使用）
+//   - templateId: 短信模板ID（模拟用，不实际使用）
+//   - smsAccount: 短信账户（模拟用，不实际使用）
+// 返回:
+//   - *Mocker: 模拟短信客户端实例
+//   - error: 错误信息
+func NewMocker(accessId, accessKey, sign, templateId string, smsAccount []string) (*Mocker, error) {
+	return &Mocker{}, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 模拟客户端不发送HTTP请求，设置的客户端不会被使用
+// 参数:
+//   - client: HTTP客户端（模拟用，不实际使用）
+func (m *Mocker) SetHTTPClient(client *http.Client) {}
+
+// SetEndpoint 设置服务端点
+// 模拟客户端不发送请求，设置的服务端点不会被使用
+// 参数:
+//   - endpoint: 服务端点（模拟用，不实际使用）
+func (m *Mocker) SetEndpoint(endpoint string) {}
+
+// SetStatusCallback 设置状态报告回调地址
+// 模拟客户端不会推送状态报告，设置的回调地址不会被使用
+// 参数:
+//   - callbackUrl: 状态报告回调地址（模拟用，不实际使用）
+func (m *Mocker) SetStatusCallback(callbackUrl string) {}
+
+// SendMessage 模拟发送短信
+// 参数:
+//   - param: 短信模板参数（不实际使用）
+//   - targetPhoneNumber: 目标手机号码列表（不实际使用）
+// 返回:
+//   - error: 始终返回nil（模拟成功）
+func (m *Mocker) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := m.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（不实际使用）
+//   - targetPhoneNumber: 目标手机号码列表（不实际使用）
+// 返回:
+//   - *SendResult: 发送结果（所有号码均标记为已受理）
+//   - error: 始终返回nil（模拟成功）
+func (m *Mocker) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	result := newSendResult(SMS_MOCK)
+	result.acceptAll("", targetPhoneNumber)
+	return result, nil
+}
+
+// QueryStatus 模拟查询短信送达状态
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询条件列表
+// 返回:
+//   - []*DeliveryReport: 状态报告（所有查询均标记为已送达）
+//   - error: 始终返回nil（模拟成功）
+func (m *Mocker) QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	reports := make([]*DeliveryReport, 0, len(queries))
+	for _, query := range queries {
+		report := newDeliveryReport(SMS_MOCK, query)
+		report.Status = DELIVERY_DELIVERED
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
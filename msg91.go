@@ -1,122 +1,339 @@
-// Package sms Msg91短信服务实现
-package sms
-
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"strings"
-)
-
-// Msg91Client Msg91短信客户端
-// 封装Msg91短信API调用
-type Msg91Client struct {
-	authKey    string // 认证密钥
-	senderId   string // 发送方ID
-	templateId string // 模板ID
-}
-
-// GetMsg91Client 创建Msg91短信客户端
-// 参数:
-//   - senderId: 发送方ID
-//   - authKey: 认证密钥
-//   - templateId: 模板ID
-// 返回:
-//   - *Msg91Client: Msg91短信客户端实例
-//   - error: 错误信息
-func GetMsg91Client(senderId string, authKey string, templateId string) (*Msg91Client, error) {
-	msg91Client := &Msg91Client{
-		authKey:    authKey,
-		senderId:   senderId,
-		templateId: templateId,
-	}
-
-	return msg91Client, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (m *Msg91Client) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	url := "https://control.msg91.com/api/v5/flow/"
-
-	for _, mobile := range targetPhoneNumber {
-		if strings.HasPrefix(mobile, "+") {
-			mobile = mobile[1:]
-		}
-
-		payload, err := buildPayload(m.templateId, m.senderId, "0", mobile, param)
-		if err != nil {
-			return fmt.Errorf("SMS build payload failed: %v", err)
-		}
-
-		err = postMsg91SendRequest(url, strings.NewReader(payload), m.authKey)
-		if err != nil {
-			return fmt.Errorf("send message failed: %v", err)
-		}
-	}
-
-	return nil
-}
-
-// buildPayload 构建请求负载
-// 参数:
-//   - templateId: 模板ID
-//   - senderId: 发送方ID
-//   - shortURL: 短链接
-//   - mobiles: 手机号码
-//   - variables: 变量参数
-// 返回:
-//   - string: JSON格式的请求负载
-//   - error: 错误信息
-func buildPayload(templateId, senderId, shortURL, mobiles string, variables map[string]string) (string, error) {
-	payload := make(map[string]interface{})
-
-	payload["template_id"] = templateId
-	payload["sender"] = senderId
-	payload["short_url"] = shortURL
-	payload["mobiles"] = mobiles
-
-	for k, v := range variables {
-		payload[k] = v
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	return string(jsonData), nil
-}
-
-// postMsg91SendRequest 发送Msg91请求
-// 参数:
-//   - url: 请求URL
-//   - payload: 请求负载
-//   - authKey: 认证密钥
-// 返回:
-//   - error: 错误信息
-func postMsg91SendRequest(url string, payload io.Reader, authKey string) error {
-	req, _ := http.NewRequest("POST", url, payload)
-
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("authkey", authKey)
-
-	res, _ := http.DefaultClient.Do(req)
-
-	err := res.Body.Close()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+// Package sms Msg91短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// MSG91_ENDPOINT Msg91默认服务端点
+const MSG91_ENDPOINT = "https://control.msg91.com"
+
+// msg91NumberFormat Msg91要求的号码格式，国家代码加号码，不带"+"
+// 未包含国家代码的号码按印度号码处理
+var msg91NumberFormat = numberFormat{region: "IN", domestic: phone.FORMAT_DIGITS, international: phone.FORMAT_DIGITS}
+
+// Msg91Client Msg91短信客户端
+// 封装Msg91短信API调用
+type Msg91Client struct {
+	authKey    string       // 认证密钥
+	senderId   string       // 发送方ID
+	templateId string       // 模板ID
+	endpoint   string       // 服务端点
+	httpClient *http.Client // HTTP客户端
+}
+
+// MSG91_BALANCE_ROUTE Msg91余额查询的路由类型（4为事务类短信）
+const MSG91_BALANCE_ROUTE = "4"
+
+// msg91Location Msg91状态报告使用的时区（印度标准时间）
+var msg91Location = time.FixedZone("IST", 5*60*60+30*60)
+
+// Msg91DeliveryReport Msg91状态报告推送结构体
+type Msg91DeliveryReport struct {
+	RequestId string              `json:"requestId"` // 请求ID，即发送时返回的消息ID
+	UserId    string              `json:"userId"`    // 用户ID
+	SenderId  string              `json:"senderId"`  // 发送方ID
+	Report    []Msg91NumberReport `json:"report"`    // 各号码的状态
+}
+
+// Msg91NumberReport Msg91单个号码的状态
+type Msg91NumberReport struct {
+	Date   string `json:"date"`   // 状态时间
+	Number string `json:"number"` // 接收方号码
+	Status string `json:"status"` // 状态码（1为已送达，2、9、16、17、25为发送失败）
+	Desc   string `json:"desc"`   // 状态描述
+}
+
+// Msg91Response Msg91响应结构体
+type Msg91Response struct {
+	Type    string `json:"type"`    // 响应类型（success/error）
+	Message string `json:"message"` // 成功时为请求ID，失败时为错误信息
+}
+
+// init 注册Msg91短信服务
+func init() {
+	Register(SMS_MSG91, func(config ProviderConfig) (SmsProvider, error) {
+		return GetMsg91Client(config.AccessId, config.AccessKey, config.Template)
+	}, "AccessId", "AccessKey", "Template")
+}
+
+// GetMsg91Client 创建Msg91短信客户端
+// 参数:
+//   - senderId: 发送方ID
+//   - authKey: 认证密钥
+//   - templateId: 模板ID
+// 返回:
+//   - *Msg91Client: Msg91短信客户端实例
+//   - error: 错误信息
+func GetMsg91Client(senderId string, authKey string, templateId string) (*Msg91Client, error) {
+	msg91Client := &Msg91Client{
+		authKey:    authKey,
+		senderId:   senderId,
+		templateId: templateId,
+		endpoint:   MSG91_ENDPOINT,
+		httpClient: &http.Client{},
+	}
+
+	return msg91Client, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (m *Msg91Client) SetHTTPClient(client *http.Client) {
+	m.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为MSG91_ENDPOINT
+func (m *Msg91Client) SetEndpoint(endpoint string) {
+	m.endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (m *Msg91Client) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := m.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (m *Msg91Client) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	url := joinEndpoint(m.endpoint, "/api/v5/flow/")
+
+	result := newSendResult(SMS_MSG91)
+	for _, phoneNumber := range targetPhoneNumber {
+		mobile, err := msg91NumberFormat.format(phoneNumber)
+		if err != nil {
+			return result, err
+		}
+
+		payload, err := buildPayload(m.templateId, m.senderId, "0", mobile, param)
+		if err != nil {
+			return result, fmt.Errorf("SMS build payload failed: %v", err)
+		}
+
+		respBody, statusCode, err := postMsg91SendRequest(ctx, m.httpClient, url, strings.NewReader(payload), m.authKey)
+		if err != nil {
+			return result, fmt.Errorf("send message failed: %w", err)
+		}
+
+		var msg91Response Msg91Response
+		if err = json.Unmarshal(respBody, &msg91Response); err != nil {
+			if statusCode >= http.StatusInternalServerError {
+				return result, newHttpStatusError(SMS_MSG91, statusCode, string(respBody))
+			}
+			return result, fmt.Errorf("send message failed: %v", err)
+		}
+
+		recipient := result.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			Accepted:    msg91Response.Type == "success",
+			Code:        msg91Response.Type,
+			Raw:         string(respBody),
+		})
+		if !recipient.Accepted {
+			recipient.Message = msg91Response.Message
+			// Msg91未返回错误码，按HTTP状态码归类
+			return result, newHttpStatusError(SMS_MSG91, statusCode, msg91Response.Message).forNumber(phoneNumber)
+		}
+		// 发送成功时message字段为请求ID
+		recipient.MessageId = msg91Response.Message
+	}
+
+	return result, nil
+}
+
+// buildPayload 构建请求负载
+// 参数:
+//   - templateId: 模板ID
+//   - senderId: 发送方ID
+//   - shortURL: 短链接
+//   - mobiles: 手机号码
+//   - variables: 变量参数
+// 返回:
+//   - string: JSON格式的请求负载
+//   - error: 错误信息
+func buildPayload(templateId, senderId, shortURL, mobiles string, variables map[string]string) (string, error) {
+	payload := make(map[string]interface{})
+
+	payload["template_id"] = templateId
+	payload["sender"] = senderId
+	payload["short_url"] = shortURL
+	payload["mobiles"] = mobiles
+
+	for k, v := range variables {
+		payload[k] = v
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonData), nil
+}
+
+// postMsg91SendRequest 发送Msg91请求
+// 参数:
+//   - ctx: 上下文
+//   - client: HTTP客户端
+//   - url: 请求URL
+//   - payload: 请求负载
+//   - authKey: 认证密钥
+// 返回:
+//   - []byte: 响应内容
+//   - int: HTTP状态码
+//   - error: 错误信息
+func postMsg91SendRequest(ctx context.Context, client *http.Client, url string, payload io.Reader, authKey string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("content-type", "application/json")
+	req.Header.Add("authkey", authKey)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, res.StatusCode, nil
+}
+
+// QueryBalance 查询账户余额
+// 通过balance.php接口查询MSG91_BALANCE_ROUTE路由的剩余积分
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为积分
+//   - error: 错误信息
+func (m *Msg91Client) QueryBalance(ctx context.Context) (*Balance, error) {
+	query := url.Values{}
+	query.Set("authkey", m.authKey)
+	query.Set("type", MSG91_BALANCE_ROUTE)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", joinEndpoint(m.endpoint, "/api/balance.php?"+query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// 成功时响应内容为余额数值，失败时为错误信息
+	amount, err := strconv.ParseFloat(strings.TrimSpace(string(respBody)), 64)
+	if err != nil {
+		// Msg91未返回错误码，按HTTP状态码归类
+		return nil, newHttpStatusError(SMS_MSG91, resp.StatusCode, string(respBody))
+	}
+
+	return &Balance{
+		Provider: SMS_MSG91,
+		Amount:   amount,
+		Unit:     BALANCE_UNIT_CREDITS,
+		Raw:      string(respBody),
+	}, nil
+}
+
+// parseMsg91Report 解析Msg91状态报告推送请求
+// 回调地址需在Msg91控制台配置，请求体为JSON或包含JSON的data表单字段
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseMsg91Report(r *http.Request) ([]*DeliveryReport, error) {
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		body, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		data = body
+	} else {
+		form, err := readForm(r)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(form.Get("data"))
+	}
+
+	var items []Msg91DeliveryReport
+	if err := json.Unmarshal(data, &items); err != nil {
+		var item Msg91DeliveryReport
+		if err = json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("bad callback body: %w", err)
+		}
+		items = []Msg91DeliveryReport{item}
+	}
+
+	reports := make([]*DeliveryReport, 0)
+	for _, item := range items {
+		for _, number := range item.Report {
+			report := &DeliveryReport{
+				MessageId:   item.RequestId,
+				PhoneNumber: number.Number,
+				Status:      DELIVERY_UNKNOWN,
+				Code:        number.Status,
+				Message:     number.Desc,
+				DeliveredAt: parseTime(time.DateTime, number.Date, msg91Location),
+			}
+			switch number.Status {
+			case "1":
+				report.Status = DELIVERY_DELIVERED
+			case "2", "9", "16", "17", "25":
+				report.Status = DELIVERY_FAILED
+			}
+			if raw, err := json.Marshal(number); err == nil {
+				report.Raw = string(raw)
+			}
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
@@ -1,127 +1,283 @@
-// Package sms Netgsm短信服务实现
-package sms
-
-import (
-	"bytes"
-	"encoding/xml"
-	"errors"
-	"fmt"
-	"io"
-	"net/http"
-)
-
-// NetgsmClient Netgsm短信客户端
-// 封装Netgsm短信API调用
-type NetgsmClient struct {
-	accessId   string       // 访问ID
-	accessKey  string       // 访问密钥
-	sign       string       // 短信签名
-	template   string       // 短信模板
-	httpClient *http.Client // HTTP客户端
-}
-
-// NetgsmResponse Netgsm响应结构体
-type NetgsmResponse struct {
-	Code  string `xml:"main>code"`  // 响应代码
-	JobId string `xml:"main>jobId"` // 任务ID
-	Error string `xml:"main>error"` // 错误信息
-}
-
-// GetNetgsmClient 创建Netgsm短信客户端
-// 参数:
-//   - accessId: 访问ID
-//   - accessKey: 访问密钥
-//   - sign: 短信签名
-//   - template: 短信模板
-// 返回:
-//   - *NetgsmClient: Netgsm短信客户端实例
-//   - error: 错误信息
-func GetNetgsmClient(accessId, accessKey, sign, template string) (*NetgsmClient, error) {
-	return &NetgsmClient{
-		accessId:   accessId,
-		accessKey:  accessKey,
-		sign:       sign,
-		template:   template,
-		httpClient: &http.Client{},
-	}, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *NetgsmClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	for _, phoneNumber := range targetPhoneNumber {
-		data := fmt.Sprintf(`
-<mainbody>
-   <header>
-       <usercode>%s</usercode>
-       <password>%s</password>
-       <msgheader>%s</msgheader>
-   </header>
-   <body>
-       <msg>
-           <![CDATA[%s]]>
-       </msg>
-       <no>%s</no>
-   </body>
-</mainbody>`, c.accessId, c.accessKey, c.sign, c.template, phoneNumber)
-
-		headers := map[string]string{
-			"Content-Type": "application/xml",
-		}
-
-		respBody, err := c.postXML("https://api.netgsm.com.tr/sms/send/otp", data, headers)
-		if err != nil {
-			return err
-		}
-
-		var netgsmResponse NetgsmResponse
-		if err := xml.Unmarshal([]byte(respBody), &netgsmResponse); err != nil {
-			return err
-		}
-
-		if netgsmResponse.Code != "0" {
-			return errors.New(netgsmResponse.Error)
-		}
-	}
-	return nil
-}
-
-// postXML 发送XML格式的POST请求
-// 参数:
-//   - url: 请求URL
-//   - xmlData: XML数据
-//   - headers: 请求头
-// 返回:
-//   - string: 响应内容
-//   - error: 错误信息
-func (c *NetgsmClient) postXML(url, xmlData string, headers map[string]string) (string, error) {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(xmlData)))
-	if err != nil {
-		return "", err
-	}
-
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	return string(respBody), nil
-}
+// Package sms Netgsm短信服务实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// NETGSM_ENDPOINT Netgsm默认服务端点
+const NETGSM_ENDPOINT = "https://api.netgsm.com.tr"
+
+// netgsmNumberFormat Netgsm要求的号码格式
+// 土耳其号码不带国家代码和长途前缀0（如5XXXXXXXXX），其他号码为国家代码加号码，不带"+"
+var netgsmNumberFormat = numberFormat{region: "TR", domestic: phone.FORMAT_NATIONAL, international: phone.FORMAT_DIGITS}
+
+// NetgsmClient Netgsm短信客户端
+// 封装Netgsm短信API调用
+type NetgsmClient struct {
+	accessId   string       // 访问ID
+	accessKey  string       // 访问密钥
+	sign       string       // 短信签名
+	template   string       // 短信模板
+	endpoint   string       // 服务端点
+	httpClient *http.Client // HTTP客户端
+	renderMode RenderMode   // 短信模板的渲染方式，为空时原样发送短信模板
+}
+
+// netgsmErrorCodes Netgsm OTP接口错误码与标准错误的对应关系
+var netgsmErrorCodes = map[string]error{
+	"20":  ErrContentBlocked,      // 短信内容有误或超出长度
+	"30":  ErrAuthFailed,          // 用户名、密码错误或无API权限
+	"40":  ErrSignRejected,        // 发送方标识未在系统中定义
+	"41":  ErrSignRejected,        // 发送方标识未在系统中定义
+	"50":  ErrInvalidNumber,       // 接收号码有误
+	"60":  ErrInsufficientBalance, // 账户未开通OTP短信套餐
+	"70":  ErrInvalidParameter,    // 请求参数有误
+	"80":  ErrRateLimited,         // 超出发送频率限制
+	"100": ErrServiceUnavailable,  // 系统错误
+}
+
+// NetgsmResponse Netgsm响应结构体
+type NetgsmResponse struct {
+	Code  string `xml:"main>code"`  // 响应代码
+	JobId string `xml:"main>jobId"` // 任务ID
+	Error string `xml:"main>error"` // 错误信息
+}
+
+// NETGSM_BALANCE_UNIT Netgsm余额的货币单位（土耳其里拉）
+const NETGSM_BALANCE_UNIT = "TRY"
+
+// init 注册Netgsm短信服务
+func init() {
+	Register(SMS_NETGSM, func(config ProviderConfig) (SmsProvider, error) {
+		return GetNetgsmClient(config.AccessId, config.AccessKey, config.Sign, config.Template)
+	}, "AccessId", "AccessKey", "Sign", "Template")
+}
+
+// GetNetgsmClient 创建Netgsm短信客户端
+// 参数:
+//   - accessId: 访问ID
+//   - accessKey: 访问密钥
+//   - sign: 短信签名
+//   - template: 短信模板
+// 返回:
+//   - *NetgsmClient: Netgsm短信客户端实例
+//   - error: 错误信息
+func GetNetgsmClient(accessId, accessKey, sign, template string) (*NetgsmClient, error) {
+	return &NetgsmClient{
+		accessId:   accessId,
+		accessKey:  accessKey,
+		sign:       sign,
+		template:   template,
+		endpoint:   NETGSM_ENDPOINT,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *NetgsmClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为NETGSM_ENDPOINT
+func (c *NetgsmClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SetMessageTemplate 设置短信模板
+// 未设置时原样发送创建客户端时传入的短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式，为空时使用RENDER_PLACEHOLDER
+func (c *NetgsmClient) SetMessageTemplate(text string, mode RenderMode) {
+	if mode == "" {
+		mode = RENDER_PLACEHOLDER
+	}
+	c.template = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *NetgsmClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *NetgsmClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	msg := c.template
+	if c.renderMode != "" {
+		rendered, err := RenderMessage(c.template, c.renderMode, param)
+		if err != nil {
+			return nil, err
+		}
+		msg = rendered
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	result := newSendResult(SMS_NETGSM)
+	for _, phoneNumber := range targetPhoneNumber {
+		mobile, err := netgsmNumberFormat.format(phoneNumber)
+		if err != nil {
+			return result, err
+		}
+
+		data := fmt.Sprintf(`
+<mainbody>
+   <header>
+       <usercode>%s</usercode>
+       <password>%s</password>
+       <msgheader>%s</msgheader>
+   </header>
+   <body>
+       <msg>
+           <![CDATA[%s]]>
+       </msg>
+       <no>%s</no>
+   </body>
+</mainbody>`, c.accessId, c.accessKey, c.sign, msg, mobile)
+
+		headers := map[string]string{
+			"Content-Type": "application/xml",
+		}
+
+		respBody, err := c.postXML(ctx, joinEndpoint(c.endpoint, "/sms/send/otp"), data, headers)
+		if err != nil {
+			return result, err
+		}
+
+		var netgsmResponse NetgsmResponse
+		if err := xml.Unmarshal([]byte(respBody), &netgsmResponse); err != nil {
+			return result, err
+		}
+
+		result.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			MessageId:   netgsmResponse.JobId,
+			Accepted:    netgsmResponse.Code == "0",
+			Code:        netgsmResponse.Code,
+			Message:     netgsmResponse.Error,
+			Raw:         respBody,
+		})
+
+		if netgsmResponse.Code != "0" {
+			return result, newSmsError(SMS_NETGSM, netgsmResponse.Code, netgsmResponse.Error, netgsmErrorCodes).forNumber(phoneNumber)
+		}
+	}
+	return result, nil
+}
+
+// QueryBalance 查询账户余额
+// 通过/balance/list/get接口查询剩余信用额度
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为土耳其里拉
+//   - error: 错误信息
+func (c *NetgsmClient) QueryBalance(ctx context.Context) (*Balance, error) {
+	query := url.Values{}
+	query.Set("usercode", c.accessId)
+	query.Set("password", c.accessKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", joinEndpoint(c.endpoint, "/balance/list/get/?"+query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_NETGSM, resp.StatusCode, string(respBody))
+	}
+
+	// 成功时响应内容为"00 余额"，余额使用逗号作为小数点；失败时为错误码
+	fields := strings.Fields(string(respBody))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("bad response: %q", string(respBody))
+	}
+	if fields[0] != "00" || len(fields) < 2 {
+		return nil, newSmsError(SMS_NETGSM, fields[0], "balance query failed", netgsmErrorCodes)
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad response: %q", string(respBody))
+	}
+
+	return &Balance{
+		Provider: SMS_NETGSM,
+		Amount:   amount,
+		Unit:     NETGSM_BALANCE_UNIT,
+		Raw:      string(respBody),
+	}, nil
+}
+
+// postXML 发送XML格式的POST请求
+// 参数:
+//   - ctx: 上下文
+//   - url: 请求URL
+//   - xmlData: XML数据
+//   - headers: 请求头
+// 返回:
+//   - string: 响应内容
+//   - error: 错误信息
+func (c *NetgsmClient) postXML(ctx context.Context, url, xmlData string, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(xmlData)))
+	if err != nil {
+		return "", err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(respBody), nil
+}
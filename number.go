@@ -0,0 +1,87 @@
+// Package sms 接收方号码格式化
+package sms
+
+import (
+	"fmt"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// numberFormat 服务商要求的接收方号码格式
+type numberFormat struct {
+	region        string       // 服务商所在地区（ISO 3166-1二字母代码），未包含国家代码的号码按该地区解析；为空时号码必须以"+"或"00"开头
+	domestic      phone.Format // 服务商所在地区号码的格式
+	international phone.Format // 其他地区号码的格式，为空时不支持其他地区的号码
+}
+
+// parse 解析号码并校验服务商是否支持该号码所属地区
+// 参数:
+//   - phoneNumber: 调用方传入的号码
+// 返回:
+//   - *phone.Number: 解析后的号码
+//   - bool: 号码是否属于服务商所在地区
+//   - error: 错误信息
+func (f numberFormat) parse(phoneNumber string) (*phone.Number, bool, error) {
+	number, err := phone.Parse(phoneNumber, f.region)
+	if err != nil {
+		return nil, false, err
+	}
+
+	domestic := f.region == "" || number.InRegion(f.region)
+	if !domestic && f.international == "" {
+		return nil, false, fmt.Errorf("%w: unsupported country code: %s", ErrInvalidNumber, phoneNumber)
+	}
+	return number, domestic, nil
+}
+
+// format 将号码转换为服务商要求的格式
+// 参数:
+//   - phoneNumber: 调用方传入的号码
+// 返回:
+//   - string: 服务商要求格式的号码
+//   - error: 错误信息，号码无效或服务商不支持该地区时返回ErrInvalidNumber
+func (f numberFormat) format(phoneNumber string) (string, error) {
+	number, domestic, err := f.parse(phoneNumber)
+	if err != nil {
+		return "", err
+	}
+	if domestic {
+		return number.Format(f.domestic), nil
+	}
+	return number.Format(f.international), nil
+}
+
+// formatAll 将号码列表转换为服务商要求的格式
+// 参数:
+//   - phoneNumbers: 调用方传入的号码列表
+// 返回:
+//   - []string: 与号码列表一一对应的服务商要求格式的号码
+//   - error: 错误信息，任一号码无效时返回
+func (f numberFormat) formatAll(phoneNumbers []string) ([]string, error) {
+	formatted := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		mobile, err := f.format(phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		formatted = append(formatted, mobile)
+	}
+	return formatted, nil
+}
+
+// restoreNumbers 将服务商返回的号码还原为调用方传入的号码
+// 适用于在响应中回显接收方号码的批量接口，保证故障转移和路由能按原始号码合并结果
+// 参数:
+//   - formatted: 发送给服务商的号码列表
+//   - phoneNumbers: 与formatted一一对应的调用方传入的号码列表
+func (r *SendResult) restoreNumbers(formatted []string, phoneNumbers []string) {
+	originals := make(map[string]string, len(formatted))
+	for i, mobile := range formatted {
+		originals[mobile] = phoneNumbers[i]
+	}
+	for _, recipient := range r.Recipients {
+		if phoneNumber, ok := originals[recipient.PhoneNumber]; ok {
+			recipient.PhoneNumber = phoneNumber
+		}
+	}
+}
@@ -1,126 +1,330 @@
-// Package sms OSON短信服务实现
-package sms
-
-import (
-	"crypto/sha256"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
-	"time"
-
-	"github.com/google/uuid"
-)
-
-// OsonClient OSON短信客户端
-// 封装OSON短信API调用
-type OsonClient struct {
-	Endpoint         string // API端点
-	SenderId         string // 发送方ID
-	SecretAccessHash string // 访问密钥哈希
-	Sign             string // 短信签名
-	Message          string // 短信内容
-}
-
-// OsonResponse OSON响应结构体
-type OsonResponse struct {
-	Status        string    `json:"status"`          // 状态 (ok)
-	Timestamp     time.Time `json:"timestamp"`       // 时间戳 (2017-07-07 16:58:12)
-	TxnId         string    `json:"txn_id"`          // 交易ID
-	MsgId         uint      `json:"msg_id"`          // 消息ID (40127)
-	SmscMsgId     string    `json:"smsc_msg_id"`     // SMSC消息ID
-	SmscMsgStatus string    `json:"smsc_msg_status"` // SMSC消息状态
-	SmscMsgParts  string    `json:"smsc_msg_parts"`  // SMSC消息部分
-}
-
-// GetOsonClient 创建OSON短信客户端
-// 参数:
-//   - senderId: 发送方ID
-//   - secretAccessHash: 访问密钥哈希
-//   - sign: 短信签名
-//   - message: 短信内容
-//
-// 返回:
-//   - *OsonClient: OSON短信客户端实例
-//   - error: 错误信息
-func GetOsonClient(senderId, secretAccessHash, sign, message string) (*OsonClient, error) {
-	return &OsonClient{
-		Endpoint:         "https://api.osonsms.com/sendsms_v1.php",
-		SenderId:         senderId,
-		SecretAccessHash: secretAccessHash,
-		Sign:             sign,
-		Message:          message,
-	}, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-//
-// 返回:
-//   - error: 错误信息
-func (c *OsonClient) SendMessage(param map[string]string, targetPhoneNumber ...string) (err error) {
-	// 初始化HTTP客户端用于向短信中心发送请求
-	// 设置25+秒的超时时间以确保短信中心的响应已被处理
-	client := &http.Client{
-		Timeout: 20 * time.Second,
-	}
-
-	if c.Message == "" {
-		c.Message = fmt.Sprintf("Hello. Your authorization code: %s", param["code"])
-	} else {
-		c.Message += param["code"]
-	}
-
-	txnId := uuid.NewString()
-	buildStrHash := strings.Join([]string{txnId, c.SenderId, c.Sign, targetPhoneNumber[0], c.SecretAccessHash}, ";")
-
-	hash := sha256.New()
-	hash.Write([]byte(buildStrHash))
-	bs := hash.Sum(nil)
-	strHash := fmt.Sprintf("%x", bs)
-
-	urlLink, err := url.Parse(c.Endpoint)
-	if err != nil {
-		return
-	}
-
-	urlParams := url.Values{}
-	urlParams.Add("from", c.Sign)
-	urlParams.Add("phone_number", targetPhoneNumber[0])
-	urlParams.Add("msg", c.Message)
-	urlParams.Add("str_hash", strHash)
-	urlParams.Add("txn_id", txnId)
-	urlParams.Add("login", c.SenderId)
-
-	urlLink.RawQuery = urlParams.Encode()
-
-	request, err := http.NewRequest(http.MethodGet, urlLink.String(), nil)
-	if err != nil {
-		return
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return
-	}
-
-	resultBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	var result OsonResponse
-	if err = json.Unmarshal(resultBytes, &result); err != nil {
-		return
-	}
-
-	if result.Status != "ok" {
-		return fmt.Errorf("sms service returned error status not 200: Status Code: %d Error: %s", resp.StatusCode, string(resultBytes))
-	}
-
-	return
-}
+// Package sms OSON短信服务实现
+package sms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// OSON_ENDPOINT OSON默认服务端点
+const OSON_ENDPOINT = "https://api.osonsms.com"
+
+// OSON_DEFAULT_MESSAGE OSON未设置短信内容时使用的默认模板
+const OSON_DEFAULT_MESSAGE = "Hello. Your authorization code: {code}"
+
+// osonNumberFormat OSON要求的号码格式，国家代码加号码，不带"+"
+// 未包含国家代码的号码按塔吉克斯坦号码处理
+var osonNumberFormat = numberFormat{region: "TJ", domestic: phone.FORMAT_DIGITS, international: phone.FORMAT_DIGITS}
+
+// OsonClient OSON短信客户端
+// 封装OSON短信API调用
+type OsonClient struct {
+	Endpoint         string       // API端点
+	SenderId         string       // 发送方ID
+	SecretAccessHash string       // 访问密钥哈希
+	Sign             string       // 短信签名
+	Message          string       // 短信内容模板，不含占位符时在末尾追加code参数
+	httpClient       *http.Client // HTTP客户端
+	renderMode       RenderMode   // 短信内容模板的渲染方式
+}
+
+// OsonResponse OSON响应结构体
+type OsonResponse struct {
+	Status        string      `json:"status"`          // 状态 (ok)
+	Timestamp     string      `json:"timestamp"`       // 时间戳 (2017-07-07 16:58:12)
+	TxnId         string      `json:"txn_id"`          // 交易ID
+	MsgId         uint        `json:"msg_id"`          // 消息ID (40127)
+	SmscMsgId     string      `json:"smsc_msg_id"`     // SMSC消息ID
+	SmscMsgStatus string      `json:"smsc_msg_status"` // SMSC消息状态
+	SmscMsgParts  json.Number `json:"smsc_msg_parts"`  // SMSC消息部分（数字或数字字符串）
+}
+
+// OSON_BALANCE_UNIT OSON余额的货币单位（塔吉克斯坦索莫尼）
+const OSON_BALANCE_UNIT = "TJS"
+
+// OsonBalanceResponse OSON余额查询响应结构体
+type OsonBalanceResponse struct {
+	Balance   float64 `json:"balance"`   // 余额
+	Timestamp string  `json:"timestamp"` // 时间戳
+	Error     *struct {
+		Code int    `json:"code"` // 错误码
+		Msg  string `json:"msg"`  // 错误信息
+	} `json:"error"` // 错误信息，查询成功时为空
+}
+
+// init 注册OSON短信服务
+func init() {
+	Register(SMS_OSONI, func(config ProviderConfig) (SmsProvider, error) {
+		return GetOsonClient(config.AccessId, config.AccessKey, config.Sign, config.Template)
+	}, "AccessId", "AccessKey", "Sign")
+}
+
+// GetOsonClient 创建OSON短信客户端
+// 参数:
+//   - senderId: 发送方ID
+//   - secretAccessHash: 访问密钥哈希
+//   - sign: 短信签名
+//   - message: 短信内容
+//
+// 返回:
+//   - *OsonClient: OSON短信客户端实例
+//   - error: 错误信息
+func GetOsonClient(senderId, secretAccessHash, sign, message string) (*OsonClient, error) {
+	return &OsonClient{
+		Endpoint:         joinEndpoint(OSON_ENDPOINT, "/sendsms_v1.php"),
+		SenderId:         senderId,
+		SecretAccessHash: secretAccessHash,
+		Sign:             sign,
+		Message:          message,
+		// 设置25+秒的超时时间以确保短信中心的响应已被处理
+		httpClient: &http.Client{
+			Timeout: 20 * time.Second,
+		},
+	}, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *OsonClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 请求地址为endpoint拼接"/sendsms_v1.php"，默认为OSON_ENDPOINT
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *OsonClient) SetEndpoint(endpoint string) {
+	c.Endpoint = joinEndpoint(endpoint, "/sendsms_v1.php")
+}
+
+// SetMessageTemplate 设置短信内容模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (c *OsonClient) SetMessageTemplate(text string, mode RenderMode) {
+	c.Message = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - error: 错误信息
+func (c *OsonClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *OsonClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	message, err := c.renderMessage(param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := osonNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// OSON每次请求只支持一个号码，逐个发送
+	sendResult := newSendResult(SMS_OSONI)
+	for i, mobile := range phoneNumbers {
+		recipient, err := c.send(ctx, message, targetPhoneNumber[i], mobile)
+		if recipient != nil {
+			sendResult.add(recipient)
+		}
+		if err != nil {
+			return sendResult, err
+		}
+	}
+
+	return sendResult, nil
+}
+
+// send 向单个号码发送短信
+// 参数:
+//   - ctx: 上下文
+//   - message: 短信内容
+//   - phoneNumber: 调用方传入的手机号码
+//   - mobile: OSON格式的手机号码
+//
+// 返回:
+//   - *RecipientResult: 接收方发送结果，未收到响应时为nil
+//   - error: 错误信息
+func (c *OsonClient) send(ctx context.Context, message string, phoneNumber string, mobile string) (*RecipientResult, error) {
+	txnId := uuid.NewString()
+	buildStrHash := strings.Join([]string{txnId, c.SenderId, c.Sign, mobile, c.SecretAccessHash}, ";")
+
+	hash := sha256.New()
+	hash.Write([]byte(buildStrHash))
+	bs := hash.Sum(nil)
+	strHash := fmt.Sprintf("%x", bs)
+
+	urlLink, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	urlParams := url.Values{}
+	urlParams.Add("from", c.Sign)
+	urlParams.Add("phone_number", mobile)
+	urlParams.Add("msg", message)
+	urlParams.Add("str_hash", strHash)
+	urlParams.Add("txn_id", txnId)
+	urlParams.Add("login", c.SenderId)
+
+	urlLink.RawQuery = urlParams.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, urlLink.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	resultBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_OSONI, resp.StatusCode, string(resultBytes)).forNumber(phoneNumber)
+	}
+
+	var result OsonResponse
+	if err = json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, err
+	}
+
+	recipient := &RecipientResult{
+		PhoneNumber: phoneNumber,
+		MessageId:   strconv.FormatUint(uint64(result.MsgId), 10),
+		Accepted:    result.Status == "ok",
+		Code:        result.Status,
+		Raw:         string(resultBytes),
+	}
+	if parts, err := strconv.Atoi(result.SmscMsgParts.String()); err == nil {
+		recipient.Segments = parts
+	}
+
+	if !recipient.Accepted {
+		// OSON未返回错误码，按HTTP状态码归类
+		return recipient, newHttpStatusError(SMS_OSONI, resp.StatusCode, string(resultBytes)).forNumber(phoneNumber)
+	}
+
+	return recipient, nil
+}
+
+// renderMessage 渲染短信内容
+// 未设置短信内容时使用OSON_DEFAULT_MESSAGE；占位符模式下模板不含占位符和fmt格式化动词时，在末尾追加code参数
+// 参数:
+//   - param: 短信模板参数
+//
+// 返回:
+//   - string: 短信内容
+//   - error: 错误信息
+func (c *OsonClient) renderMessage(param map[string]string) (string, error) {
+	text := c.Message
+	if text == "" {
+		text = OSON_DEFAULT_MESSAGE
+	}
+
+	tmpl, err := ParseMessageTemplate(text, c.renderMode)
+	if err != nil {
+		return "", err
+	}
+	if tmpl.tmpl == nil && !tmpl.legacy && len(tmpl.Variables()) == 0 {
+		return RenderMessage(text+"{code}", c.renderMode, param)
+	}
+	return tmpl.Render(param)
+}
+
+// QueryBalance 查询账户余额
+// 通过check_balance.php接口查询，请求地址与发送接口位于同一服务端点
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为塔吉克斯坦索莫尼
+//   - error: 错误信息
+func (c *OsonClient) QueryBalance(ctx context.Context) (*Balance, error) {
+	txnId := uuid.NewString()
+	buildStrHash := strings.Join([]string{txnId, c.SenderId, c.SecretAccessHash}, ";")
+	strHash := fmt.Sprintf("%x", sha256.Sum256([]byte(buildStrHash)))
+
+	urlLink, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/sendsms_v1.php") + "/check_balance.php")
+	if err != nil {
+		return nil, err
+	}
+
+	urlParams := url.Values{}
+	urlParams.Add("login", c.SenderId)
+	urlParams.Add("txn_id", txnId)
+	urlParams.Add("str_hash", strHash)
+	urlLink.RawQuery = urlParams.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, urlLink.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	resultBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_OSONI, resp.StatusCode, string(resultBytes))
+	}
+
+	var result OsonBalanceResponse
+	if err = json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, newSmsError(SMS_OSONI, strconv.Itoa(result.Error.Code), result.Error.Msg, nil).withStatus(resp.StatusCode)
+	}
+
+	return &Balance{
+		Provider: SMS_OSONI,
+		Amount:   result.Balance,
+		Unit:     OSON_BALANCE_UNIT,
+		Raw:      string(resultBytes),
+	}, nil
+}
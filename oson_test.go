@@ -0,0 +1,72 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOsonSendResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantAccepted  bool
+		wantMessageId string
+		wantSegments  int
+		wantErr       bool
+	}{
+		{
+			name:          "numeric parts",
+			body:          `{"status":"ok","timestamp":"2017-07-07 16:58:12","txn_id":"ae9c2fa5","msg_id":40127,"smsc_msg_id":"45f22479","smsc_msg_status":"success","smsc_msg_parts":1}`,
+			wantAccepted:  true,
+			wantMessageId: "40127",
+			wantSegments:  1,
+		},
+		{
+			name:          "string parts",
+			body:          `{"status":"ok","timestamp":"2017-07-07 16:58:12","txn_id":"ae9c2fa5","msg_id":40128,"smsc_msg_id":"45f22480","smsc_msg_status":"success","smsc_msg_parts":"2"}`,
+			wantAccepted:  true,
+			wantMessageId: "40128",
+			wantSegments:  2,
+		},
+		{
+			name:    "rejected",
+			body:    `{"status":"error","timestamp":"2017-07-07 16:58:12","txn_id":"ae9c2fa5"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client, err := GetOsonClient("login", "hash", "sender", "Your code: ")
+			if err != nil {
+				t.Fatalf("GetOsonClient() error = %v", err)
+			}
+			client.SetEndpoint(server.URL)
+
+			result, err := client.SendMessageContext(context.Background(), map[string]string{"code": "123456"}, "+992900000000")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendMessageContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result.Recipients) != 1 {
+				t.Fatalf("got %d recipients, want 1", len(result.Recipients))
+			}
+			recipient := result.Recipients[0]
+			if recipient.Accepted != tt.wantAccepted {
+				t.Errorf("Accepted = %v, want %v", recipient.Accepted, tt.wantAccepted)
+			}
+			if tt.wantAccepted && recipient.MessageId != tt.wantMessageId {
+				t.Errorf("MessageId = %q, want %q", recipient.MessageId, tt.wantMessageId)
+			}
+			if recipient.Segments != tt.wantSegments {
+				t.Errorf("Segments = %d, want %d", recipient.Segments, tt.wantSegments)
+			}
+		})
+	}
+}
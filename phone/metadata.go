@@ -0,0 +1,92 @@
+// Package phone 号码校验元数据
+package phone
+
+// mobileRule 地区手机号码规则
+type mobileRule struct {
+	lengths  []int    // 手机号码国内有效号码的长度
+	prefixes []string // 手机号段前缀，为空时不校验号段
+}
+
+// callingCodes 已分配的三位数国家代码（一位和两位数的国家代码见twoDigitCodes）
+var callingCodes = map[string]bool{
+	"211": true, "212": true, "213": true, "216": true, "218": true, "220": true, "221": true,
+	"222": true, "223": true, "224": true, "225": true, "226": true, "227": true, "228": true,
+	"229": true, "230": true, "231": true, "232": true, "233": true, "234": true, "235": true,
+	"236": true, "237": true, "238": true, "239": true, "240": true, "241": true, "242": true,
+	"243": true, "244": true, "245": true, "246": true, "247": true, "248": true, "249": true,
+	"250": true, "251": true, "252": true, "253": true, "254": true, "255": true, "256": true,
+	"257": true, "258": true, "260": true, "261": true, "262": true, "263": true, "264": true,
+	"265": true, "266": true, "267": true, "268": true, "269": true, "290": true, "291": true,
+	"297": true, "298": true, "299": true, "350": true, "351": true, "352": true, "353": true,
+	"354": true, "355": true, "356": true, "357": true, "358": true, "359": true, "370": true,
+	"371": true, "372": true, "373": true, "374": true, "375": true, "376": true, "377": true,
+	"378": true, "380": true, "381": true, "382": true, "383": true, "385": true, "386": true,
+	"387": true, "389": true, "420": true, "421": true, "423": true, "500": true, "501": true,
+	"502": true, "503": true, "504": true, "505": true, "506": true, "507": true, "508": true,
+	"509": true, "590": true, "591": true, "592": true, "593": true, "594": true, "595": true,
+	"596": true, "597": true, "598": true, "599": true, "670": true, "672": true, "673": true,
+	"674": true, "675": true, "676": true, "677": true, "678": true, "679": true, "680": true,
+	"681": true, "682": true, "683": true, "685": true, "686": true, "687": true, "688": true,
+	"689": true, "690": true, "691": true, "692": true, "850": true, "852": true, "853": true,
+	"855": true, "856": true, "880": true, "886": true, "960": true, "961": true, "962": true,
+	"963": true, "964": true, "965": true, "966": true, "967": true, "968": true, "970": true,
+	"971": true, "972": true, "973": true, "974": true, "975": true, "976": true, "977": true,
+	"992": true, "993": true, "994": true, "995": true, "996": true, "998": true,
+}
+
+// mobileRules 按ISO 3166-1二字母代码索引的手机号码规则
+// 未收录的地区只校验国家代码和E.164号码长度
+var mobileRules = map[string]mobileRule{
+	"AE": {[]int{9}, []string{"5"}},
+	"AU": {[]int{9}, []string{"4"}},
+	"BD": {[]int{10}, []string{"1"}},
+	"BR": {[]int{11}, nil},
+	"CA": {[]int{10}, []string{"2", "3", "4", "5", "6", "7", "8", "9"}},
+	"CN": {[]int{11}, []string{"13", "14", "15", "16", "17", "18", "19"}},
+	"DE": {[]int{10, 11}, []string{"15", "16", "17"}},
+	"EG": {[]int{10}, []string{"1"}},
+	"ES": {[]int{9}, []string{"6", "7"}},
+	"FR": {[]int{9}, []string{"6", "7"}},
+	"GB": {[]int{10}, []string{"7"}},
+	"HK": {[]int{8}, []string{"4", "5", "6", "7", "9"}},
+	"ID": {[]int{9, 10, 11, 12}, []string{"8"}},
+	"IN": {[]int{10}, []string{"6", "7", "8", "9"}},
+	"IT": {[]int{9, 10}, []string{"3"}},
+	"JP": {[]int{10}, []string{"70", "80", "90"}},
+	"KR": {[]int{9, 10}, []string{"1"}},
+	"KZ": {[]int{10}, []string{"7"}},
+	"MO": {[]int{8}, []string{"6"}},
+	"MX": {[]int{10}, nil},
+	"MY": {[]int{9, 10}, []string{"1"}},
+	"NG": {[]int{10}, []string{"70", "80", "81", "90", "91"}},
+	"NL": {[]int{9}, []string{"6"}},
+	"NZ": {[]int{8, 9, 10}, []string{"2"}},
+	"PH": {[]int{10}, []string{"9"}},
+	"PK": {[]int{10}, []string{"3"}},
+	"RU": {[]int{10}, []string{"9"}},
+	"SA": {[]int{9}, []string{"5"}},
+	"SG": {[]int{8}, []string{"8", "9"}},
+	"TH": {[]int{9}, []string{"6", "8", "9"}},
+	"TJ": {[]int{9}, nil},
+	"TR": {[]int{10}, []string{"5"}},
+	"TW": {[]int{9}, []string{"9"}},
+	"US": {[]int{10}, []string{"2", "3", "4", "5", "6", "7", "8", "9"}},
+	"VN": {[]int{9}, []string{"3", "5", "7", "8", "9"}},
+	"ZA": {[]int{9}, []string{"6", "7", "8"}},
+}
+
+// validCallingCode 判断国家代码是否已分配
+// 参数:
+//   - code: 国家代码
+// 返回:
+//   - bool: 是否已分配
+func validCallingCode(code string) bool {
+	switch len(code) {
+	case 1:
+		return code == "1" || code == "7"
+	case 2:
+		return twoDigitCodes[code]
+	default:
+		return callingCodes[code]
+	}
+}
@@ -0,0 +1,229 @@
+// Package phone 手机号码解析与格式化
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidNumber 手机号码无效
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Format 号码格式
+type Format string
+
+// 号码格式常量定义，以中国大陆号码13800138000为例
+const (
+	FORMAT_E164     Format = "e164"     // E.164格式，如"+8613800138000"
+	FORMAT_NATIONAL Format = "national" // 国内有效号码，不含国家代码和长途前缀，如"13800138000"
+	FORMAT_DIGITS   Format = "digits"   // 国家代码加国内有效号码，不含"+"，如"8613800138000"
+)
+
+// E.164号码长度限制
+const (
+	MIN_NATIONAL_LENGTH = 4  // 国内有效号码的最小长度
+	MAX_LENGTH          = 15 // 国家代码加国内有效号码的最大长度
+)
+
+// Number 解析后的手机号码
+type Number struct {
+	CountryCode    string // 国家代码，如"86"
+	NationalNumber string // 国内有效号码，如"13800138000"
+}
+
+// Parse 解析手机号码
+// 以"+"或"00"开头的号码视为包含国家代码，号码中的空格、"-"、"."和括号会被忽略
+// 其他号码按默认地区的国内格式解析并去除长途前缀；长度超过该地区国内号码时视为已包含国家代码（如"971501234567"），
+// 优先匹配默认地区的国家代码，否则按号码开头的国家代码拆分
+// 参数:
+//   - number: 手机号码
+//   - defaultRegion: 默认地区，ISO 3166-1二字母代码，如"CN"；为空时号码必须以"+"或"00"开头
+// 返回:
+//   - *Number: 解析后的手机号码
+//   - error: 错误信息
+func Parse(number string, defaultRegion string) (*Number, error) {
+	digits, international, err := clean(number)
+	if err != nil {
+		return nil, err
+	}
+	if international {
+		return newNumber(splitCallingCode(digits))
+	}
+	if defaultRegion == "" {
+		return nil, fmt.Errorf("%w: missing country code: %s", ErrInvalidNumber, number)
+	}
+
+	r, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown region: %s", ErrInvalidNumber, defaultRegion)
+	}
+	switch {
+	case r.trunkPrefix != "" && strings.HasPrefix(digits, r.trunkPrefix) && len(digits)-len(r.trunkPrefix) <= r.maxLength:
+		digits = digits[len(r.trunkPrefix):]
+	case len(digits) > r.maxLength && strings.HasPrefix(digits, r.callingCode):
+		return newNumber(r.callingCode, digits[len(r.callingCode):])
+	}
+	if len(digits) > r.maxLength {
+		if countryCode, nationalNumber := splitCallingCode(digits); validCallingCode(countryCode) {
+			return newNumber(countryCode, nationalNumber)
+		}
+		return nil, fmt.Errorf("%w: too long for region %s: %s", ErrInvalidNumber, defaultRegion, number)
+	}
+	return newNumber(r.callingCode, digits)
+}
+
+// Normalize 解析手机号码并转换为指定格式
+// 参数:
+//   - number: 手机号码
+//   - defaultRegion: 默认地区，见Parse
+//   - format: 号码格式
+// 返回:
+//   - string: 格式化后的号码
+//   - error: 错误信息
+func Normalize(number string, defaultRegion string, format Format) (string, error) {
+	n, err := Parse(number, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return n.Format(format), nil
+}
+
+// Validate 解析并校验手机号码
+// 参数:
+//   - number: 手机号码
+//   - defaultRegion: 默认地区，见Parse
+// 返回:
+//   - *Number: 解析后的手机号码
+//   - error: 错误信息，号码无效时返回包含原因的ErrInvalidNumber
+func Validate(number string, defaultRegion string) (*Number, error) {
+	n, err := Parse(number, defaultRegion)
+	if err != nil {
+		return nil, err
+	}
+	if err = n.Validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Validate 按内置的元数据校验国家代码、国内有效号码长度和手机号段，不需要联网
+// 未收录手机号码规则的地区只校验国家代码和E.164号码长度
+// 返回:
+//   - error: 号码无效时返回包含原因的ErrInvalidNumber
+func (n *Number) Validate() error {
+	if !validCallingCode(n.CountryCode) {
+		return fmt.Errorf("%w: unknown country calling code: +%s", ErrInvalidNumber, n.CountryCode)
+	}
+
+	checked, lengthMatched := false, false
+	for regionCode, rule := range mobileRules {
+		if regions[regionCode].callingCode != n.CountryCode {
+			continue
+		}
+		checked = true
+		if !slices.Contains(rule.lengths, len(n.NationalNumber)) {
+			continue
+		}
+		lengthMatched = true
+		if len(rule.prefixes) == 0 || slices.ContainsFunc(rule.prefixes, func(prefix string) bool {
+			return strings.HasPrefix(n.NationalNumber, prefix)
+		}) {
+			return nil
+		}
+	}
+
+	switch {
+	case !checked:
+		return nil
+	case !lengthMatched:
+		return fmt.Errorf("%w: bad length for +%s: %s", ErrInvalidNumber, n.CountryCode, n)
+	default:
+		return fmt.Errorf("%w: not a mobile number: %s", ErrInvalidNumber, n)
+	}
+}
+
+// Format 将号码转换为指定格式
+// 参数:
+//   - format: 号码格式，未知格式按FORMAT_E164处理
+// 返回:
+//   - string: 格式化后的号码
+func (n *Number) Format(format Format) string {
+	switch format {
+	case FORMAT_NATIONAL:
+		return n.NationalNumber
+	case FORMAT_DIGITS:
+		return n.CountryCode + n.NationalNumber
+	default:
+		return "+" + n.CountryCode + n.NationalNumber
+	}
+}
+
+// String 返回E.164格式的号码
+func (n *Number) String() string {
+	return n.Format(FORMAT_E164)
+}
+
+// InRegion 判断号码是否属于指定地区（按国家代码判断，共用国家代码的地区如美国和加拿大视为相同）
+// 参数:
+//   - regionCode: ISO 3166-1二字母地区代码
+// 返回:
+//   - bool: 是否属于该地区
+func (n *Number) InRegion(regionCode string) bool {
+	code, ok := CallingCode(regionCode)
+	return ok && code == n.CountryCode
+}
+
+// clean 去除号码中的分隔符和国际冠字
+// 参数:
+//   - number: 手机号码
+// 返回:
+//   - string: 纯数字号码
+//   - bool: 是否以"+"或"00"开头
+//   - error: 错误信息
+func clean(number string) (string, bool, error) {
+	var b strings.Builder
+	international := false
+	for _, c := range strings.TrimSpace(number) {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c == '+' && b.Len() == 0 && !international:
+			international = true
+		case strings.ContainsRune(" -.()", c):
+		default:
+			return "", false, fmt.Errorf("%w: unexpected character %q: %s", ErrInvalidNumber, c, number)
+		}
+	}
+
+	digits := b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		international = true
+		digits = digits[2:]
+	}
+	if digits == "" {
+		return "", false, fmt.Errorf("%w: empty number", ErrInvalidNumber)
+	}
+	return digits, international, nil
+}
+
+// newNumber 创建手机号码并校验长度
+// 参数:
+//   - countryCode: 国家代码
+//   - nationalNumber: 国内有效号码
+// 返回:
+//   - *Number: 手机号码
+//   - error: 错误信息
+func newNumber(countryCode string, nationalNumber string) (*Number, error) {
+	if strings.HasPrefix(countryCode, "0") {
+		return nil, fmt.Errorf("%w: bad country code: %s", ErrInvalidNumber, countryCode)
+	}
+	if len(nationalNumber) < MIN_NATIONAL_LENGTH {
+		return nil, fmt.Errorf("%w: too short: +%s%s", ErrInvalidNumber, countryCode, nationalNumber)
+	}
+	if len(countryCode)+len(nationalNumber) > MAX_LENGTH {
+		return nil, fmt.Errorf("%w: too long: +%s%s", ErrInvalidNumber, countryCode, nationalNumber)
+	}
+	return &Number{CountryCode: countryCode, NationalNumber: nationalNumber}, nil
+}
@@ -0,0 +1,102 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		number        string
+		defaultRegion string
+		want          string
+		wantErr       bool
+	}{
+		{"e164", "+8613800138000", "", "+8613800138000", false},
+		{"separators", "+86 (138) 0013-8000", "", "+8613800138000", false},
+		{"international prefix", "008613800138000", "", "+8613800138000", false},
+		{"national", "13800138000", "CN", "+8613800138000", false},
+		{"trunk prefix", "89123456789", "RU", "+79123456789", false},
+		{"lowercase region", "13800138000", "cn", "+8613800138000", false},
+		{"digits with default region calling code", "8613800138000", "CN", "+8613800138000", false},
+		{"digits with other calling code", "971501234567", "TW", "+971501234567", false},
+		{"digits with two-digit calling code", "447911123456", "CN", "+447911123456", false},
+		{"digits with one-digit calling code", "12025550123", "TW", "+12025550123", false},
+		{"missing country code", "13800138000", "", "", true},
+		{"unknown region", "13800138000", "XX", "", true},
+		{"unexpected character", "138a0013800", "CN", "", true},
+		{"empty", " ", "CN", "", true},
+		{"too short", "+86123", "", "", true},
+		{"too long", "+8613800138000123", "", "", true},
+		{"too long for region", "0971501234567", "TW", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.number, tt.defaultRegion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q, %q) error = %v, wantErr %v", tt.number, tt.defaultRegion, err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidNumber) {
+					t.Errorf("Parse(%q, %q) error = %v, want ErrInvalidNumber", tt.number, tt.defaultRegion, err)
+				}
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("Parse(%q, %q) = %s, want %s", tt.number, tt.defaultRegion, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		number        string
+		defaultRegion string
+		wantErr       bool
+	}{
+		{"mainland mobile", "+8613800138000", "", false},
+		{"digits-only international under vendor region", "971501234567", "TW", false},
+		{"digits-only international under same region", "8613800138000", "CN", false},
+		{"region without mobile rules", "+3545512345", "", false},
+		{"not a mobile number", "+8612800138000", "", true},
+		{"bad length", "+861380013800", "", true},
+		{"unknown calling code", "+969123456789", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(tt.number, tt.defaultRegion)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q, %q) error = %v, wantErr %v", tt.number, tt.defaultRegion, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FORMAT_E164, "+8613800138000"},
+		{FORMAT_NATIONAL, "13800138000"},
+		{FORMAT_DIGITS, "8613800138000"},
+		{"unknown", "+8613800138000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := Normalize("013800138000", "CN", tt.format)
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
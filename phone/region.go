@@ -0,0 +1,92 @@
+// Package phone 地区号码规则
+package phone
+
+import "strings"
+
+// region 地区号码规则
+type region struct {
+	callingCode string // 国家代码
+	trunkPrefix string // 国内长途前缀，如中国大陆为"0"，俄罗斯为"8"
+	maxLength   int    // 国内有效号码（不含长途前缀）的最大长度
+}
+
+// regions 按ISO 3166-1二字母代码索引的地区号码规则
+var regions = map[string]region{
+	"AE": {"971", "0", 9},
+	"AU": {"61", "0", 9},
+	"BD": {"880", "0", 10},
+	"BR": {"55", "0", 11},
+	"CA": {"1", "1", 10},
+	"CN": {"86", "0", 11},
+	"DE": {"49", "0", 13},
+	"EG": {"20", "0", 10},
+	"ES": {"34", "", 9},
+	"FR": {"33", "0", 9},
+	"GB": {"44", "0", 10},
+	"HK": {"852", "", 8},
+	"ID": {"62", "0", 12},
+	"IN": {"91", "0", 10},
+	"IT": {"39", "", 11},
+	"JP": {"81", "0", 10},
+	"KR": {"82", "0", 10},
+	"KZ": {"7", "8", 10},
+	"MO": {"853", "", 8},
+	"MX": {"52", "", 10},
+	"MY": {"60", "0", 10},
+	"NG": {"234", "0", 10},
+	"NL": {"31", "0", 9},
+	"NZ": {"64", "0", 10},
+	"PH": {"63", "0", 10},
+	"PK": {"92", "0", 10},
+	"RU": {"7", "8", 10},
+	"SA": {"966", "0", 9},
+	"SG": {"65", "", 8},
+	"TH": {"66", "0", 9},
+	"TJ": {"992", "8", 9},
+	"TR": {"90", "0", 10},
+	"TW": {"886", "0", 9},
+	"US": {"1", "1", 10},
+	"VN": {"84", "0", 10},
+	"ZA": {"27", "0", 9},
+}
+
+// twoDigitCodes 两位数的国家代码
+// 首位为1或7的国家代码为一位数，其余不在此表中的为三位数
+var twoDigitCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true,
+	"36": true, "39": true, "40": true, "41": true, "43": true, "44": true, "45": true,
+	"46": true, "47": true, "48": true, "49": true, "51": true, "52": true, "53": true,
+	"54": true, "55": true, "56": true, "57": true, "58": true, "60": true, "61": true,
+	"62": true, "63": true, "64": true, "65": true, "66": true, "81": true, "82": true,
+	"84": true, "86": true, "90": true, "91": true, "92": true, "93": true, "94": true,
+	"95": true, "98": true,
+}
+
+// CallingCode 获取地区的国家代码
+// 参数:
+//   - regionCode: ISO 3166-1二字母地区代码，如"CN"
+// 返回:
+//   - string: 国家代码，如"86"
+//   - bool: 是否支持该地区
+func CallingCode(regionCode string) (string, bool) {
+	r, ok := regions[strings.ToUpper(regionCode)]
+	return r.callingCode, ok
+}
+
+// splitCallingCode 拆分包含国家代码的号码
+// 参数:
+//   - digits: 不含"+"的号码
+// 返回:
+//   - string: 国家代码
+//   - string: 国内有效号码
+func splitCallingCode(digits string) (string, string) {
+	n := 3
+	switch {
+	case digits[0] == '1' || digits[0] == '7':
+		n = 1
+	case len(digits) >= 2 && twoDigitCodes[digits[:2]]:
+		n = 2
+	}
+	n = min(n, len(digits))
+	return digits[:n], digits[n:]
+}
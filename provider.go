@@ -1,95 +1,137 @@
-// Package sms 短信服务提供商统一接口
-package sms
-
-import "fmt"
-
-// 短信服务提供商常量定义
-const (
-	SMS_TWILIO  string = "Twilio_SMS"        // Twilio短信服务
-	SMS_AMAZON  string = "Amazon_SNS"        // 亚马逊SNS短信服务
-	SMS_AZURE   string = "Azure_ACS"         // 微软Azure通信服务
-	SMS_MSG91   string = "Msg91_SMS"         // Msg91短信服务
-	SMS_GCCPAY  string = "GCCPAY_SMS"        // GCCPAY短信服务
-	SMS_INFOBIP string = "Infobip_SMS"       // Infobip短信服务
-	SMS_SUBMAIL string = "SUBMAIL_SMS"       // SUBMAIL短信服务
-	SMS_SMSBAO  string = "SmsBao_SMS"        // 短信宝服务
-	SMS_ALIYUN  string = "Aliyun_SMS"        // 阿里云短信服务
-	SMS_TENCENT string = "Tencent_Cloud_SMS" // 腾讯云短信服务
-	SMS_BAIdU   string = "Baidu_Cloud_SMS"   // 百度云短信服务
-	SMS_VOCL    string = "Volc_Engine_SMS"   // 火山引擎短信服务
-	SMS_HUAWEI  string = "Huawei_Cloud_SMS"  // 华为云短信服务
-	SMS_UCloud  string = "UCloud_SMS"        // UCloud短信服务
-	SMS_HUYI    string = "Huyi_SMS"          // 互亿无线短信服务
-	SMS_MOCK    string = "Mock SMS"          // 模拟短信服务
-	SMS_NETGSM  string = "Netgsm_SMS"        // Netgsm短信服务
-	SMS_OSONI   string = "OSON_SMS"          // OSON短信服务
-	SMS_UNI     string = "Uni_SMS"           // Uni短信服务
-)
-
-// SmsProvider 短信服务提供商接口
-// 定义了发送短信的统一接口
-type SmsProvider interface {
-	// SendMessage 发送短信
-	// 参数:
-	//   - param: 短信模板参数
-	//   - targetPhoneNumber: 目标手机号码列表
-	// 返回:
-	//   - error: 错误信息
-	SendMessage(param map[string]string, targetPhoneNumber ...string) error
-}
-
-// NewSmsProvider 创建短信服务提供商实例
-// 参数:
-//   - provider: 服务提供商类型
-//   - accessId: 访问ID
-//   - accessKey: 访问密钥
-//   - sign: 短信签名
-//   - template: 短信模板
-//   - other: 其他参数
-// 返回:
-//   - SmsProvider: 短信服务提供商实例
-//   - error: 错误信息
-func NewSmsProvider(provider string, accessId string, accessKey string, sign string, template string, other ...string) (SmsProvider, error) {
-	switch provider {
-	case SMS_TWILIO:
-		return GetTwilioClient(accessId, accessKey, template)
-	case SMS_AMAZON:
-		return GetAmazonSNSClient(accessId, accessKey, template, other)
-	case SMS_AZURE:
-		return GetACSClient(accessKey, template, other)
-	case SMS_MSG91:
-		return GetMsg91Client(accessId, accessKey, template)
-	case SMS_GCCPAY:
-		return GetGCCPAYClient(accessId, accessKey, template)
-	case SMS_INFOBIP:
-		return GetInfobipClient(accessId, accessKey, template, other)
-	case SMS_SUBMAIL:
-		return GetSubmailClient(accessId, accessKey, template)
-	case SMS_SMSBAO:
-		return GetSmsbaoClient(accessId, accessKey, sign, template, other)
-	case SMS_ALIYUN:
-		return GetAliyunClient(accessId, accessKey, sign, template)
-	case SMS_TENCENT:
-		return GetTencentClient(accessId, accessKey, sign, template, other)
-	case SMS_BAIdU:
-		return GetBceClient(accessId, accessKey, sign, template, other)
-	case SMS_VOCL:
-		return GetVolcClient(accessId, accessKey, sign, template, other)
-	case SMS_HUAWEI:
-		return GetHuaweiClient(accessId, accessKey, sign, template, other)
-	case SMS_UCloud:
-		return GetUcloudClient(accessId, accessKey, sign, template, other)
-	case SMS_HUYI:
-		return GetHuyiClient(accessId, accessKey, template)
-	case SMS_NETGSM:
-		return GetNetgsmClient(accessId, accessKey, sign, template)
-	case SMS_MOCK:
-		return NewMocker(accessId, accessKey, sign, template, other)
-	case SMS_OSONI:
-		return GetOsonClient(accessId, accessKey, sign, template)
-	case SMS_UNI:
-		return GetUnismsClient(accessId, accessKey, sign, template)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
-	}
-}
+// Package sms 短信服务提供商统一接口
+package sms
+
+import "context"
+
+// 短信服务提供商常量定义
+const (
+	SMS_TWILIO  string = "Twilio_SMS"        // Twilio短信服务
+	SMS_AMAZON  string = "Amazon_SNS"        // 亚马逊SNS短信服务
+	SMS_AZURE   string = "Azure_ACS"         // 微软Azure通信服务
+	SMS_MSG91   string = "Msg91_SMS"         // Msg91短信服务
+	SMS_GCCPAY  string = "GCCPAY_SMS"        // GCCPAY短信服务
+	SMS_INFOBIP string = "Infobip_SMS"       // Infobip短信服务
+	SMS_SUBMAIL string = "SUBMAIL_SMS"       // SUBMAIL短信服务
+	SMS_SMSBAO  string = "SmsBao_SMS"        // 短信宝服务
+	SMS_ALIYUN  string = "Aliyun_SMS"        // 阿里云短信服务
+	SMS_TENCENT string = "Tencent_Cloud_SMS" // 腾讯云短信服务
+	SMS_BAIdU   string = "Baidu_Cloud_SMS"   // 百度云短信服务
+	SMS_VOCL    string = "Volc_Engine_SMS"   // 火山引擎短信服务
+	SMS_HUAWEI  string = "Huawei_Cloud_SMS"  // 华为云短信服务
+	SMS_UCloud  string = "UCloud_SMS"        // UCloud短信服务
+	SMS_HUYI    string = "Huyi_SMS"          // 互亿无线短信服务
+	SMS_MOCK    string = "Mock SMS"          // 模拟短信服务
+	SMS_NETGSM  string = "Netgsm_SMS"        // Netgsm短信服务
+	SMS_OSONI   string = "OSON_SMS"          // OSON短信服务
+	SMS_UNI     string = "Uni_SMS"           // Uni短信服务
+)
+
+// SmsProvider 短信服务提供商接口
+// 定义了发送短信的统一接口
+type SmsProvider interface {
+	// SendMessage 发送短信
+	// 参数:
+	//   - param: 短信模板参数
+	//   - targetPhoneNumber: 目标手机号码列表
+	// 返回:
+	//   - error: 错误信息
+	SendMessage(param map[string]string, targetPhoneNumber ...string) error
+}
+
+// ContextSmsProvider 支持上下文和结构化发送结果的短信服务提供商接口
+// 内置的服务商和组合服务商均实现了该接口；只实现SmsProvider的服务商可以通过SendMessageContext函数调用
+type ContextSmsProvider interface {
+	SmsProvider
+
+	// SendMessageContext 发送短信（支持上下文）
+	// 上下文被取消或超时后立即返回，不再等待服务商响应
+	// 返回错误时发送结果仍可能包含部分接收方的受理状态
+	// 参数:
+	//   - ctx: 上下文
+	//   - param: 短信模板参数
+	//   - targetPhoneNumber: 目标手机号码列表
+	// 返回:
+	//   - *SendResult: 发送结果，包含各接收方的消息ID和受理状态
+	//   - error: 错误信息
+	SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error)
+}
+
+// SendMessageContext 使用上下文发送短信
+// 服务商实现了ContextSmsProvider时直接调用其SendMessageContext；
+// 否则在上下文控制下调用SendMessage，成功时将所有号码标记为已受理（没有消息ID），失败时不标记任何号码
+// 参数:
+//   - ctx: 上下文
+//   - provider: 短信服务提供商实例
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func SendMessageContext(ctx context.Context, provider SmsProvider, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if contextProvider, ok := provider.(ContextSmsProvider); ok {
+		return contextProvider.SendMessageContext(ctx, param, targetPhoneNumber...)
+	}
+
+	result := newSendResult("")
+	_, err := callWithContext(ctx, func() (struct{}, error) {
+		return struct{}{}, provider.SendMessage(param, targetPhoneNumber...)
+	})
+	if err != nil {
+		return result, err
+	}
+	result.acceptAll("", targetPhoneNumber)
+	return result, nil
+}
+
+// NewSmsProvider 创建短信服务提供商实例
+// 参数:
+//   - provider: 服务提供商类型
+//   - accessId: 访问ID
+//   - accessKey: 访问密钥
+//   - sign: 短信签名
+//   - template: 短信模板
+//   - other: 其他参数（按服务商约定的顺序，推荐使用NewSmsProviderFromConfig）
+// 返回:
+//   - SmsProvider: 短信服务提供商实例
+//   - error: 错误信息
+func NewSmsProvider(provider string, accessId string, accessKey string, sign string, template string, other ...string) (SmsProvider, error) {
+	entry, err := lookupProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.factory(newLegacyConfig(provider, accessId, accessKey, sign, template, other))
+}
+
+// callWithContext 在上下文控制下执行不支持上下文的SDK调用
+// SDK调用在独立的goroutine中执行，上下文结束时立即返回上下文错误
+// 参数:
+//   - ctx: 上下文
+//   - fn: SDK调用函数
+// 返回:
+//   - T: SDK调用结果
+//   - error: 错误信息
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type callResult struct {
+		value T
+		err   error
+	}
+
+	done := make(chan callResult, 1)
+	go func() {
+		value, err := fn()
+		done <- callResult{value: value, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-done:
+		return result.value, result.err
+	}
+}
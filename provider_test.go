@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// plainProvider 只实现SmsProvider接口的短信服务提供商
+type plainProvider struct {
+	err   error // 发送返回的错误
+	calls int   // 发送次数
+}
+
+// SendMessage 发送短信
+func (p *plainProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	p.calls++
+	return p.err
+}
+
+func TestSendMessageContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		provider     SmsProvider
+		wantErr      error
+		wantAccepted int
+	}{
+		{"context provider", context.Background(), &stubProvider{}, nil, 2},
+		{"plain provider", context.Background(), &plainProvider{}, nil, 2},
+		{"plain provider failure", context.Background(), &plainProvider{err: ErrServiceUnavailable}, ErrServiceUnavailable, 0},
+		{"plain provider canceled", canceled, &plainProvider{}, context.Canceled, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SendMessageContext(tt.ctx, tt.provider, nil, "+8613800138000", "+8613800138001")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendMessageContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(result.Accepted()); got != tt.wantAccepted {
+				t.Errorf("Accepted() = %d numbers, want %d", got, tt.wantAccepted)
+			}
+		})
+	}
+}
+
+func TestFailoverWithPlainProvider(t *testing.T) {
+	primary := &plainProvider{err: ErrServiceUnavailable}
+	secondary := &plainProvider{}
+	failover, err := NewFailoverProvider(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewFailoverProvider() error = %v", err)
+	}
+
+	if err := failover.SendMessage(nil, "+8613800138000"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if primary.calls != 1 || secondary.calls != 1 {
+		t.Errorf("calls = %d, %d, want 1, 1", primary.calls, secondary.calls)
+	}
+}
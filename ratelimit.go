@@ -0,0 +1,335 @@
+// Package sms 短信发送频率限制
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// RateLimit 单个号码的发送频率限制
+type RateLimit struct {
+	Limit  int           // 时间窗口内允许发送的次数
+	Window time.Duration // 时间窗口
+}
+
+// RateLimitPolicy 发送频率限制策略
+type RateLimitPolicy struct {
+	Rate         float64     // 每秒允许发送的短信条数（全局令牌桶），为0时不限制
+	Burst        int         // 令牌桶容量，即允许的突发条数，为0时取Rate向上取整
+	PerRecipient []RateLimit // 每个号码的发送频率限制，如每分钟1条、每小时5条、每天10条
+
+	DefaultRegion string // 号码未包含国家代码时使用的默认地区（如"CN"），同一号码的不同写法按E.164格式合并计数
+}
+
+// RateLimitError 发送频率超限错误
+// 在请求服务商之前由客户端限流返回，满足errors.Is(err, ErrRateLimited)，
+// 但不属于可重试错误（见IsRetryable），不会触发重试、故障转移，也不计入熔断器和负载均衡的失败次数
+type RateLimitError struct {
+	PhoneNumber string        // 超出限制的号码，全局限制时为空
+	Limit       int           // 时间窗口内允许发送的次数，全局限制时为令牌桶容量
+	Window      time.Duration // 时间窗口，全局限制时为0
+	RetryAfter  time.Duration // 再次发送前建议等待的时间
+}
+
+// Error 获取错误信息
+// 返回:
+//   - string: 错误信息
+func (e *RateLimitError) Error() string {
+	if e.PhoneNumber == "" {
+		return fmt.Sprintf("rate limited: retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("rate limited: %s, %d per %s, retry after %s", e.PhoneNumber, e.Limit, e.Window, e.RetryAfter)
+}
+
+// Unwrap 获取标准错误
+// 返回:
+//   - error: 标准错误ErrRateLimited
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimitProvider 带发送频率限制的短信服务提供商
+// 超出限制的号码不会发送给服务商，直接返回*RateLimitError；号码在发送前计入频率限制
+type RateLimitProvider struct {
+	provider  SmsProvider            // 被包装的服务提供商
+	policy    RateLimitPolicy        // 频率限制策略
+	mu        sync.Mutex             // 限流状态锁
+	tokens    float64                // 令牌桶剩余令牌
+	updatedAt time.Time              // 令牌桶更新时间
+	history   map[string][]time.Time // 各号码（E.164格式）在最长时间窗口内的发送时间
+	sweptAt   time.Time              // 上次清理发送记录的时间
+	maxWindow time.Duration          // 最长时间窗口
+}
+
+// 确保RateLimitProvider实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &RateLimitProvider{}
+
+// WithRateLimit 为短信服务提供商添加发送频率限制
+// 参数:
+//   - provider: 短信服务提供商
+//   - policy: 频率限制策略
+// 返回:
+//   - *RateLimitProvider: 带发送频率限制的短信服务提供商
+func WithRateLimit(provider SmsProvider, policy RateLimitPolicy) *RateLimitProvider {
+	if policy.Rate > 0 && policy.Burst <= 0 {
+		policy.Burst = int(math.Ceil(policy.Rate))
+	}
+
+	limits := make([]RateLimit, 0, len(policy.PerRecipient))
+	var maxWindow time.Duration
+	for _, limit := range policy.PerRecipient {
+		if limit.Limit <= 0 || limit.Window <= 0 {
+			continue
+		}
+		limits = append(limits, limit)
+		maxWindow = max(maxWindow, limit.Window)
+	}
+	policy.PerRecipient = limits
+
+	return &RateLimitProvider{
+		provider:  provider,
+		policy:    policy,
+		tokens:    float64(policy.Burst),
+		updatedAt: time.Now(),
+		history:   make(map[string][]time.Time),
+		sweptAt:   time.Now(),
+		maxWindow: maxWindow,
+	}
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (r *RateLimitProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := r.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 超出单号码限制的号码标记为未受理，其余号码正常发送；
+// 启用全局限制时按令牌桶容量（Burst）分批发送，某一批令牌不足或发送失败时该批及后续号码均不发送
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息，超出限制时包含*RateLimitError
+func (r *RateLimitProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	batchSize := len(targetPhoneNumber)
+	if r.policy.Rate > 0 {
+		batchSize = min(batchSize, r.policy.Burst)
+	}
+
+	result := newSendResult("")
+	var errs []error
+	for start := 0; start < len(targetPhoneNumber); start += batchSize {
+		batch := targetPhoneNumber[start:min(start+batchSize, len(targetPhoneNumber))]
+		allowed, batchErrs := r.reserve(batch)
+		errs = append(errs, batchErrs...)
+
+		var failed error
+		if len(allowed) > 0 {
+			sent, err := SendMessageContext(ctx, r.provider, param, allowed...)
+			if err != nil {
+				errs = append(errs, err)
+				failed = err
+			}
+			if sent != nil {
+				result.Provider = sent.Provider
+				result.RequestId = sent.RequestId
+				result.Raw = sent.Raw
+				result.Recipients = append(result.Recipients, sent.Recipients...)
+				result.Attempts = append(result.Attempts, sent.Attempts...)
+			}
+		}
+
+		for _, err := range batchErrs {
+			var limitErr *RateLimitError
+			if !errors.As(err, &limitErr) {
+				continue
+			}
+			if limitErr.PhoneNumber != "" {
+				result.add(&RecipientResult{PhoneNumber: limitErr.PhoneNumber, Message: limitErr.Error()})
+				continue
+			}
+			for _, phoneNumber := range batch {
+				result.add(&RecipientResult{PhoneNumber: phoneNumber, Message: limitErr.Error()})
+			}
+			failed = limitErr
+		}
+
+		if failed != nil {
+			for _, phoneNumber := range targetPhoneNumber[start+len(batch):] {
+				result.add(&RecipientResult{PhoneNumber: phoneNumber, Message: failed.Error()})
+			}
+			break
+		}
+	}
+	result.sortRecipients(targetPhoneNumber)
+
+	return result, errors.Join(errs...)
+}
+
+// reserve 检查频率限制并预占发送配额
+// 参数:
+//   - phoneNumbers: 目标手机号码列表
+// 返回:
+//   - []string: 允许发送的号码
+//   - []error: 超出限制的*RateLimitError
+func (r *RateLimitProvider) reserve(phoneNumbers []string) ([]string, []error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.sweep(now)
+
+	var errs []error
+	allowed := make([]string, 0, len(phoneNumbers))
+	keys := make([]string, 0, len(phoneNumbers))
+	counts := make(map[string]int, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		key := r.recipientKey(phoneNumber)
+		if err := r.checkRecipient(phoneNumber, key, counts[key], now); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		counts[key]++
+		allowed = append(allowed, phoneNumber)
+		keys = append(keys, key)
+	}
+
+	if len(allowed) == 0 {
+		return allowed, errs
+	}
+	if err := r.take(len(allowed), now); err != nil {
+		return nil, []error{err}
+	}
+
+	if r.maxWindow > 0 {
+		for _, key := range keys {
+			r.history[key] = append(r.history[key], now)
+		}
+	}
+	return allowed, errs
+}
+
+// recipientKey 获取号码的频率限制计数键
+// 号码可以解析时使用E.164格式，使"+8613800138000"、"13800138000"和"+86 138 0013 8000"合并计数；
+// 无法解析时（如未设置DefaultRegion且号码不含国家代码）使用去除空白后的原始号码
+// 参数:
+//   - phoneNumber: 手机号码
+// 返回:
+//   - string: 计数键
+func (r *RateLimitProvider) recipientKey(phoneNumber string) string {
+	if number, err := phone.Parse(phoneNumber, r.policy.DefaultRegion); err == nil {
+		return number.String()
+	}
+	return strings.Join(strings.Fields(phoneNumber), "")
+}
+
+// checkRecipient 检查号码的发送频率限制
+// 参数:
+//   - phoneNumber: 手机号码
+//   - key: 号码的计数键
+//   - pending: 本次请求中该号码已允许发送的次数
+//   - now: 当前时间
+// 返回:
+//   - error: 超出限制时返回*RateLimitError
+func (r *RateLimitProvider) checkRecipient(phoneNumber string, key string, pending int, now time.Time) error {
+	if r.maxWindow == 0 {
+		return nil
+	}
+
+	history := r.history[key]
+	for len(history) > 0 && now.Sub(history[0]) >= r.maxWindow {
+		history = history[1:]
+	}
+	if len(history) == 0 {
+		delete(r.history, key)
+	} else {
+		r.history[key] = history
+	}
+
+	for _, limit := range r.policy.PerRecipient {
+		count := pending
+		var oldest time.Time
+		for _, sentAt := range history {
+			if now.Sub(sentAt) < limit.Window {
+				if count == pending {
+					oldest = sentAt
+				}
+				count++
+			}
+		}
+		if count < limit.Limit {
+			continue
+		}
+
+		retryAfter := limit.Window
+		if !oldest.IsZero() {
+			retryAfter = oldest.Add(limit.Window).Sub(now)
+		}
+		return &RateLimitError{
+			PhoneNumber: phoneNumber,
+			Limit:       limit.Limit,
+			Window:      limit.Window,
+			RetryAfter:  retryAfter,
+		}
+	}
+	return nil
+}
+
+// take 从全局令牌桶中取出令牌
+// 参数:
+//   - n: 令牌数
+//   - now: 当前时间
+// 返回:
+//   - error: 令牌不足时返回*RateLimitError
+func (r *RateLimitProvider) take(n int, now time.Time) error {
+	if r.policy.Rate <= 0 {
+		return nil
+	}
+
+	elapsed := now.Sub(r.updatedAt).Seconds()
+	r.tokens = min(float64(r.policy.Burst), r.tokens+elapsed*r.policy.Rate)
+	r.updatedAt = now
+
+	if r.tokens >= float64(n) {
+		r.tokens -= float64(n)
+		return nil
+	}
+
+	wait := (float64(n) - r.tokens) / r.policy.Rate
+	return &RateLimitError{
+		Limit:      r.policy.Burst,
+		RetryAfter: time.Duration(wait * float64(time.Second)),
+	}
+}
+
+// sweep 清理超出最长时间窗口的发送记录
+// 每个最长时间窗口最多清理一次，避免发送记录无限增长
+// 参数:
+//   - now: 当前时间
+func (r *RateLimitProvider) sweep(now time.Time) {
+	if r.maxWindow == 0 || now.Sub(r.sweptAt) < r.maxWindow {
+		return
+	}
+
+	for phoneNumber, history := range r.history {
+		if now.Sub(history[len(history)-1]) >= r.maxWindow {
+			delete(r.history, phoneNumber)
+		}
+	}
+	r.sweptAt = now
+}
@@ -0,0 +1,167 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimitErrorNotRetryable(t *testing.T) {
+	limitErr := &RateLimitError{PhoneNumber: "+8613800138000", Limit: 1, Window: time.Minute, RetryAfter: time.Minute}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit error", limitErr, false},
+		{"wrapped rate limit error", fmt.Errorf("send: %w", limitErr), false},
+		{"provider rate limited", ErrRateLimited, true},
+		{"joined with provider failure", errors.Join(limitErr, ErrServiceUnavailable), true},
+		{"joined with invalid number", errors.Join(limitErr, ErrInvalidNumber), false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if !errors.Is(limitErr, ErrRateLimited) {
+		t.Errorf("errors.Is(%v, ErrRateLimited) = false, want true", limitErr)
+	}
+}
+
+func TestRateLimitWithRetry(t *testing.T) {
+	provider := &stubProvider{}
+	limited := WithRateLimit(provider, RateLimitPolicy{
+		PerRecipient: []RateLimit{{Limit: 1, Window: time.Minute}},
+	})
+	client := WithRetry(limited, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	if err := client.SendMessage(nil, "+8613800138000"); err != nil {
+		t.Fatalf("first SendMessage() error = %v", err)
+	}
+	err := client.SendMessage(nil, "+8613800138000")
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("second SendMessage() error = %v, want *RateLimitError", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+}
+
+func TestRateLimitNormalizesRecipients(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultRegion string
+		first         string
+		second        string
+		wantLimited   bool
+	}{
+		{"same spelling", "", "+8613800138000", "+8613800138000", true},
+		{"spaces", "", "+8613800138000", "+86 138 0013 8000", true},
+		{"international prefix", "", "+8613800138000", "008613800138000", true},
+		{"national with default region", "CN", "+8613800138000", "13800138000", true},
+		{"trunk prefix with default region", "RU", "+79123456789", "89123456789", true},
+		{"national without default region", "", "+8613800138000", "13800138000", false},
+		{"different numbers", "CN", "13800138000", "13800138001", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{}
+			client := WithRateLimit(provider, RateLimitPolicy{
+				PerRecipient:  []RateLimit{{Limit: 1, Window: time.Minute}},
+				DefaultRegion: tt.defaultRegion,
+			})
+
+			if err := client.SendMessage(nil, tt.first); err != nil {
+				t.Fatalf("SendMessage(%q) error = %v", tt.first, err)
+			}
+			result, err := client.SendMessageContext(context.Background(), nil, tt.second)
+			var limitErr *RateLimitError
+			if limited := errors.As(err, &limitErr); limited != tt.wantLimited {
+				t.Fatalf("SendMessage(%q) error = %v, want limited %v", tt.second, err, tt.wantLimited)
+			}
+			if tt.wantLimited {
+				if limitErr.PhoneNumber != tt.second {
+					t.Errorf("RateLimitError.PhoneNumber = %q, want %q", limitErr.PhoneNumber, tt.second)
+				}
+				if got := result.Rejected(); len(got) != 1 || got[0] != tt.second {
+					t.Errorf("Rejected() = %v, want [%s]", got, tt.second)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitBatchLargerThanBurst(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        float64
+		burst       int
+		targets     []string
+		wantSent    [][]string
+		wantLimited []string
+	}{
+		{"single batch", 0.001, 3, []string{"+8613800138000", "+8613800138001"}, [][]string{{"+8613800138000", "+8613800138001"}}, nil},
+		{"burst of one", 0.001, 1, []string{"+8613800138000", "+8613800138001"}, [][]string{{"+8613800138000"}}, []string{"+8613800138001"}},
+		{"batch larger than burst", 0.001, 2, []string{"+8613800138000", "+8613800138001", "+8613800138002"}, [][]string{{"+8613800138000", "+8613800138001"}}, []string{"+8613800138002"}},
+		{
+			"chunks sent as tokens refill", 1e12, 2,
+			[]string{"+8613800138000", "+8613800138001", "+8613800138002", "+8613800138003", "+8613800138004"},
+			[][]string{{"+8613800138000", "+8613800138001"}, {"+8613800138002", "+8613800138003"}, {"+8613800138004"}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{}
+			client := WithRateLimit(provider, RateLimitPolicy{Rate: tt.rate, Burst: tt.burst})
+
+			result, err := client.SendMessageContext(context.Background(), nil, tt.targets...)
+			if fmt.Sprint(provider.sent) != fmt.Sprint(tt.wantSent) {
+				t.Errorf("provider sent %v, want %v", provider.sent, tt.wantSent)
+			}
+			if got := result.Rejected(); fmt.Sprint(got) != fmt.Sprint(tt.wantLimited) {
+				t.Errorf("Rejected() = %v, want %v", got, tt.wantLimited)
+			}
+			var limitErr *RateLimitError
+			if limited := errors.As(err, &limitErr); limited != (len(tt.wantLimited) > 0) {
+				t.Errorf("SendMessageContext() error = %v, want limited %v", err, len(tt.wantLimited) > 0)
+			}
+			if len(result.Recipients) != len(tt.targets) {
+				t.Errorf("got %d recipients, want %d", len(result.Recipients), len(tt.targets))
+			}
+		})
+	}
+}
+
+func TestRateLimitBurstRefill(t *testing.T) {
+	provider := &stubProvider{}
+	client := WithRateLimit(provider, RateLimitPolicy{Rate: 1000, Burst: 1})
+
+	targets := []string{"+8613800138000", "+8613800138001"}
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = client.SendMessage(nil, targets[len(provider.sent):]...); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if fmt.Sprint(provider.sent) != fmt.Sprint([][]string{{targets[0]}, {targets[1]}}) {
+		t.Errorf("provider sent %v, want one recipient per batch", provider.sent)
+	}
+}
@@ -0,0 +1,82 @@
+// Package sms 短信服务提供商注册表
+package sms
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ProviderFactory 短信服务提供商工厂函数
+// 根据配置创建短信服务提供商实例
+type ProviderFactory func(config ProviderConfig) (SmsProvider, error)
+
+// providerEntry 已注册的短信服务提供商
+type providerEntry struct {
+	factory        ProviderFactory // 工厂函数
+	requiredFields []string        // 必填配置字段
+}
+
+var (
+	registryMu sync.RWMutex                      // 注册表读写锁
+	registry   = make(map[string]*providerEntry) // 已注册的短信服务提供商
+)
+
+// Register 注册短信服务提供商
+// 内置服务商在包初始化时自动注册，自定义服务商注册后即可通过NewSmsProviderFromConfig按名称创建
+// 名称为空、工厂函数为空或重复注册时会panic
+// 参数:
+//   - name: 服务提供商名称
+//   - factory: 工厂函数
+//   - requiredFields: 必填配置字段（ProviderConfig的字段名，如"AccessId"），创建前由Validate校验
+func Register(name string, factory ProviderFactory, requiredFields ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("sms: Register provider name is empty")
+	}
+	if factory == nil {
+		panic("sms: Register factory is nil for provider " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("sms: Register called twice for provider " + name)
+	}
+
+	registry[name] = &providerEntry{
+		factory:        factory,
+		requiredFields: requiredFields,
+	}
+}
+
+// Providers 获取已注册的短信服务提供商名称
+// 返回:
+//   - []string: 按名称排序的服务提供商列表
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupProvider 查找已注册的短信服务提供商
+// 参数:
+//   - name: 服务提供商名称
+// 返回:
+//   - *providerEntry: 已注册的服务提供商
+//   - error: 未注册时返回错误
+func lookupProvider(name string) (*providerEntry, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	entry, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", name)
+	}
+	return entry, nil
+}
@@ -0,0 +1,249 @@
+// Package sms 短信内容模板渲染
+package sms
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// RenderMode 短信内容模板的渲染方式
+type RenderMode string
+
+// 渲染方式常量定义
+const (
+	RENDER_PLACEHOLDER   RenderMode = "placeholder"   // {name}占位符，"{{"和"}}"分别表示字面的"{"和"}"（默认）
+	RENDER_TEXT_TEMPLATE RenderMode = "text/template" // Go text/template语法，如"{{.code}}"、"{{if .name}}...{{end}}"
+)
+
+// templatePart 占位符模板的组成部分
+type templatePart struct {
+	text     string // 字面文本
+	variable string // 占位符变量名，为空时为字面文本
+}
+
+// MessageTemplate 解析后的短信内容模板
+type MessageTemplate struct {
+	text   string             // 模板原文
+	parts  []templatePart     // 占位符模板的组成部分
+	tmpl   *template.Template // text/template模板
+	legacy bool               // 是否为不含占位符、使用"%s"或"%v"填充code参数的旧格式模板
+}
+
+// ParseMessageTemplate 解析短信内容模板
+// 占位符模式下，不含占位符但包含"%s"或"%v"的模板按旧格式处理，即fmt.Sprintf(template, param["code"])；
+// 其他"%"（如"50% off"）按字面文本处理
+// 参数:
+//   - text: 模板内容，如"您的验证码是{code}，{minutes}分钟内有效"
+//   - mode: 渲染方式，为空时使用RENDER_PLACEHOLDER
+//
+// 返回:
+//   - *MessageTemplate: 解析后的模板
+//   - error: 错误信息，模板语法错误时返回
+func ParseMessageTemplate(text string, mode RenderMode) (*MessageTemplate, error) {
+	t := &MessageTemplate{text: text}
+	switch mode {
+	case "", RENDER_PLACEHOLDER:
+		parts, err := parsePlaceholders(text)
+		if err != nil {
+			return nil, err
+		}
+		t.parts = parts
+		t.legacy = len(t.Variables()) == 0 && hasLegacyVerb(text)
+	case RENDER_TEXT_TEMPLATE:
+		tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("bad template: %w", err)
+		}
+		t.tmpl = tmpl
+	default:
+		return nil, fmt.Errorf("bad parameter: render mode %q", mode)
+	}
+	return t, nil
+}
+
+// RenderMessage 解析并渲染短信内容模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式，为空时使用RENDER_PLACEHOLDER
+//   - param: 模板参数
+//
+// 返回:
+//   - string: 短信内容
+//   - error: 错误信息，模板语法错误或缺少参数时返回
+func RenderMessage(text string, mode RenderMode, param map[string]string) (string, error) {
+	t, err := ParseMessageTemplate(text, mode)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(param)
+}
+
+// Variables 获取占位符模板中的变量名（去重，按出现顺序）
+// 返回:
+//   - []string: 变量名列表，text/template模板返回nil
+func (t *MessageTemplate) Variables() []string {
+	var variables []string
+	for _, part := range t.parts {
+		if part.variable != "" && !slices.Contains(variables, part.variable) {
+			variables = append(variables, part.variable)
+		}
+	}
+	return variables
+}
+
+// Render 使用模板参数渲染短信内容
+// 参数:
+//   - param: 模板参数
+//
+// 返回:
+//   - string: 短信内容
+//   - error: 错误信息，缺少参数时返回"missing parameter: 变量名"
+func (t *MessageTemplate) Render(param map[string]string) (string, error) {
+	if t.tmpl != nil {
+		var b strings.Builder
+		if err := t.tmpl.Execute(&b, param); err != nil {
+			return "", fmt.Errorf("render template: %w", err)
+		}
+		return b.String(), nil
+	}
+
+	if t.legacy {
+		code, ok := param["code"]
+		if !ok {
+			return "", fmt.Errorf("missing parameter: code")
+		}
+		return fmt.Sprintf(t.text, code), nil
+	}
+
+	var b strings.Builder
+	var missing []string
+	for _, part := range t.parts {
+		if part.variable == "" {
+			b.WriteString(part.text)
+			continue
+		}
+		value, ok := param[part.variable]
+		if !ok {
+			if !slices.Contains(missing, part.variable) {
+				missing = append(missing, part.variable)
+			}
+			continue
+		}
+		b.WriteString(value)
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing parameter: %s", strings.Join(missing, ", "))
+	}
+	return b.String(), nil
+}
+
+// String 获取模板原文
+// 返回:
+//   - string: 模板原文
+func (t *MessageTemplate) String() string {
+	return t.text
+}
+
+// hasLegacyVerb 判断模板是否包含旧格式模板使用的"%s"或"%v"格式化动词
+// "%%"为转义的字面"%"，不视为格式化动词
+// 参数:
+//   - text: 模板内容
+//
+// 返回:
+//   - bool: 是否包含"%s"或"%v"
+func hasLegacyVerb(text string) bool {
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] != '%' {
+			continue
+		}
+		switch text[i+1] {
+		case 's', 'v':
+			return true
+		case '%':
+			i++
+		}
+	}
+	return false
+}
+
+// parsePlaceholders 解析{name}占位符模板
+// 参数:
+//   - text: 模板内容
+//
+// 返回:
+//   - []templatePart: 模板的组成部分
+//   - error: 错误信息，占位符未闭合、为空或出现未转义的"}"时返回
+func parsePlaceholders(text string) ([]templatePart, error) {
+	var parts []templatePart
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "{{"):
+			literal.WriteByte('{')
+			i += 2
+		case strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte('}')
+			i += 2
+		case text[i] == '{':
+			end := strings.IndexAny(text[i+1:], "{}")
+			if end < 0 || text[i+1+end] != '}' {
+				return nil, fmt.Errorf("bad template: unclosed placeholder at offset %d", i)
+			}
+			name := strings.TrimSpace(text[i+1 : i+1+end])
+			if name == "" {
+				return nil, fmt.Errorf("bad template: empty placeholder at offset %d", i)
+			}
+			flush()
+			parts = append(parts, templatePart{variable: name})
+			i += end + 2
+		case text[i] == '}':
+			return nil, fmt.Errorf("bad template: unexpected \"}\" at offset %d", i)
+		default:
+			literal.WriteByte(text[i])
+			i++
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// MessageTemplater 支持自定义短信内容模板的短信服务提供商
+// 目前支持Twilio、亚马逊SNS、Infobip、互亿无线、短信宝、OSON、Netgsm和Azure等在本地渲染短信内容的服务商
+type MessageTemplater interface {
+	// SetMessageTemplate 设置短信内容模板
+	// 参数:
+	//   - text: 模板内容
+	//   - mode: 渲染方式
+	SetMessageTemplate(text string, mode RenderMode)
+}
+
+// SetMessageTemplate 为短信服务提供商设置短信内容模板
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - text: 模板内容
+//   - mode: 渲染方式，为空时使用RENDER_PLACEHOLDER
+//
+// 返回:
+//   - error: 错误信息，模板语法错误或服务商不支持自定义短信内容模板时返回错误
+func SetMessageTemplate(provider SmsProvider, text string, mode RenderMode) error {
+	if _, err := ParseMessageTemplate(text, mode); err != nil {
+		return err
+	}
+
+	templater, ok := provider.(MessageTemplater)
+	if !ok {
+		return fmt.Errorf("provider does not support message template: %T", provider)
+	}
+
+	templater.SetMessageTemplate(text, mode)
+	return nil
+}
@@ -0,0 +1,59 @@
+package sms
+
+import (
+	"testing"
+)
+
+func TestRenderMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		mode    RenderMode
+		param   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"placeholders", "Hi {name}, your code is {code}", "", map[string]string{"name": "Ann", "code": "123456"}, "Hi Ann, your code is 123456", false},
+		{"repeated placeholder", "{code} {code}", RENDER_PLACEHOLDER, map[string]string{"code": "1"}, "1 1", false},
+		{"spaces in placeholder", "code: { code }", "", map[string]string{"code": "1"}, "code: 1", false},
+		{"escaped braces", "{{code}} is {code}", "", map[string]string{"code": "1"}, "{code} is 1", false},
+		{"missing parameter", "Hi {name}, {code}", "", map[string]string{}, "", true},
+		{"unclosed placeholder", "code: {code", "", map[string]string{"code": "1"}, "", true},
+		{"empty placeholder", "code: {}", "", nil, "", true},
+		{"unexpected closing brace", "code: }", "", nil, "", true},
+		{"plain text", "Hello", "", nil, "Hello", false},
+		{"percent in plain text", "Get 50% off today", "", map[string]string{"code": "1"}, "Get 50% off today", false},
+		{"percent in plain text without code", "Get 50% off today", "", nil, "Get 50% off today", false},
+		{"percent with placeholder", "{code} gets 50% off", "", map[string]string{"code": "1"}, "1 gets 50% off", false},
+		{"legacy %s", "Your code is %s", "", map[string]string{"code": "123456"}, "Your code is 123456", false},
+		{"legacy %v", "Your code is %v", "", map[string]string{"code": "123456"}, "Your code is 123456", false},
+		{"legacy missing code", "Your code is %s", "", nil, "", true},
+		{"escaped percent is not legacy", "100%% sure", "", nil, "100%% sure", false},
+		{"text/template", "{{if .name}}Hi {{.name}}, {{end}}code {{.code}}", RENDER_TEXT_TEMPLATE, map[string]string{"name": "Ann", "code": "1"}, "Hi Ann, code 1", false},
+		{"text/template missing key", "code {{.code}}", RENDER_TEXT_TEMPLATE, map[string]string{}, "", true},
+		{"text/template syntax error", "code {{.code", RENDER_TEXT_TEMPLATE, nil, "", true},
+		{"unknown mode", "code", "jinja", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderMessage(tt.text, tt.mode, tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderMessage(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderMessage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageTemplateVariables(t *testing.T) {
+	tmpl, err := ParseMessageTemplate("{name} {code} {name} {{literal}}", "")
+	if err != nil {
+		t.Fatalf("ParseMessageTemplate() error = %v", err)
+	}
+	if got, want := tmpl.Variables(), []string{"name", "code"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
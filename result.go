@@ -0,0 +1,113 @@
+// Package sms 短信发送结果定义
+package sms
+
+// SendResult 短信发送结果
+// 发送失败时也可能返回部分接收方的结果，调用方可据此判断哪些号码已被服务商受理
+type SendResult struct {
+	Provider   string             // 服务提供商类型
+	RequestId  string             // 服务商请求ID
+	Recipients []*RecipientResult // 各接收方的发送结果
+	Raw        string             // 服务商原始响应（批量接口）
+	Attempts   []*SendResult      // 组合服务商（故障转移、路由）中各服务商的发送结果（按发送顺序）
+}
+
+// RecipientResult 单个接收方的发送结果
+type RecipientResult struct {
+	PhoneNumber string // 接收方号码
+	MessageId   string // 服务商消息ID，用于关联后续的状态报告
+	Accepted    bool   // 服务商是否已受理
+	Code        string // 服务商状态码
+	Message     string // 服务商状态描述
+	Fee         int    // 计费条数（服务商未返回时为0）
+	Segments    int    // 短信拆分条数（服务商未返回时为0）
+	Raw         string // 服务商原始响应（逐个号码发送的接口）
+}
+
+// newSendResult 创建短信发送结果
+// 参数:
+//   - provider: 服务提供商类型
+// 返回:
+//   - *SendResult: 短信发送结果
+func newSendResult(provider string) *SendResult {
+	return &SendResult{
+		Provider:   provider,
+		Recipients: make([]*RecipientResult, 0),
+	}
+}
+
+// add 添加接收方发送结果
+// 参数:
+//   - recipient: 接收方发送结果
+// 返回:
+//   - *RecipientResult: 添加的接收方发送结果
+func (r *SendResult) add(recipient *RecipientResult) *RecipientResult {
+	r.Recipients = append(r.Recipients, recipient)
+	return recipient
+}
+
+// acceptAll 将所有号码标记为已受理
+// 适用于只返回一个批次ID的服务商
+// 参数:
+//   - messageId: 服务商消息ID
+//   - phoneNumbers: 接收方号码列表
+func (r *SendResult) acceptAll(messageId string, phoneNumbers []string) {
+	for _, phoneNumber := range phoneNumbers {
+		r.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			MessageId:   messageId,
+			Accepted:    true,
+		})
+	}
+}
+
+// Accepted 获取已被服务商受理的号码
+// 返回:
+//   - []string: 已受理的号码列表
+func (r *SendResult) Accepted() []string {
+	phoneNumbers := make([]string, 0, len(r.Recipients))
+	for _, recipient := range r.Recipients {
+		if recipient.Accepted {
+			phoneNumbers = append(phoneNumbers, recipient.PhoneNumber)
+		}
+	}
+	return phoneNumbers
+}
+
+// Rejected 获取被服务商拒绝的号码
+// 返回:
+//   - []string: 被拒绝的号码列表
+func (r *SendResult) Rejected() []string {
+	phoneNumbers := make([]string, 0)
+	for _, recipient := range r.Recipients {
+		if !recipient.Accepted {
+			phoneNumbers = append(phoneNumbers, recipient.PhoneNumber)
+		}
+	}
+	return phoneNumbers
+}
+
+// Recipient 根据号码获取接收方发送结果
+// 参数:
+//   - phoneNumber: 接收方号码
+// 返回:
+//   - *RecipientResult: 接收方发送结果，未找到时返回nil
+func (r *SendResult) Recipient(phoneNumber string) *RecipientResult {
+	for _, recipient := range r.Recipients {
+		if recipient.PhoneNumber == phoneNumber {
+			return recipient
+		}
+	}
+	return nil
+}
+
+// stringValue 获取字符串指针的值
+// 参数:
+//   - value: 字符串指针
+// 返回:
+//   - string: 字符串值，指针为空时返回空字符串
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
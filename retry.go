@@ -0,0 +1,148 @@
+// Package sms 短信发送重试
+package sms
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// 重试策略默认值
+const (
+	DEFAULT_RETRY_ATTEMPTS   = 3                      // 默认最大发送次数（含首次发送）
+	DEFAULT_RETRY_BACKOFF    = 500 * time.Millisecond // 默认首次重试前的等待时间
+	DEFAULT_RETRY_MAXBACKOFF = 10 * time.Second       // 默认最长等待时间
+	DEFAULT_RETRY_MULTIPLIER = 2.0                    // 默认等待时间增长倍数
+	DEFAULT_RETRY_JITTER     = 0.2                    // 默认抖动比例
+)
+
+// RetryPolicy 重试策略
+// 第n次重试前等待InitialBackoff*Multiplier^(n-1)，不超过MaxBackoff，
+// 再按Jitter比例随机缩短，避免大量请求同时重试
+type RetryPolicy struct {
+	MaxAttempts    int           // 最大发送次数（含首次发送），为0时使用默认值，为1时不重试
+	InitialBackoff time.Duration // 首次重试前的等待时间，为0时使用默认值
+	MaxBackoff     time.Duration // 最长等待时间，为0时使用默认值
+	Multiplier     float64       // 等待时间增长倍数，小于1时使用默认值
+	Jitter         float64       // 抖动比例（0~1），实际等待时间在[backoff*(1-Jitter), backoff]之间随机
+}
+
+// DefaultRetryPolicy 获取默认重试策略
+// 返回:
+//   - RetryPolicy: 最多发送3次，等待时间从500毫秒开始翻倍，最长10秒，抖动20%
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DEFAULT_RETRY_ATTEMPTS,
+		InitialBackoff: DEFAULT_RETRY_BACKOFF,
+		MaxBackoff:     DEFAULT_RETRY_MAXBACKOFF,
+		Multiplier:     DEFAULT_RETRY_MULTIPLIER,
+		Jitter:         DEFAULT_RETRY_JITTER,
+	}
+}
+
+// RetryProvider 带重试的短信服务提供商
+// 发送返回可重试错误（见IsRetryable）时按重试策略等待后重新发送，
+// 只重新发送尚未被受理的号码，已受理的号码不会重复发送
+type RetryProvider struct {
+	provider SmsProvider // 被包装的服务提供商
+	policy   RetryPolicy // 重试策略
+}
+
+// 确保RetryProvider实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &RetryProvider{}
+
+// WithRetry 为短信服务提供商添加重试
+// 参数:
+//   - provider: 短信服务提供商
+//   - policy: 重试策略，未设置的字段使用默认值
+// 返回:
+//   - *RetryProvider: 带重试的短信服务提供商
+func WithRetry(provider SmsProvider, policy RetryPolicy) *RetryProvider {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DEFAULT_RETRY_ATTEMPTS
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DEFAULT_RETRY_BACKOFF
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DEFAULT_RETRY_MAXBACKOFF
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = DEFAULT_RETRY_MULTIPLIER
+	}
+	policy.Jitter = min(max(policy.Jitter, 0), 1)
+
+	return &RetryProvider{
+		provider: provider,
+		policy:   policy,
+	}
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (r *RetryProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := r.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 遇到不可重试的错误、达到最大发送次数或上下文结束时返回最后一次的错误
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 合并后的发送结果，Attempts包含每次发送的结果
+//   - error: 错误信息
+func (r *RetryProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	result := newSendResult("")
+	defer result.sortRecipients(targetPhoneNumber)
+	pending := targetPhoneNumber
+
+	for attempt := 1; ; attempt++ {
+		current, err := SendMessageContext(ctx, r.provider, param, pending...)
+		if current != nil {
+			result.Provider = current.Provider
+			result.RequestId = current.RequestId
+			result.Raw = current.Raw
+			result.Attempts = append(result.Attempts, current)
+		}
+
+		last := err == nil || !IsRetryable(err) || attempt >= r.policy.MaxAttempts
+		pending = result.merge(current, pending, last)
+		if err == nil || (len(pending) == 0 && current != nil) {
+			return result, nil
+		}
+		if last {
+			return result, err
+		}
+
+		timer := time.NewTimer(r.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			result.merge(current, pending, true)
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 计算第n次重试前的等待时间
+// 参数:
+//   - retry: 重试序号（从1开始）
+// 返回:
+//   - time.Duration: 等待时间
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= p.Multiplier
+	}
+	backoff = min(backoff, float64(p.MaxBackoff))
+	backoff -= backoff * p.Jitter * rand.Float64()
+	return time.Duration(backoff)
+}
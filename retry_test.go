@@ -0,0 +1,141 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// flakyProvider 前几次发送失败的服务提供商
+type flakyProvider struct {
+	stubProvider
+	errs []error // 每次发送返回的错误，用完后发送成功
+}
+
+func (f *flakyProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	f.err = nil
+	if f.calls < len(f.errs) {
+		f.err = f.errs[f.calls]
+	}
+	return f.stubProvider.SendMessageContext(ctx, param, targetPhoneNumber...)
+}
+
+func TestRetryProvider(t *testing.T) {
+	numbers := []string{"+8613800138000", "+8613800138001"}
+
+	tests := []struct {
+		name         string
+		provider     *flakyProvider
+		wantErr      error
+		wantSent     [][]string
+		wantAccepted []string
+	}{
+		{
+			name:         "first attempt succeeds",
+			provider:     &flakyProvider{},
+			wantSent:     [][]string{numbers},
+			wantAccepted: numbers,
+		},
+		{
+			name:         "retryable error then success",
+			provider:     &flakyProvider{errs: []error{ErrServiceUnavailable}},
+			wantSent:     [][]string{numbers, numbers},
+			wantAccepted: numbers,
+		},
+		{
+			name:         "retryable error until max attempts",
+			provider:     &flakyProvider{errs: []error{ErrServiceUnavailable, ErrRateLimited, ErrServiceUnavailable}},
+			wantErr:      ErrServiceUnavailable,
+			wantSent:     [][]string{numbers, numbers, numbers},
+			wantAccepted: nil,
+		},
+		{
+			name:         "non-retryable error stops",
+			provider:     &flakyProvider{errs: []error{ErrAuthFailed}},
+			wantErr:      ErrAuthFailed,
+			wantSent:     [][]string{numbers},
+			wantAccepted: nil,
+		},
+		{
+			name:         "non-retryable error after retry stops",
+			provider:     &flakyProvider{errs: []error{ErrServiceUnavailable, ErrInsufficientBalance, ErrServiceUnavailable}},
+			wantErr:      ErrInsufficientBalance,
+			wantSent:     [][]string{numbers, numbers},
+			wantAccepted: nil,
+		},
+		{
+			name:         "only pending numbers are resent",
+			provider:     &flakyProvider{stubProvider: stubProvider{accept: numbers[:1]}, errs: []error{ErrServiceUnavailable}},
+			wantSent:     [][]string{numbers, numbers[1:]},
+			wantAccepted: numbers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry := WithRetry(tt.provider, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+			result, err := retry.SendMessageContext(context.Background(), nil, numbers...)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("SendMessageContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(tt.provider.sent) != fmt.Sprint(tt.wantSent) {
+				t.Errorf("sent %v, want %v", tt.provider.sent, tt.wantSent)
+			}
+			if got := result.Accepted(); fmt.Sprint(got) != fmt.Sprint(tt.wantAccepted) {
+				t.Errorf("Accepted() = %v, want %v", got, tt.wantAccepted)
+			}
+			if len(result.Attempts) != len(tt.wantSent) {
+				t.Errorf("got %d attempts, want %d", len(result.Attempts), len(tt.wantSent))
+			}
+		})
+	}
+}
+
+func TestRetryProviderCanceled(t *testing.T) {
+	provider := &stubProvider{err: ErrServiceUnavailable}
+	retry := WithRetry(provider, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := retry.SendMessageContext(ctx, nil, "+8613800138000")
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("SendMessageContext() error = %v, want %v", err, ErrServiceUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SendMessageContext() took %v, want backoff to stop on cancel", elapsed)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+	if len(result.Recipients) != 1 || result.Recipients[0].Accepted {
+		t.Errorf("Recipients = %v, want one rejected recipient", result.Recipients)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := WithRetry(nil, RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}).policy
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			if got := policy.backoff(tt.retry); got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.retry, got, tt.want)
+			}
+		})
+	}
+}
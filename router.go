@@ -0,0 +1,175 @@
+// Package sms 按国家代码路由的短信服务提供商
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// RouterProvider 按国家代码路由的短信服务提供商
+// 根据接收方号码的国家代码（最长前缀匹配）选择服务提供商，未匹配的号码使用默认服务提供商；
+// 一次发送给多个号码时按路由拆分为多个批次分别发送，再合并发送结果
+type RouterProvider struct {
+	routes          map[string]SmsProvider // 按国家代码（不含"+"）索引的服务提供商
+	defaultProvider SmsProvider            // 默认服务提供商
+}
+
+// 确保RouterProvider实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &RouterProvider{}
+
+// routeBatch 同一路由的号码批次
+type routeBatch struct {
+	prefix       string      // 匹配的国家代码或号码前缀，默认服务提供商为空
+	provider     SmsProvider // 服务提供商
+	phoneNumbers []string    // 号码列表
+}
+
+// NewRouterProvider 创建按国家代码路由的短信服务提供商
+// 参数:
+//   - routes: 国家代码或号码前缀（如"86"、"+7"、"1242"）到服务提供商的映射
+//   - defaultProvider: 默认服务提供商，为nil时未匹配的号码发送失败
+// 返回:
+//   - *RouterProvider: 路由短信服务提供商实例
+//   - error: 错误信息
+func NewRouterProvider(routes map[string]SmsProvider, defaultProvider SmsProvider) (*RouterProvider, error) {
+	if len(routes) == 0 && defaultProvider == nil {
+		return nil, fmt.Errorf("missing parameter: routes")
+	}
+
+	router := &RouterProvider{
+		routes:          make(map[string]SmsProvider, len(routes)),
+		defaultProvider: defaultProvider,
+	}
+	for prefix, provider := range routes {
+		code := strings.TrimPrefix(prefix, "+")
+		if code == "" || strings.Trim(code, "0123456789") != "" {
+			return nil, fmt.Errorf("bad parameter: route prefix %q", prefix)
+		}
+		if provider == nil {
+			return nil, fmt.Errorf("bad parameter: route %q provider is nil", prefix)
+		}
+		router.routes[code] = provider
+	}
+
+	return router, nil
+}
+
+// Route 获取号码对应的服务提供商
+// 参数:
+//   - phoneNumber: 包含国家代码的手机号码，如"+8613800138000"、"0086 138 0013 8000"；不以"+"或"00"开头的号码使用默认服务提供商
+// 返回:
+//   - SmsProvider: 服务提供商，无可用路由时返回nil
+func (r *RouterProvider) Route(phoneNumber string) SmsProvider {
+	_, provider := r.route(phoneNumber)
+	return provider
+}
+
+// route 获取号码匹配的路由
+// 参数:
+//   - phoneNumber: 手机号码
+// 返回:
+//   - string: 匹配的国家代码或号码前缀，使用默认服务提供商时为空
+//   - SmsProvider: 服务提供商，无可用路由时返回nil
+func (r *RouterProvider) route(phoneNumber string) (string, SmsProvider) {
+	number, err := phone.Parse(phoneNumber, "")
+	if err != nil {
+		return "", r.defaultProvider
+	}
+
+	digits := number.Format(phone.FORMAT_DIGITS)
+	for i := len(digits); i > 0; i-- {
+		if provider, ok := r.routes[digits[:i]]; ok {
+			return digits[:i], provider
+		}
+	}
+	return "", r.defaultProvider
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (r *RouterProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := r.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 各批次依次发送，某个批次失败不影响其他批次；无可用路由的号码标记为未受理并返回ErrInvalidNumber
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 合并后的发送结果，只有一个批次时Provider为该批次的服务商，Attempts包含各批次的发送结果
+//   - error: 错误信息，多个批次失败时合并各批次的错误
+func (r *RouterProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	result := newSendResult("")
+
+	var errs []error
+	batches := make([]*routeBatch, 0)
+	for _, phoneNumber := range targetPhoneNumber {
+		prefix, provider := r.route(phoneNumber)
+		if provider == nil {
+			result.add(&RecipientResult{
+				PhoneNumber: phoneNumber,
+				Message:     "no route",
+			})
+			errs = append(errs, fmt.Errorf("%w: no route for %s", ErrInvalidNumber, phoneNumber))
+			continue
+		}
+
+		var batch *routeBatch
+		// 按匹配的路由分组，服务提供商可能是不可比较的类型，不能直接用==比较
+		for _, item := range batches {
+			if item.prefix == prefix {
+				batch = item
+				break
+			}
+		}
+		if batch == nil {
+			batch = &routeBatch{prefix: prefix, provider: provider}
+			batches = append(batches, batch)
+		}
+		batch.phoneNumbers = append(batch.phoneNumbers, phoneNumber)
+	}
+
+	for _, batch := range batches {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		attempt, err := SendMessageContext(ctx, batch.provider, param, batch.phoneNumbers...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if attempt == nil {
+			continue
+		}
+
+		result.Attempts = append(result.Attempts, attempt)
+		result.merge(attempt, batch.phoneNumbers, true)
+	}
+
+	result.sortRecipients(targetPhoneNumber)
+
+	if len(batches) == 1 && len(result.Attempts) == 1 {
+		attempt := result.Attempts[0]
+		result.Provider = attempt.Provider
+		result.RequestId = attempt.RequestId
+		result.Raw = attempt.Raw
+	}
+
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRouterRoute(t *testing.T) {
+	china, russia, bahamas, fallback := &stubProvider{}, &stubProvider{}, &stubProvider{}, &stubProvider{}
+	router, err := NewRouterProvider(map[string]SmsProvider{"86": china, "+7": russia, "1242": bahamas}, fallback)
+	if err != nil {
+		t.Fatalf("NewRouterProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		want        SmsProvider
+	}{
+		{"country code", "+8613800138000", china},
+		{"international prefix and spaces", "0086 138 0013 8000", china},
+		{"one-digit country code", "+79123456789", russia},
+		{"longest prefix wins", "+12423591234", bahamas},
+		{"shared country code without route", "+14155550100", fallback},
+		{"national number", "13800138000", fallback},
+		{"unparsable number", "abc", fallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := router.Route(tt.phoneNumber); got != tt.want {
+				t.Errorf("Route(%q) = %p, want %p", tt.phoneNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterSendMessage(t *testing.T) {
+	numbers := []string{"+8613800138000", "+905551234567", "+8613800138001", "+14155550100"}
+
+	tests := []struct {
+		name         string
+		china        *stubProvider
+		fallback     SmsProvider
+		wantChina    [][]string
+		wantAccepted []string
+		wantErr      error
+	}{
+		{
+			name:         "batches by country",
+			china:        &stubProvider{},
+			fallback:     &stubProvider{},
+			wantChina:    [][]string{{numbers[0], numbers[2]}},
+			wantAccepted: numbers,
+		},
+		{
+			name:         "failed batch does not affect others",
+			china:        &stubProvider{err: ErrServiceUnavailable},
+			fallback:     &stubProvider{},
+			wantChina:    [][]string{{numbers[0], numbers[2]}},
+			wantAccepted: []string{numbers[1], numbers[3]},
+			wantErr:      ErrServiceUnavailable,
+		},
+		{
+			name:         "no route",
+			china:        &stubProvider{},
+			fallback:     nil,
+			wantChina:    [][]string{{numbers[0], numbers[2]}},
+			wantAccepted: []string{numbers[0], numbers[2]},
+			wantErr:      ErrInvalidNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := NewRouterProvider(map[string]SmsProvider{"86": tt.china}, tt.fallback)
+			if err != nil {
+				t.Fatalf("NewRouterProvider() error = %v", err)
+			}
+
+			result, err := router.SendMessageContext(context.Background(), nil, numbers...)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("SendMessageContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(tt.china.sent) != fmt.Sprint(tt.wantChina) {
+				t.Errorf("+86 provider sent %v, want %v", tt.china.sent, tt.wantChina)
+			}
+			if got := result.Accepted(); fmt.Sprint(got) != fmt.Sprint(tt.wantAccepted) {
+				t.Errorf("Accepted() = %v, want %v", got, tt.wantAccepted)
+			}
+			if len(result.Recipients) != len(numbers) {
+				t.Errorf("got %d recipients, want %d", len(result.Recipients), len(numbers))
+			}
+		})
+	}
+}
+
+func TestNewRouterProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[string]SmsProvider
+	}{
+		{"no routes", nil},
+		{"empty prefix", map[string]SmsProvider{"+": &stubProvider{}}},
+		{"non-digit prefix", map[string]SmsProvider{"CN": &stubProvider{}}},
+		{"nil provider", map[string]SmsProvider{"86": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRouterProvider(tt.routes, nil); err == nil {
+				t.Error("NewRouterProvider() error = nil, want error")
+			}
+		})
+	}
+}
+
+// mapProvider 不可比较（包含map）的值类型短信服务提供商
+type mapProvider struct {
+	sent map[string]int // 各号码的发送次数
+}
+
+// SendMessage 发送短信
+func (p mapProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	for _, phoneNumber := range targetPhoneNumber {
+		p.sent[phoneNumber]++
+	}
+	return nil
+}
+
+func TestRouterNonComparableProvider(t *testing.T) {
+	china := mapProvider{sent: make(map[string]int)}
+	other := mapProvider{sent: make(map[string]int)}
+	router, err := NewRouterProvider(map[string]SmsProvider{"86": china}, other)
+	if err != nil {
+		t.Fatalf("NewRouterProvider() error = %v", err)
+	}
+
+	if err := router.SendMessage(nil, "+8613800138000", "+14155550100", "+8613800138001"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if len(china.sent) != 2 || len(other.sent) != 1 {
+		t.Errorf("sent = %v, %v, want 2 numbers via +86 and 1 via default", china.sent, other.sent)
+	}
+}
@@ -0,0 +1,94 @@
+// Package sms 短信签名管理
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SignSource 标准化的短信签名来源
+type SignSource string
+
+// 短信签名来源常量定义
+const (
+	SIGN_COMPANY   SignSource = "company"   // 企事业单位的全称或简称
+	SIGN_WEBSITE   SignSource = "website"   // 已备案网站的全称或简称
+	SIGN_APP       SignSource = "app"       // APP应用的全称或简称
+	SIGN_WECHAT    SignSource = "wechat"    // 公众号或小程序的全称或简称
+	SIGN_TRADEMARK SignSource = "trademark" // 已注册商标的全称或简称
+)
+
+// SignRequest 创建短信签名的请求
+type SignRequest struct {
+	Name          string     // 签名名称，不含【】
+	Source        SignSource // 签名来源
+	International bool       // 是否为国际/港澳台短信签名
+	Proof         []byte     // 资质证明图片，如营业执照、商标注册书
+	ProofFormat   string     // 资质证明图片格式，如"jpg"、"png"
+	Remark        string     // 申请说明，如使用场景，阿里云必填
+}
+
+// Sign 短信签名
+type Sign struct {
+	Provider      string       // 服务提供商类型
+	SignId        string       // 签名ID（阿里云为签名名称）
+	Name          string       // 签名名称
+	International bool         // 是否为国际/港澳台短信签名
+	Status        ReviewStatus // 审核状态
+	Reason        string       // 审核意见，通常为审核未通过的原因
+	CreatedAt     time.Time    // 提交时间（服务商未返回时为零值）
+	Raw           string       // 服务商原始响应
+}
+
+// SignManager 支持管理短信签名的短信服务提供商
+// 目前支持阿里云、腾讯云和百度云
+type SignManager interface {
+	// CreateSign 创建短信签名并提交审核
+	// 参数:
+	//   - ctx: 上下文
+	//   - request: 创建短信签名的请求
+	// 返回:
+	//   - *Sign: 短信签名，审核状态为REVIEW_PENDING
+	//   - error: 错误信息
+	CreateSign(ctx context.Context, request SignRequest) (*Sign, error)
+
+	// ListSigns 查询全部短信签名
+	// 参数:
+	//   - ctx: 上下文
+	// 返回:
+	//   - []*Sign: 短信签名列表
+	//   - error: 错误信息，服务商未提供查询签名列表的接口时返回errors.ErrUnsupported
+	ListSigns(ctx context.Context) ([]*Sign, error)
+
+	// GetSign 查询短信签名及其审核状态
+	// 参数:
+	//   - ctx: 上下文
+	//   - signId: 签名ID
+	// 返回:
+	//   - *Sign: 短信签名
+	//   - error: 错误信息
+	GetSign(ctx context.Context, signId string) (*Sign, error)
+
+	// DeleteSign 删除短信签名
+	// 参数:
+	//   - ctx: 上下文
+	//   - signId: 签名ID
+	// 返回:
+	//   - error: 错误信息
+	DeleteSign(ctx context.Context, signId string) error
+}
+
+// GetSignManager 获取短信服务提供商的签名管理接口
+// 参数:
+//   - provider: 短信服务提供商实例
+// 返回:
+//   - SignManager: 签名管理接口
+//   - error: 错误信息，服务商不支持管理短信签名时返回错误
+func GetSignManager(provider SmsProvider) (SignManager, error) {
+	manager, ok := provider.(SignManager)
+	if !ok {
+		return nil, fmt.Errorf("provider does not support sign management: %T", provider)
+	}
+	return manager, nil
+}
@@ -1,102 +1,261 @@
-// Package sms 短信宝短信服务实现
-package sms
-
-import (
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
-)
-
-// SmsBaoClient 短信宝客户端
-// 封装短信宝API调用
-type SmsBaoClient struct {
-	username string // 用户名
-	apikey   string // API密钥
-	sign     string // 短信签名
-	template string // 短信模板
-	goodsid  string // 商品ID
-}
-
-// GetSmsbaoClient 创建短信宝客户端
-// 参数:
-//   - username: 短信宝用户名
-//   - apikey: 短信宝API密钥
-//   - sign: 短信签名
-//   - template: 短信模板
-//   - other: 其他参数（[0]为商品ID，可选）
-// 返回:
-//   - *SmsBaoClient: 短信宝客户端实例
-//   - error: 错误信息
-func GetSmsbaoClient(username string, apikey string, sign string, template string, other []string) (*SmsBaoClient, error) {
-	var goodsid string
-	if len(other) == 0 {
-		goodsid = ""
-	} else {
-		goodsid = other[0]
-	}
-	return &SmsBaoClient{
-		username: username,
-		apikey:   apikey,
-		sign:     sign,
-		template: template,
-		goodsid:  goodsid,
-	}, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 目标手机号码列表（仅支持中国大陆号码）
-// 返回:
-//   - error: 错误信息
-func (c *SmsBaoClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	smsContent := url.QueryEscape("【" + c.sign + "】" + fmt.Sprintf(c.template, code))
-	for _, mobile := range targetPhoneNumber {
-		if strings.HasPrefix(mobile, "+86") {
-			mobile = mobile[3:]
-		} else if strings.HasPrefix(mobile, "+") {
-			return fmt.Errorf("unsupported country code")
-		}
-		// 短信宝API接口地址
-		url := fmt.Sprintf("https://api.smsbao.com/sms?u=%s&p=%s&g=%s&m=%s&c=%s", c.username, c.apikey, c.goodsid, mobile, smsContent)
-
-		client := &http.Client{}
-		req, _ := http.NewRequest("GET", url, nil)
-		resp, err := client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		switch string(body) {
-		case "30":
-			return fmt.Errorf("password error")
-		case "40":
-			return fmt.Errorf("account not exist")
-		case "41":
-			return fmt.Errorf("overdue account")
-		case "43":
-			return fmt.Errorf("IP address limit")
-		case "50":
-			return fmt.Errorf("content contain forbidden words")
-		case "51":
-			return fmt.Errorf("phone number incorrect")
-		}
-	}
-
-	return nil
-}
+// Package sms 短信宝短信服务实现
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// SMSBAO_ENDPOINT 短信宝默认服务端点
+const SMSBAO_ENDPOINT = "https://api.smsbao.com"
+
+// smsbaoNumberFormat 短信宝要求的号码格式，仅支持不带国家代码的中国大陆号码
+var smsbaoNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL}
+
+// SmsBaoClient 短信宝客户端
+// 封装短信宝API调用
+type SmsBaoClient struct {
+	username     string       // 用户名
+	apikey       string       // API密钥
+	sign         string       // 短信签名
+	template     string       // 短信模板
+	goodsid      string       // 商品ID
+	endpoint     string       // 服务端点
+	httpClient   *http.Client // HTTP客户端
+	segmentLimit SegmentLimit // 拆分条数限制
+	renderMode   RenderMode   // 短信模板的渲染方式
+}
+
+// init 注册短信宝短信服务
+func init() {
+	Register(SMS_SMSBAO, func(config ProviderConfig) (SmsProvider, error) {
+		return GetSmsbaoClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.GoodsId))
+	}, "AccessId", "AccessKey", "Sign", "Template")
+}
+
+// GetSmsbaoClient 创建短信宝客户端
+// 参数:
+//   - username: 短信宝用户名
+//   - apikey: 短信宝API密钥
+//   - sign: 短信签名
+//   - template: 短信模板
+//   - other: 其他参数（[0]为商品ID，可选）
+// 返回:
+//   - *SmsBaoClient: 短信宝客户端实例
+//   - error: 错误信息
+func GetSmsbaoClient(username string, apikey string, sign string, template string, other []string) (*SmsBaoClient, error) {
+	var goodsid string
+	if len(other) == 0 {
+		goodsid = ""
+	} else {
+		goodsid = other[0]
+	}
+	return &SmsBaoClient{
+		username:   username,
+		apikey:     apikey,
+		sign:       sign,
+		template:   template,
+		goodsid:    goodsid,
+		endpoint:   SMSBAO_ENDPOINT,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *SmsBaoClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为SMSBAO_ENDPOINT
+func (c *SmsBaoClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SetSegmentLimit 设置短信拆分条数限制
+// 参数:
+//   - limit: 拆分条数限制
+func (c *SmsBaoClient) SetSegmentLimit(limit SegmentLimit) {
+	c.segmentLimit = limit
+}
+
+// SetMessageTemplate 设置短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (c *SmsBaoClient) SetMessageTemplate(text string, mode RenderMode) {
+	c.template = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表（仅支持中国大陆号码）
+// 返回:
+//   - error: 错误信息
+func (c *SmsBaoClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表（仅支持中国大陆号码）
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *SmsBaoClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	text, err := RenderMessage(c.template, c.renderMode, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	content := "【" + c.sign + "】" + text
+	if err = c.segmentLimit.check(content); err != nil {
+		return nil, err
+	}
+
+	smsContent := url.QueryEscape(content)
+	result := newSendResult(SMS_SMSBAO)
+	for _, phoneNumber := range targetPhoneNumber {
+		mobile, err := smsbaoNumberFormat.format(phoneNumber)
+		if err != nil {
+			return result, err
+		}
+		// 短信宝API接口地址
+		url := fmt.Sprintf("%s?u=%s&p=%s&g=%s&m=%s&c=%s", joinEndpoint(c.endpoint, "/sms"), c.username, c.apikey, c.goodsid, mobile, smsContent)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return result, err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return result, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return result, err
+		}
+
+		statusCode := strings.TrimSpace(string(body))
+		recipient := result.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			Accepted:    statusCode == "0",
+			Code:        statusCode,
+			Raw:         string(body),
+		})
+		if !recipient.Accepted {
+			smsErr := smsbaoError(statusCode).forNumber(phoneNumber)
+			recipient.Message = smsErr.Message
+			return result, smsErr
+		}
+	}
+
+	return result, nil
+}
+
+// QueryBalance 查询账户余额
+// 通过/query接口查询剩余短信条数
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为短信条数
+//   - error: 错误信息
+func (c *SmsBaoClient) QueryBalance(ctx context.Context) (*Balance, error) {
+	url := fmt.Sprintf("%s?u=%s&p=%s", joinEndpoint(c.endpoint, "/query"), c.username, c.apikey)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_SMSBAO, resp.StatusCode, string(body))
+	}
+
+	// 成功时第一行为状态码0，第二行为"已发送条数,剩余条数"
+	lines := strings.Fields(string(body))
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("bad response: %q", string(body))
+	}
+	if lines[0] != "0" {
+		return nil, smsbaoError(lines[0])
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("bad response: %q", string(body))
+	}
+
+	counts := strings.Split(lines[1], ",")
+	amount, err := strconv.ParseFloat(counts[len(counts)-1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad response: %q", string(body))
+	}
+
+	return &Balance{
+		Provider: SMS_SMSBAO,
+		Amount:   amount,
+		Unit:     BALANCE_UNIT_MESSAGES,
+		Raw:      string(body),
+	}, nil
+}
+
+// smsbaoErrorMessages 短信宝状态码对应的错误信息
+var smsbaoErrorMessages = map[string]string{
+	"30": "password error",
+	"40": "account not exist",
+	"41": "overdue account",
+	"43": "IP address limit",
+	"50": "content contain forbidden words",
+	"51": "phone number incorrect",
+}
+
+// smsbaoErrorCodes 短信宝状态码与标准错误的对应关系
+var smsbaoErrorCodes = map[string]error{
+	"30": ErrAuthFailed,
+	"40": ErrAuthFailed,
+	"41": ErrInsufficientBalance,
+	"43": ErrAuthFailed,
+	"50": ErrContentBlocked,
+	"51": ErrInvalidNumber,
+}
+
+// smsbaoError 将短信宝失败状态码转换为短信服务商错误
+// 参数:
+//   - statusCode: 短信宝返回的状态码
+// 返回:
+//   - *SmsError: 短信服务商错误
+func smsbaoError(statusCode string) *SmsError {
+	message, ok := smsbaoErrorMessages[statusCode]
+	if !ok {
+		message = "unknown status code"
+	}
+
+	return newSmsError(SMS_SMSBAO, statusCode, message, smsbaoErrorCodes)
+}
@@ -0,0 +1,124 @@
+// Package sms 短信送达状态查询
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeliveryStatus 标准化的短信送达状态
+type DeliveryStatus string
+
+// 短信送达状态常量定义
+const (
+	DELIVERY_QUEUED    DeliveryStatus = "queued"    // 服务商已受理，尚未提交运营商
+	DELIVERY_SENT      DeliveryStatus = "sent"      // 已提交运营商，等待状态报告
+	DELIVERY_DELIVERED DeliveryStatus = "delivered" // 已送达
+	DELIVERY_FAILED    DeliveryStatus = "failed"    // 发送失败或无法送达
+	DELIVERY_UNKNOWN   DeliveryStatus = "unknown"   // 未查询到状态
+)
+
+// StatusQuery 送达状态查询条件
+type StatusQuery struct {
+	MessageId   string    // 发送时返回的服务商消息ID（RecipientResult.MessageId）
+	PhoneNumber string    // 接收方号码，阿里云和腾讯云必填
+	SentAt      time.Time // 发送时间，用于确定查询的时间范围，为零值时查询最近的发送记录
+}
+
+// DeliveryReport 短信送达状态报告
+type DeliveryReport struct {
+	Provider    string         // 服务提供商类型
+	MessageId   string         // 服务商消息ID
+	PhoneNumber string         // 接收方号码
+	Status      DeliveryStatus // 标准化的送达状态
+	Code        string         // 服务商状态码
+	Message     string         // 服务商状态描述
+	SentAt      time.Time      // 发送时间（服务商未返回时为零值）
+	DeliveredAt time.Time      // 送达或失败时间（服务商未返回时为零值）
+	Raw         string         // 服务商原始响应
+}
+
+// StatusQuerier 支持查询短信送达状态的短信服务提供商
+// 目前支持阿里云、腾讯云、Twilio和Infobip
+type StatusQuerier interface {
+	// QueryStatus 查询短信送达状态
+	// 阿里云同一批次的号码共用一个消息ID，因此按查询条件而不是消息ID返回状态报告
+	// 参数:
+	//   - ctx: 上下文
+	//   - queries: 查询条件列表
+	// 返回:
+	//   - []*DeliveryReport: 与查询条件一一对应的状态报告，未查询到时Status为DELIVERY_UNKNOWN
+	//   - error: 错误信息
+	QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error)
+}
+
+// QueryStatus 查询短信送达状态
+// 参数:
+//   - ctx: 上下文
+//   - provider: 短信服务提供商实例，需为发送短信的服务商客户端
+//   - queries: 查询条件列表
+// 返回:
+//   - []*DeliveryReport: 与查询条件一一对应的状态报告
+//   - error: 错误信息，服务商不支持查询送达状态时返回错误
+func QueryStatus(ctx context.Context, provider SmsProvider, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	querier, ok := provider.(StatusQuerier)
+	if !ok {
+		return nil, fmt.Errorf("provider does not support status query: %T", provider)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("missing parameter: queries")
+	}
+
+	return querier.QueryStatus(ctx, queries...)
+}
+
+// StatusQueries 根据发送结果生成送达状态查询条件
+// 参数:
+//   - sentAt: 发送时间
+// 返回:
+//   - []StatusQuery: 已受理号码的查询条件
+func (r *SendResult) StatusQueries(sentAt time.Time) []StatusQuery {
+	queries := make([]StatusQuery, 0, len(r.Recipients))
+	for _, recipient := range r.Recipients {
+		if !recipient.Accepted {
+			continue
+		}
+		queries = append(queries, StatusQuery{
+			MessageId:   recipient.MessageId,
+			PhoneNumber: recipient.PhoneNumber,
+			SentAt:      sentAt,
+		})
+	}
+	return queries
+}
+
+// newDeliveryReport 创建状态为未知的送达状态报告
+// 参数:
+//   - provider: 服务提供商类型
+//   - query: 查询条件
+// 返回:
+//   - *DeliveryReport: 送达状态报告
+func newDeliveryReport(provider string, query StatusQuery) *DeliveryReport {
+	return &DeliveryReport{
+		Provider:    provider,
+		MessageId:   query.MessageId,
+		PhoneNumber: query.PhoneNumber,
+		Status:      DELIVERY_UNKNOWN,
+	}
+}
+
+// parseTime 按指定格式解析时间
+// 参数:
+//   - layout: 时间格式
+//   - value: 时间字符串
+//   - loc: 时间字符串未包含时区时使用的时区
+// 返回:
+//   - time.Time: 解析后的时间，解析失败时为零值
+func parseTime(layout string, value string, loc *time.Location) time.Time {
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
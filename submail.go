@@ -1,154 +1,395 @@
-// Package sms SUBMAIL短信服务实现
-package sms
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"mime/multipart"
-	"net/http"
-	"strings"
-)
-
-// SubmailClient SUBMAIL短信客户端
-// 封装SUBMAIL短信API调用
-type SubmailClient struct {
-	api       string // API地址
-	appid     string // 应用ID
-	signature string // 签名
-	project   string // 项目标识
-}
-
-// SubmailResult SUBMAIL响应结果结构体
-type SubmailResult struct {
-	Status string `json:"status"` // 状态
-	Code   int    `json:"code"`   // 状态码
-	Msg    string `json:"msg"`    // 消息
-}
-
-// buildSubmailPostdata 构建SUBMAIL POST数据
-// 参数:
-//   - param: 短信模板参数
-//   - appid: 应用ID
-//   - signature: 签名
-//   - project: 项目标识
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - map[string]string: POST数据
-//   - error: 错误信息
-func buildSubmailPostdata(param map[string]string, appid string, signature string, project string, targetPhoneNumber []string) (map[string]string, error) {
-	multi := make([]map[string]interface{}, 0, 32)
-
-	for _, phoneNumber := range targetPhoneNumber[0:] {
-		multi = append(multi, map[string]interface{}{
-			"to":   phoneNumber,
-			"vars": param,
-		})
-	}
-
-	m, err := json.Marshal(multi)
-	if err != nil {
-		return nil, err
-	}
-
-	postdata := make(map[string]string)
-	postdata["appid"] = appid
-	postdata["signature"] = signature
-	postdata["project"] = project
-	postdata["multi"] = string(m)
-	return postdata, nil
-}
-
-// GetSubmailClient 创建SUBMAIL短信客户端
-// 参数:
-//   - appid: 应用ID
-//   - signature: 签名
-//   - project: 项目标识
-// 返回:
-//   - *SubmailClient: SUBMAIL短信客户端实例
-//   - error: 错误信息
-func GetSubmailClient(appid string, signature string, project string) (*SubmailClient, error) {
-	submailClient := &SubmailClient{
-		api:       "https://api-v4.mysubmail.com/sms/multixsend",
-		appid:     appid,
-		signature: signature,
-		project:   project,
-	}
-	return submailClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *SubmailClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	postdata, err := buildSubmailPostdata(param, c.appid, c.signature, c.project, targetPhoneNumber)
-	if err != nil {
-		return err
-	}
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	for key, val := range postdata {
-		err = writer.WriteField(key, val)
-		if err != nil {
-			return err
-		}
-	}
-
-	contentType := writer.FormDataContentType()
-	err = writer.Close()
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(c.api, contentType, body)
-	if err != nil {
-		return err
-	}
-
-	result, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	return handleSubmailResult(result)
-}
-
-// handleSubmailResult 处理SUBMAIL响应结果
-// 参数:
-//   - result: 响应结果字节数组
-// 返回:
-//   - error: 错误信息
-func handleSubmailResult(result []byte) error {
-	var submailSuccessResult []SubmailResult
-	err := json.Unmarshal(result, &submailSuccessResult)
-	if err != nil {
-		var submailErrorResult SubmailResult
-		err = json.Unmarshal(result, &submailErrorResult)
-		if err != nil {
-			return err
-		}
-
-		if submailErrorResult.Msg != "" {
-			return fmt.Errorf(submailErrorResult.Msg)
-		}
-	}
-
-	errMsgs := []string{}
-	for _, submailResult := range submailSuccessResult {
-		if submailResult.Status != "success" {
-			errMsg := fmt.Sprintf("%s, %d, %s", submailResult.Status, submailResult.Code, submailResult.Msg)
-			errMsgs = append(errMsgs, errMsg)
-		}
-	}
-
-	if len(errMsgs) > 0 {
-		return fmt.Errorf(strings.Join(errMsgs, "|"))
-	}
-
-	return nil
-}
+// Package sms SUBMAIL短信服务实现
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// submailNumberFormat SUBMAIL国内短信接口要求的号码格式，仅支持不带国家代码的中国大陆号码
+var submailNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL}
+
+// submailErrorCodes SUBMAIL错误码与标准错误的对应关系
+var submailErrorCodes = map[string]error{
+	"101": ErrAuthFailed, // 不正确的APP ID
+	"102": ErrAuthFailed, // 应用已被禁用
+	"103": ErrAuthFailed, // 未启用开发者设置
+	"104": ErrAuthFailed, // 应用无此API的使用权限
+}
+
+// SUBMAIL_ENDPOINT SUBMAIL默认服务端点
+const SUBMAIL_ENDPOINT = "https://api-v4.mysubmail.com"
+
+// SubmailClient SUBMAIL短信客户端
+// 封装SUBMAIL短信API调用
+type SubmailClient struct {
+	appid      string       // 应用ID
+	signature  string       // 签名
+	project    string       // 项目标识
+	endpoint   string       // 服务端点
+	httpClient *http.Client // HTTP客户端
+}
+
+// SubmailVerifier SUBMAIL状态推送（SUBHOOK）签名校验器
+// 校验推送参数中的signature是否等于md5(token + 应用密钥)
+type SubmailVerifier struct {
+	AppKey string // 应用密钥（即GetSubmailClient的signature参数）
+}
+
+// 确保SubmailVerifier实现了WebhookVerifier接口
+var _ WebhookVerifier = SubmailVerifier{}
+
+// SubmailResult SUBMAIL响应结果结构体
+type SubmailResult struct {
+	Status string `json:"status"`  // 状态
+	Code   int    `json:"code"`    // 状态码
+	Msg    string `json:"msg"`     // 消息
+	To     string `json:"to"`      // 接收方号码
+	SendId string `json:"send_id"` // 发送ID
+	Fee    int    `json:"fee"`     // 计费条数
+}
+
+// SubmailBalanceResponse SUBMAIL余额查询响应结构体
+type SubmailBalanceResponse struct {
+	Status               string      `json:"status"`                // 状态
+	Code                 int         `json:"code"`                  // 状态码
+	Msg                  string      `json:"msg"`                   // 消息
+	Balance              json.Number `json:"balance"`               // 通用短信余额
+	TransactionalBalance json.Number `json:"transactional_balance"` // 事务类短信余额
+}
+
+// buildSubmailPostdata 构建SUBMAIL POST数据
+// 参数:
+//   - param: 短信模板参数
+//   - appid: 应用ID
+//   - signature: 签名
+//   - project: 项目标识
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - map[string]string: POST数据
+//   - error: 错误信息
+func buildSubmailPostdata(param map[string]string, appid string, signature string, project string, targetPhoneNumber []string) (map[string]string, error) {
+	multi := make([]map[string]interface{}, 0, 32)
+
+	for _, phoneNumber := range targetPhoneNumber[0:] {
+		multi = append(multi, map[string]interface{}{
+			"to":   phoneNumber,
+			"vars": param,
+		})
+	}
+
+	m, err := json.Marshal(multi)
+	if err != nil {
+		return nil, err
+	}
+
+	postdata := make(map[string]string)
+	postdata["appid"] = appid
+	postdata["signature"] = signature
+	postdata["project"] = project
+	postdata["multi"] = string(m)
+	return postdata, nil
+}
+
+// init 注册SUBMAIL短信服务
+func init() {
+	Register(SMS_SUBMAIL, func(config ProviderConfig) (SmsProvider, error) {
+		return GetSubmailClient(config.AccessId, config.AccessKey, config.Template)
+	}, "AccessId", "AccessKey", "Template")
+}
+
+// GetSubmailClient 创建SUBMAIL短信客户端
+// 参数:
+//   - appid: 应用ID
+//   - signature: 签名
+//   - project: 项目标识
+// 返回:
+//   - *SubmailClient: SUBMAIL短信客户端实例
+//   - error: 错误信息
+func GetSubmailClient(appid string, signature string, project string) (*SubmailClient, error) {
+	submailClient := &SubmailClient{
+		appid:      appid,
+		signature:  signature,
+		project:    project,
+		endpoint:   SUBMAIL_ENDPOINT,
+		httpClient: &http.Client{},
+	}
+	return submailClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *SubmailClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL，默认为SUBMAIL_ENDPOINT
+func (c *SubmailClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *SubmailClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *SubmailClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	phoneNumbers, err := submailNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	postdata, err := buildSubmailPostdata(param, c.appid, c.signature, c.project, phoneNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, val := range postdata {
+		err = writer.WriteField(key, val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	contentType := writer.FormDataContentType()
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", joinEndpoint(c.endpoint, "/sms/multixsend"), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSendResult(SMS_SUBMAIL)
+	result.Raw = string(respBody)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return result, newHttpStatusError(SMS_SUBMAIL, resp.StatusCode, string(respBody))
+	}
+
+	err = handleSubmailResult(respBody, result)
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+	return result, err
+}
+
+// handleSubmailResult 处理SUBMAIL响应结果
+// 参数:
+//   - result: 响应结果字节数组
+//   - sendResult: 发送结果，用于记录各接收方的受理状态
+// 返回:
+//   - error: 错误信息
+func handleSubmailResult(result []byte, sendResult *SendResult) error {
+	var submailSuccessResult []SubmailResult
+	err := json.Unmarshal(result, &submailSuccessResult)
+	if err != nil {
+		var submailErrorResult SubmailResult
+		err = json.Unmarshal(result, &submailErrorResult)
+		if err != nil {
+			return err
+		}
+
+		if submailErrorResult.Msg != "" {
+			return newSmsError(SMS_SUBMAIL, strconv.Itoa(submailErrorResult.Code), submailErrorResult.Msg, submailErrorCodes)
+		}
+	}
+
+	errs := []error{}
+	for _, submailResult := range submailSuccessResult {
+		sendResult.add(&RecipientResult{
+			PhoneNumber: submailResult.To,
+			MessageId:   submailResult.SendId,
+			Accepted:    submailResult.Status == "success",
+			Code:        strconv.Itoa(submailResult.Code),
+			Message:     submailResult.Msg,
+			Fee:         submailResult.Fee,
+		})
+		if submailResult.Status != "success" {
+			smsErr := newSmsError(SMS_SUBMAIL, strconv.Itoa(submailResult.Code), submailResult.Msg, submailErrorCodes)
+			errs = append(errs, smsErr.forNumber(submailResult.To))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// QueryBalance 查询账户余额
+// 通过/balance/sms接口查询，余额为通用短信余额与事务类短信余额之和
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *Balance: 账户余额，单位为短信条数
+//   - error: 错误信息
+func (c *SubmailClient) QueryBalance(ctx context.Context) (*Balance, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, val := range map[string]string{"appid": c.appid, "signature": c.signature} {
+		if err := writer.WriteField(key, val); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", joinEndpoint(c.endpoint, "/balance/sms"), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, newHttpStatusError(SMS_SUBMAIL, resp.StatusCode, string(respBody))
+	}
+
+	var balanceResponse SubmailBalanceResponse
+	if err = json.Unmarshal(respBody, &balanceResponse); err != nil {
+		return nil, err
+	}
+	if balanceResponse.Status != "success" {
+		return nil, newSmsError(SMS_SUBMAIL, strconv.Itoa(balanceResponse.Code), balanceResponse.Msg, submailErrorCodes)
+	}
+
+	balance := &Balance{
+		Provider: SMS_SUBMAIL,
+		Unit:     BALANCE_UNIT_MESSAGES,
+		Raw:      string(respBody),
+	}
+	for _, amount := range []json.Number{balanceResponse.Balance, balanceResponse.TransactionalBalance} {
+		if amount == "" {
+			continue
+		}
+		value, err := amount.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("bad response: %q", string(respBody))
+		}
+		balance.Amount += value
+	}
+	return balance, nil
+}
+
+// parseSubmailReport 解析SUBMAIL状态推送（SUBHOOK）请求
+// 回调地址需在SUBMAIL控制台配置，非短信状态的推送事件会被忽略
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseSubmailReport(r *http.Request) ([]*DeliveryReport, error) {
+	form, err := readForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	event := form.Get("events")
+	if event == "" {
+		return nil, fmt.Errorf("bad callback: missing events")
+	}
+
+	report := &DeliveryReport{
+		MessageId:   form.Get("send_id"),
+		PhoneNumber: form.Get("address"),
+		Code:        form.Get("report"),
+		Message:     event,
+		Raw:         form.Encode(),
+	}
+	switch event {
+	case "request":
+		report.Status = DELIVERY_QUEUED
+	case "sending":
+		report.Status = DELIVERY_SENT
+	case "delivered":
+		report.Status = DELIVERY_DELIVERED
+	case "dropped":
+		report.Status = DELIVERY_FAILED
+	default:
+		return nil, nil
+	}
+	if timestamp, err := strconv.ParseInt(form.Get("timestamp"), 10, 64); err == nil && timestamp > 0 {
+		report.DeliveredAt = time.Unix(timestamp, 0)
+	}
+
+	return []*DeliveryReport{report}, nil
+}
+
+// Verify 校验SUBMAIL状态推送签名
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体
+// 返回:
+//   - error: 签名不匹配时返回ErrInvalidSignature
+func (v SubmailVerifier) Verify(r *http.Request, body []byte) error {
+	form, err := bodyForm(r, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	token := form.Get("token")
+	if token == "" || v.AppKey == "" {
+		return ErrInvalidSignature
+	}
+
+	sum := md5.Sum([]byte(token + v.AppKey))
+	if !secureEqual(hex.EncodeToString(sum[:]), form.Get("signature")) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package sms 短信模板管理
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TemplateType 标准化的短信模板类型
+type TemplateType string
+
+// 短信模板类型常量定义
+const (
+	TEMPLATE_VERIFICATION TemplateType = "verification" // 验证码
+	TEMPLATE_NOTIFICATION TemplateType = "notification" // 短信通知
+	TEMPLATE_PROMOTION    TemplateType = "promotion"    // 推广短信
+)
+
+// ReviewStatus 标准化的短信模板和签名审核状态
+type ReviewStatus string
+
+// 审核状态常量定义
+const (
+	REVIEW_PENDING  ReviewStatus = "pending"  // 审核中或审核通过待生效
+	REVIEW_APPROVED ReviewStatus = "approved" // 审核通过，可以使用
+	REVIEW_REJECTED ReviewStatus = "rejected" // 审核未通过
+	REVIEW_CANCELED ReviewStatus = "canceled" // 已撤回审核
+	REVIEW_UNKNOWN  ReviewStatus = "unknown"  // 未知状态
+)
+
+// TemplateRequest 创建短信模板的请求
+type TemplateRequest struct {
+	Name          string       // 模板名称
+	Content       string       // 模板内容，变量格式遵循服务商要求（如阿里云为${code}，腾讯云为{1}）
+	Type          TemplateType // 模板类型
+	International bool         // 是否为国际/港澳台短信模板
+	Remark        string       // 申请说明，如使用场景，阿里云必填
+}
+
+// Template 短信模板
+type Template struct {
+	Provider      string       // 服务提供商类型
+	TemplateId    string       // 模板ID（阿里云为模板CODE）
+	Name          string       // 模板名称
+	Content       string       // 模板内容
+	Type          TemplateType // 模板类型（服务商未返回时为空）
+	International bool         // 是否为国际/港澳台短信模板
+	Status        ReviewStatus // 审核状态
+	Reason        string       // 审核意见，通常为审核未通过的原因
+	CreatedAt     time.Time    // 提交时间（服务商未返回时为零值）
+	Raw           string       // 服务商原始响应
+}
+
+// TemplateManager 支持管理短信模板的短信服务提供商
+// 目前支持阿里云、腾讯云和华为云
+type TemplateManager interface {
+	// CreateTemplate 创建短信模板并提交审核
+	// 参数:
+	//   - ctx: 上下文
+	//   - request: 创建短信模板的请求
+	// 返回:
+	//   - *Template: 短信模板，审核状态为REVIEW_PENDING
+	//   - error: 错误信息
+	CreateTemplate(ctx context.Context, request TemplateRequest) (*Template, error)
+
+	// ListTemplates 查询全部短信模板
+	// 参数:
+	//   - ctx: 上下文
+	// 返回:
+	//   - []*Template: 短信模板列表
+	//   - error: 错误信息
+	ListTemplates(ctx context.Context) ([]*Template, error)
+
+	// GetTemplate 查询短信模板及其审核状态
+	// 参数:
+	//   - ctx: 上下文
+	//   - templateId: 模板ID
+	// 返回:
+	//   - *Template: 短信模板
+	//   - error: 错误信息
+	GetTemplate(ctx context.Context, templateId string) (*Template, error)
+
+	// DeleteTemplate 删除短信模板
+	// 参数:
+	//   - ctx: 上下文
+	//   - templateId: 模板ID
+	// 返回:
+	//   - error: 错误信息
+	DeleteTemplate(ctx context.Context, templateId string) error
+}
+
+// GetTemplateManager 获取短信服务提供商的模板管理接口
+// 参数:
+//   - provider: 短信服务提供商实例
+// 返回:
+//   - TemplateManager: 模板管理接口
+//   - error: 错误信息，服务商不支持管理短信模板时返回错误
+func GetTemplateManager(provider SmsProvider) (TemplateManager, error) {
+	manager, ok := provider.(TemplateManager)
+	if !ok {
+		return nil, fmt.Errorf("provider does not support template management: %T", provider)
+	}
+	return manager, nil
+}
@@ -1,88 +1,760 @@
-// Package sms 腾讯云短信服务实现
-package sms
-
-import (
-	"fmt"
-	"strconv"
-
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
-	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
-)
-
-// TencentClient 腾讯云短信客户端
-// 封装腾讯云短信API调用
-type TencentClient struct {
-	core     *sms.Client // 腾讯云SDK客户端
-	appId    string      // 应用ID
-	sign     string      // 短信签名
-	template string      // 短信模板ID
-}
-
-// GetTencentClient 创建腾讯云短信客户端
-// 参数:
-//   - accessId: 腾讯云访问ID
-//   - accessKey: 腾讯云访问密钥
-//   - sign: 短信签名
-//   - templateId: 短信模板ID
-//   - appId: 应用ID列表
-// 返回:
-//   - *TencentClient: 腾讯云短信客户端实例
-//   - error: 错误信息
-func GetTencentClient(accessId string, accessKey string, sign string, templateId string, appId []string) (*TencentClient, error) {
-	if len(appId) == 0 {
-		return nil, fmt.Errorf("missing parameter: appId")
-	}
-
-	credential := common.NewCredential(accessId, accessKey)
-	config := profile.NewClientProfile()
-	config.HttpProfile.ReqMethod = "POST"
-
-	region := "ap-guangzhou"
-	client, err := sms.NewClient(credential, region, config)
-	if err != nil {
-		return nil, err
-	}
-
-	tencentClient := &TencentClient{
-		core:     client,
-		appId:    appId[0],
-		sign:     sign,
-		template: templateId,
-	}
-
-	return tencentClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（按索引顺序："0", "1", "2"...）
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *TencentClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	var paramArray []string
-	index := 0
-	for {
-		value := param[strconv.Itoa(index)]
-		if len(value) == 0 {
-			break
-		}
-		paramArray = append(paramArray, value)
-		index++
-	}
-
-	request := sms.NewSendSmsRequest()
-	request.SmsSdkAppId = common.StringPtr(c.appId)
-	request.SignName = common.StringPtr(c.sign)
-	request.TemplateParamSet = common.StringPtrs(paramArray)
-	request.TemplateId = common.StringPtr(c.template)
-	request.PhoneNumberSet = common.StringPtrs(targetPhoneNumber)
-
-	_, err := c.core.SendSms(request)
-	return err
-}
+// Package sms 腾讯云短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+)
+
+// tencentNumberFormat 腾讯云要求的号码格式（E.164，中国大陆号码也需要带+86）
+var tencentNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_E164, international: phone.FORMAT_E164}
+
+// tencentErrorCodes 腾讯云错误码与标准错误的对应关系
+// 参考文档: https://cloud.tencent.com/document/api/382/55981
+var tencentErrorCodes = map[string]error{
+	"FailedOperation.ContainSensitiveWord":                      ErrContentBlocked,
+	"FailedOperation.InsufficientBalanceInSmsPackage":           ErrInsufficientBalance,
+	"FailedOperation.ServiceSuspendDueToArrears":                ErrInsufficientBalance,
+	"FailedOperation.PhoneNumberInBlacklist":                    ErrInvalidNumber,
+	"FailedOperation.SignatureIncorrectOrUnapproved":            ErrSignRejected,
+	"FailedOperation.TemplateIncorrectOrUnapproved":             ErrTemplateRejected,
+	"FailedOperation.TemplateParamSetNotMatchApprovedTemplate":  ErrTemplateRejected,
+	"FailedOperation.JsonParseFail":                             ErrInvalidParameter,
+	"InvalidParameterValue.IncorrectPhoneNumber":                ErrInvalidNumber,
+	"InvalidParameterValue.ProhibitedUseUrlInTemplateParameter": ErrContentBlocked,
+	"LimitExceeded.PhoneNumberCountLimit":                       ErrInvalidParameter,
+	"UnsupportedOperation.UnsupportedRegion":                    ErrInvalidNumber,
+	"RequestLimitExceeded":                                      ErrRateLimited,
+	"ClientError.NetworkError":                                  ErrServiceUnavailable,
+}
+
+// tencentErrorPrefixes 腾讯云错误码前缀与标准错误的对应关系
+var tencentErrorPrefixes = map[string]error{
+	"AuthFailure.":           ErrAuthFailed,
+	"UnauthorizedOperation.": ErrAuthFailed,
+	"LimitExceeded.":         ErrRateLimited,
+	"InternalError":          ErrServiceUnavailable,
+	"InvalidParameter":       ErrInvalidParameter,
+}
+
+// 腾讯云短信模板类型
+const (
+	TENCENT_TEMPLATE_PROMOTION    = 1 // 营销短信
+	TENCENT_TEMPLATE_NOTIFICATION = 2 // 通知短信
+	TENCENT_TEMPLATE_VERIFICATION = 3 // 验证码短信
+)
+
+// 腾讯云短信模板和签名审核状态
+const (
+	TENCENT_REVIEW_APPROVED = 0  // 审核通过且已生效
+	TENCENT_REVIEW_PENDING  = 1  // 审核中
+	TENCENT_REVIEW_INACTIVE = 2  // 审核通过待生效
+	TENCENT_REVIEW_REJECTED = -1 // 审核未通过或审核失败
+)
+
+// tencentSignTypes 标准签名来源与腾讯云签名类型的对应关系
+var tencentSignTypes = map[SignSource]uint64{
+	SIGN_COMPANY:   0, // 公司
+	SIGN_APP:       1, // APP
+	SIGN_WEBSITE:   2, // 网站
+	SIGN_WECHAT:    3, // 公众号
+	SIGN_TRADEMARK: 4, // 商标
+}
+
+// 腾讯云签名证明类型
+const (
+	TENCENT_DOCUMENT_LICENSE   = 1 // 企业营业执照
+	TENCENT_DOCUMENT_TRADEMARK = 7 // 商标注册书
+)
+
+// TencentClient 腾讯云短信客户端
+// 封装腾讯云短信API调用
+type TencentClient struct {
+	core     *sms.Client // 腾讯云SDK客户端
+	appId    string      // 应用ID
+	sign     string      // 短信签名
+	template string      // 短信模板ID
+	endpoint string      // 服务端点（为空时使用SDK默认端点）
+}
+
+// init 注册腾讯云短信服务
+func init() {
+	Register(SMS_TENCENT, func(config ProviderConfig) (SmsProvider, error) {
+		return GetTencentClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.AppId))
+	}, "AccessId", "AccessKey", "Sign", "Template", "AppId")
+}
+
+// GetTencentClient 创建腾讯云短信客户端
+// 参数:
+//   - accessId: 腾讯云访问ID
+//   - accessKey: 腾讯云访问密钥
+//   - sign: 短信签名
+//   - templateId: 短信模板ID
+//   - appId: 应用ID列表
+//
+// 返回:
+//   - *TencentClient: 腾讯云短信客户端实例
+//   - error: 错误信息
+func GetTencentClient(accessId string, accessKey string, sign string, templateId string, appId []string) (*TencentClient, error) {
+	if len(appId) == 0 {
+		return nil, fmt.Errorf("missing parameter: appId")
+	}
+
+	credential := common.NewCredential(accessId, accessKey)
+	config := profile.NewClientProfile()
+	config.HttpProfile.ReqMethod = "POST"
+
+	region := "ap-guangzhou"
+	client, err := sms.NewClient(credential, region, config)
+	if err != nil {
+		return nil, err
+	}
+
+	tencentClient := &TencentClient{
+		core:     client,
+		appId:    appId[0],
+		sign:     sign,
+		template: templateId,
+	}
+
+	return tencentClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 腾讯云SDK仅使用HTTP客户端的Transport，超时由客户端配置的ReqTimeout控制
+// 参数:
+//   - client: HTTP客户端
+func (c *TencentClient) SetHTTPClient(client *http.Client) {
+	c.core.WithHttpTransport(client.Transport)
+}
+
+// SetEndpoint 设置服务端点
+// 腾讯云SDK仅使用服务端点的协议和主机
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *TencentClient) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（按索引顺序："0", "1", "2"...）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - error: 错误信息
+func (c *TencentClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（按索引顺序："0", "1", "2"...）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *TencentClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := tencentNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var paramArray []string
+	index := 0
+	for {
+		value := param[strconv.Itoa(index)]
+		if len(value) == 0 {
+			break
+		}
+		paramArray = append(paramArray, value)
+		index++
+	}
+
+	request := sms.NewSendSmsRequest()
+	c.prepareRequest(request)
+	request.SmsSdkAppId = common.StringPtr(c.appId)
+	request.SignName = common.StringPtr(c.sign)
+	request.TemplateParamSet = common.StringPtrs(paramArray)
+	request.TemplateId = common.StringPtr(c.template)
+	request.PhoneNumberSet = common.StringPtrs(phoneNumbers)
+
+	response, err := c.core.SendSmsWithContext(ctx, request)
+	if err != nil {
+		return nil, tencentSdkError(err)
+	}
+
+	result := newSendResult(SMS_TENCENT)
+	result.Raw = response.ToJsonString()
+	if response.Response == nil {
+		return result, nil
+	}
+
+	result.RequestId = stringValue(response.Response.RequestId)
+
+	errs := []error{}
+	for _, status := range response.Response.SendStatusSet {
+		recipient := result.add(&RecipientResult{
+			PhoneNumber: stringValue(status.PhoneNumber),
+			MessageId:   stringValue(status.SerialNo),
+			Accepted:    stringValue(status.Code) == "Ok",
+			Code:        stringValue(status.Code),
+			Message:     stringValue(status.Message),
+		})
+		if status.Fee != nil {
+			recipient.Fee = int(*status.Fee)
+		}
+		if !recipient.Accepted {
+			errs = append(errs, tencentError(recipient.Code, recipient.Message).forNumber(recipient.PhoneNumber))
+		}
+	}
+
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	return result, nil
+}
+
+// QueryStatus 查询短信送达状态
+// 通过PullSmsSendStatusByPhoneNumber接口拉取号码在发送时间之后的状态报告，并按消息ID匹配，
+// 查询条件必须包含PhoneNumber；腾讯云只保留最近7天的状态报告
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询条件列表
+//
+// 返回:
+//   - []*DeliveryReport: 与查询条件一一对应的状态报告
+//   - error: 错误信息
+func (c *TencentClient) QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	const limit = 100
+
+	reports := make([]*DeliveryReport, 0, len(queries))
+	for _, query := range queries {
+		if query.PhoneNumber == "" {
+			return reports, fmt.Errorf("missing parameter: phoneNumber")
+		}
+		phoneNumber, err := tencentNumberFormat.format(query.PhoneNumber)
+		if err != nil {
+			return reports, err
+		}
+
+		now := time.Now()
+		begin := now.Add(-24 * time.Hour)
+		if !query.SentAt.IsZero() {
+			begin = query.SentAt.Add(-time.Minute)
+		}
+
+		report := newDeliveryReport(SMS_TENCENT, query)
+		for offset := uint64(0); ; offset += limit {
+			request := sms.NewPullSmsSendStatusByPhoneNumberRequest()
+			c.prepareRequest(request)
+			request.SmsSdkAppId = common.StringPtr(c.appId)
+			request.PhoneNumber = common.StringPtr(phoneNumber)
+			request.BeginTime = common.Uint64Ptr(uint64(begin.Unix()))
+			request.EndTime = common.Uint64Ptr(uint64(now.Unix()))
+			request.Offset = common.Uint64Ptr(offset)
+			request.Limit = common.Uint64Ptr(limit)
+
+			response, err := c.core.PullSmsSendStatusByPhoneNumberWithContext(ctx, request)
+			if err != nil {
+				return reports, tencentSdkError(err)
+			}
+			if response.Response == nil {
+				break
+			}
+
+			found := false
+			for _, status := range response.Response.PullSmsSendStatusSet {
+				if stringValue(status.SerialNo) != query.MessageId {
+					continue
+				}
+
+				report.Code = stringValue(status.ReportStatus)
+				report.Message = stringValue(status.Description)
+				if raw, err := json.Marshal(status); err == nil {
+					report.Raw = string(raw)
+				}
+				if status.UserReceiveTime != nil {
+					report.DeliveredAt = time.Unix(int64(*status.UserReceiveTime), 0)
+				}
+				switch report.Code {
+				case "SUCCESS":
+					report.Status = DELIVERY_DELIVERED
+				case "FAIL":
+					report.Status = DELIVERY_FAILED
+				}
+				found = true
+				break
+			}
+			if found || len(response.Response.PullSmsSendStatusSet) < limit {
+				break
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// PullReplies 拉取上行短信
+// 通过PullSmsReplyStatus接口拉取，每次最多100条，直到没有新的上行短信；该接口需联系腾讯云开通
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*InboundMessage: 上行短信列表
+//   - error: 错误信息
+func (c *TencentClient) PullReplies(ctx context.Context) ([]*InboundMessage, error) {
+	const limit = 100
+
+	messages := make([]*InboundMessage, 0)
+	for {
+		request := sms.NewPullSmsReplyStatusRequest()
+		c.prepareRequest(request)
+		request.SmsSdkAppId = common.StringPtr(c.appId)
+		request.Limit = common.Uint64Ptr(limit)
+
+		response, err := c.core.PullSmsReplyStatusWithContext(ctx, request)
+		if err != nil {
+			return messages, tencentSdkError(err)
+		}
+		if response.Response == nil {
+			return messages, nil
+		}
+
+		for _, reply := range response.Response.PullSmsReplyStatusSet {
+			message := &InboundMessage{
+				Provider: SMS_TENCENT,
+				From:     stringValue(reply.PhoneNumber),
+				To:       stringValue(reply.ExtendCode),
+				Text:     stringValue(reply.ReplyContent),
+			}
+			if reply.ReplyTime != nil {
+				message.ReceivedAt = time.Unix(int64(*reply.ReplyTime), 0)
+			}
+			if raw, err := json.Marshal(reply); err == nil {
+				message.Raw = string(raw)
+			}
+			messages = append(messages, message)
+		}
+
+		if len(response.Response.PullSmsReplyStatusSet) < limit {
+			return messages, nil
+		}
+	}
+}
+
+// CreateTemplate 创建短信模板并提交审核
+// 通过AddSmsTemplate接口创建
+// 参数:
+//   - ctx: 上下文
+//   - template: 创建短信模板的请求
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息
+func (c *TencentClient) CreateTemplate(ctx context.Context, template TemplateRequest) (*Template, error) {
+	smsType := uint64(TENCENT_TEMPLATE_NOTIFICATION)
+	switch template.Type {
+	case TEMPLATE_VERIFICATION:
+		smsType = TENCENT_TEMPLATE_VERIFICATION
+	case TEMPLATE_PROMOTION:
+		smsType = TENCENT_TEMPLATE_PROMOTION
+	}
+	international := uint64(0)
+	if template.International {
+		international = 1
+	}
+
+	request := sms.NewAddSmsTemplateRequest()
+	c.prepareRequest(request)
+	request.TemplateName = common.StringPtr(template.Name)
+	request.TemplateContent = common.StringPtr(template.Content)
+	request.SmsType = common.Uint64Ptr(smsType)
+	request.International = common.Uint64Ptr(international)
+	request.Remark = common.StringPtr(template.Remark)
+
+	response, err := c.core.AddSmsTemplateWithContext(ctx, request)
+	if err != nil {
+		return nil, tencentSdkError(err)
+	}
+
+	created := &Template{
+		Provider:      SMS_TENCENT,
+		Name:          template.Name,
+		Content:       template.Content,
+		Type:          template.Type,
+		International: template.International,
+		Status:        REVIEW_PENDING,
+		Raw:           response.ToJsonString(),
+	}
+	if response.Response != nil && response.Response.AddTemplateStatus != nil {
+		created.TemplateId = stringValue(response.Response.AddTemplateStatus.TemplateId)
+	}
+	return created, nil
+}
+
+// ListTemplates 查询全部短信模板
+// 通过DescribeSmsTemplateList接口分页查询国内和国际/港澳台短信模板
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*Template: 短信模板列表
+//   - error: 错误信息
+func (c *TencentClient) ListTemplates(ctx context.Context) ([]*Template, error) {
+	const limit = 100
+
+	templates := make([]*Template, 0)
+	seen := make(map[string]bool)
+	for _, international := range []uint64{0, 1} {
+		for offset := uint64(0); ; offset += limit {
+			request := sms.NewDescribeSmsTemplateListRequest()
+			c.prepareRequest(request)
+			request.International = common.Uint64Ptr(international)
+			request.Offset = common.Uint64Ptr(offset)
+			request.Limit = common.Uint64Ptr(limit)
+
+			response, err := c.core.DescribeSmsTemplateListWithContext(ctx, request)
+			if err != nil {
+				return templates, tencentSdkError(err)
+			}
+			if response.Response == nil {
+				break
+			}
+
+			// 同时支持国内和国际/港澳台短信的模板会在两次查询中重复出现
+			for _, status := range response.Response.DescribeTemplateStatusSet {
+				template := tencentTemplate(status)
+				if seen[template.TemplateId] {
+					continue
+				}
+				seen[template.TemplateId] = true
+				templates = append(templates, template)
+			}
+			if len(response.Response.DescribeTemplateStatusSet) < limit {
+				break
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// GetTemplate 查询短信模板及其审核状态
+// 通过DescribeSmsTemplateList接口按模板ID查询，依次查询国内和国际/港澳台短信模板
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板ID
+//
+// 返回:
+//   - *Template: 短信模板
+//   - error: 错误信息，模板不存在时返回错误
+func (c *TencentClient) GetTemplate(ctx context.Context, templateId string) (*Template, error) {
+	id, err := strconv.ParseUint(templateId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad parameter: templateId %q", templateId)
+	}
+
+	for _, international := range []uint64{0, 1} {
+		request := sms.NewDescribeSmsTemplateListRequest()
+		c.prepareRequest(request)
+		request.International = common.Uint64Ptr(international)
+		request.TemplateIdSet = common.Uint64Ptrs([]uint64{id})
+
+		response, err := c.core.DescribeSmsTemplateListWithContext(ctx, request)
+		if err != nil {
+			return nil, tencentSdkError(err)
+		}
+		if response.Response != nil && len(response.Response.DescribeTemplateStatusSet) > 0 {
+			return tencentTemplate(response.Response.DescribeTemplateStatusSet[0]), nil
+		}
+	}
+
+	return nil, fmt.Errorf("template not found: %s", templateId)
+}
+
+// DeleteTemplate 删除短信模板
+// 参数:
+//   - ctx: 上下文
+//   - templateId: 模板ID
+//
+// 返回:
+//   - error: 错误信息
+func (c *TencentClient) DeleteTemplate(ctx context.Context, templateId string) error {
+	id, err := strconv.ParseUint(templateId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad parameter: templateId %q", templateId)
+	}
+
+	request := sms.NewDeleteSmsTemplateRequest()
+	c.prepareRequest(request)
+	request.TemplateId = common.Uint64Ptr(id)
+
+	if _, err = c.core.DeleteSmsTemplateWithContext(ctx, request); err != nil {
+		return tencentSdkError(err)
+	}
+	return nil
+}
+
+// CreateSign 创建短信签名并提交审核
+// 通过AddSmsSign接口创建，签名用途为自用；证明类型为企业营业执照，商标签名为商标注册书
+// 参数:
+//   - ctx: 上下文
+//   - sign: 创建短信签名的请求
+//
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息
+func (c *TencentClient) CreateSign(ctx context.Context, sign SignRequest) (*Sign, error) {
+	signType, ok := tencentSignTypes[sign.Source]
+	if !ok {
+		return nil, fmt.Errorf("bad parameter: unsupported sign source %q", sign.Source)
+	}
+	documentType := uint64(TENCENT_DOCUMENT_LICENSE)
+	if sign.Source == SIGN_TRADEMARK {
+		documentType = TENCENT_DOCUMENT_TRADEMARK
+	}
+	international := uint64(0)
+	if sign.International {
+		international = 1
+	}
+
+	request := sms.NewAddSmsSignRequest()
+	c.prepareRequest(request)
+	request.SignName = common.StringPtr(sign.Name)
+	request.SignType = common.Uint64Ptr(signType)
+	request.DocumentType = common.Uint64Ptr(documentType)
+	request.International = common.Uint64Ptr(international)
+	request.SignPurpose = common.Uint64Ptr(0)
+	request.ProofImage = common.StringPtr(base64.StdEncoding.EncodeToString(sign.Proof))
+	request.Remark = common.StringPtr(sign.Remark)
+
+	response, err := c.core.AddSmsSignWithContext(ctx, request)
+	if err != nil {
+		return nil, tencentSdkError(err)
+	}
+
+	created := &Sign{
+		Provider:      SMS_TENCENT,
+		Name:          sign.Name,
+		International: sign.International,
+		Status:        REVIEW_PENDING,
+		Raw:           response.ToJsonString(),
+	}
+	if response.Response != nil && response.Response.AddSignStatus != nil && response.Response.AddSignStatus.SignId != nil {
+		created.SignId = strconv.FormatUint(*response.Response.AddSignStatus.SignId, 10)
+	}
+	return created, nil
+}
+
+// ListSigns 查询全部短信签名
+// 腾讯云的DescribeSmsSignList接口只能按签名ID查询，不支持查询全部签名
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []*Sign: 始终为空
+//   - error: errors.ErrUnsupported
+func (c *TencentClient) ListSigns(ctx context.Context) ([]*Sign, error) {
+	return nil, fmt.Errorf("%w: tencent cloud can only query signs by id", errors.ErrUnsupported)
+}
+
+// GetSign 查询短信签名及其审核状态
+// 通过DescribeSmsSignList接口按签名ID查询，依次查询国内和国际/港澳台短信签名
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名ID
+//
+// 返回:
+//   - *Sign: 短信签名
+//   - error: 错误信息，签名不存在时返回错误
+func (c *TencentClient) GetSign(ctx context.Context, signId string) (*Sign, error) {
+	id, err := strconv.ParseUint(signId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad parameter: signId %q", signId)
+	}
+
+	for _, international := range []uint64{0, 1} {
+		request := sms.NewDescribeSmsSignListRequest()
+		c.prepareRequest(request)
+		request.International = common.Uint64Ptr(international)
+		request.SignIdSet = common.Uint64Ptrs([]uint64{id})
+
+		response, err := c.core.DescribeSmsSignListWithContext(ctx, request)
+		if err != nil {
+			return nil, tencentSdkError(err)
+		}
+		if response.Response == nil || len(response.Response.DescribeSignListStatusSet) == 0 {
+			continue
+		}
+
+		status := response.Response.DescribeSignListStatusSet[0]
+		sign := &Sign{
+			Provider: SMS_TENCENT,
+			SignId:   signId,
+			Name:     stringValue(status.SignName),
+			Status:   tencentReviewStatus(status.StatusCode),
+			Reason:   stringValue(status.ReviewReply),
+		}
+		if status.International != nil {
+			sign.International = *status.International != 0
+		}
+		if status.CreateTime != nil {
+			sign.CreatedAt = time.Unix(int64(*status.CreateTime), 0)
+		}
+		if raw, err := json.Marshal(status); err == nil {
+			sign.Raw = string(raw)
+		}
+		return sign, nil
+	}
+
+	return nil, fmt.Errorf("sign not found: %s", signId)
+}
+
+// DeleteSign 删除短信签名
+// 参数:
+//   - ctx: 上下文
+//   - signId: 签名ID
+//
+// 返回:
+//   - error: 错误信息
+func (c *TencentClient) DeleteSign(ctx context.Context, signId string) error {
+	id, err := strconv.ParseUint(signId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad parameter: signId %q", signId)
+	}
+
+	request := sms.NewDeleteSmsSignRequest()
+	c.prepareRequest(request)
+	request.SignId = common.Uint64Ptr(id)
+
+	if _, err = c.core.DeleteSmsSignWithContext(ctx, request); err != nil {
+		return tencentSdkError(err)
+	}
+	return nil
+}
+
+// prepareRequest 设置腾讯云API请求的服务端点
+// 参数:
+//   - request: 腾讯云API请求
+func (c *TencentClient) prepareRequest(request tchttp.Request) {
+	if c.endpoint != "" {
+		scheme, domain := splitEndpoint(c.endpoint)
+		request.SetScheme(scheme)
+		request.SetDomain(domain)
+	}
+}
+
+// tencentTemplate 将腾讯云模板信息转换为短信模板
+// 参数:
+//   - status: 腾讯云模板信息
+//
+// 返回:
+//   - *Template: 短信模板
+func tencentTemplate(status *sms.DescribeTemplateListStatus) *Template {
+	template := &Template{
+		Provider: SMS_TENCENT,
+		Name:     stringValue(status.TemplateName),
+		Content:  stringValue(status.TemplateContent),
+		Status:   tencentReviewStatus(status.StatusCode),
+		Reason:   stringValue(status.ReviewReply),
+	}
+	if status.TemplateId != nil {
+		template.TemplateId = strconv.FormatUint(*status.TemplateId, 10)
+	}
+	if status.International != nil {
+		template.International = *status.International != 0
+	}
+	if status.CreateTime != nil {
+		template.CreatedAt = time.Unix(int64(*status.CreateTime), 0)
+	}
+	if raw, err := json.Marshal(status); err == nil {
+		template.Raw = string(raw)
+	}
+	return template
+}
+
+// tencentReviewStatus 将腾讯云模板和签名审核状态转换为标准审核状态
+// 参数:
+//   - statusCode: 腾讯云审核状态
+//
+// 返回:
+//   - ReviewStatus: 标准审核状态
+func tencentReviewStatus(statusCode *int64) ReviewStatus {
+	if statusCode == nil {
+		return REVIEW_UNKNOWN
+	}
+
+	switch *statusCode {
+	case TENCENT_REVIEW_APPROVED:
+		return REVIEW_APPROVED
+	case TENCENT_REVIEW_PENDING, TENCENT_REVIEW_INACTIVE:
+		return REVIEW_PENDING
+	case TENCENT_REVIEW_REJECTED:
+		return REVIEW_REJECTED
+	default:
+		return REVIEW_UNKNOWN
+	}
+}
+
+// tencentError 将腾讯云错误码转换为短信服务商错误
+// 参数:
+//   - code: 腾讯云错误码
+//   - message: 腾讯云错误信息
+//
+// 返回:
+//   - *SmsError: 短信服务商错误
+func tencentError(code string, message string) *SmsError {
+	smsErr := newSmsError(SMS_TENCENT, code, message, tencentErrorCodes)
+	if smsErr.Err != nil {
+		return smsErr
+	}
+
+	for prefix, err := range tencentErrorPrefixes {
+		if strings.HasPrefix(code, prefix) {
+			smsErr.Err = err
+			break
+		}
+	}
+
+	return smsErr
+}
+
+// tencentSdkError 将腾讯云SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+//
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func tencentSdkError(err error) error {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if errors.As(err, &sdkErr) {
+		return tencentError(sdkErr.Code, sdkErr.Message)
+	}
+	return err
+}
@@ -1,72 +1,568 @@
-// Package sms Twilio短信服务实现
-package sms
-
-import (
-	"fmt"
-
-	"github.com/twilio/twilio-go"
-	openapi "github.com/twilio/twilio-go/rest/api/v2010"
-)
-
-// TwilioClient Twilio短信客户端
-// 封装Twilio短信API调用
-type TwilioClient struct {
-	template string              // 短信模板
-	core     *twilio.RestClient  // Twilio REST客户端
-}
-
-// GetTwilioClient 创建Twilio短信客户端
-// 参数:
-//   - accessId: Twilio账户SID
-//   - accessKey: Twilio认证令牌
-//   - template: 短信模板
-// 返回:
-//   - *TwilioClient: Twilio短信客户端实例
-//   - error: 错误信息
-func GetTwilioClient(accessId string, accessKey string, template string) (*TwilioClient, error) {
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: accessId,
-		Password: accessKey,
-	})
-
-	twilioClient := &TwilioClient{
-		core:     client,
-		template: template,
-	}
-
-	return twilioClient, nil
-}
-
-// SendMessage 发送短信
-// 注意: targetPhoneNumber[0]是发送方号码，因此targetPhoneNumber至少需要两个参数
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 手机号码列表（[0]为发送方，[1:]为接收方）
-// 返回:
-//   - error: 错误信息
-func (c *TwilioClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	bodyContent := fmt.Sprintf(c.template, code)
-
-	if len(targetPhoneNumber) < 2 {
-		return fmt.Errorf("bad parameter: targetPhoneNumber")
-	}
-
-	params := &openapi.CreateMessageParams{}
-	params.SetFrom(targetPhoneNumber[0])
-	params.SetBody(bodyContent)
-
-	for i := 1; i < len(targetPhoneNumber); i++ {
-		params.SetTo(targetPhoneNumber[i])
-		_, err := c.core.Api.CreateMessage(params)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
+// Package sms Twilio短信服务实现
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smart-unicom/sms/phone"
+	"github.com/twilio/twilio-go"
+	twclient "github.com/twilio/twilio-go/client"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// TWILIO_ENDPOINT Twilio默认服务端点
+const TWILIO_ENDPOINT = "https://api.twilio.com"
+
+// twilioNumberFormat Twilio要求的接收方号码格式（E.164）
+var twilioNumberFormat = numberFormat{domestic: phone.FORMAT_E164}
+
+// twilioErrorCodes Twilio错误码与标准错误的对应关系
+// 参考文档: https://www.twilio.com/docs/api/errors
+var twilioErrorCodes = map[string]error{
+	"20003": ErrAuthFailed,     // 鉴权失败
+	"20005": ErrAuthFailed,     // 账户未激活
+	"20429": ErrRateLimited,    // 请求过多
+	"14107": ErrRateLimited,    // 发送频率超限
+	"21211": ErrInvalidNumber,  // 接收方号码无效
+	"21214": ErrInvalidNumber,  // 接收方号码无法送达
+	"21408": ErrInvalidNumber,  // 未开通该地区的发送权限
+	"21610": ErrInvalidNumber,  // 接收方已退订
+	"21612": ErrInvalidNumber,  // 无法发送至该号码
+	"21614": ErrInvalidNumber,  // 接收方号码不是手机号码
+	"21212": ErrSignRejected,   // 发送方号码无效
+	"21606": ErrSignRejected,   // 发送方号码不支持短信
+	"21617": ErrContentBlocked, // 短信内容超出长度限制
+	"30007": ErrContentBlocked, // 运营商过滤
+}
+
+// TwilioClient Twilio短信客户端
+// 封装Twilio短信API调用
+type TwilioClient struct {
+	template       string             // 短信模板
+	sender         string             // 发送方号码
+	core           *twilio.RestClient // Twilio REST客户端
+	statusCallback string             // 状态报告回调地址
+	segmentLimit   SegmentLimit       // 拆分条数限制
+	renderMode     RenderMode         // 短信模板的渲染方式
+}
+
+// TwilioVerifier Twilio回调请求签名校验器
+// 校验X-Twilio-Signature请求头（以认证令牌为密钥的HMAC-SHA1签名）
+// 参考文档: https://www.twilio.com/docs/usage/security#validating-requests
+type TwilioVerifier struct {
+	AuthToken string // Twilio认证令牌
+	URL       string // Twilio请求的回调地址（含查询参数），为空时根据请求还原，位于反向代理之后时建议设置
+}
+
+// 确保TwilioClient实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &TwilioClient{}
+
+// 确保TwilioLegacyClient实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &TwilioLegacyClient{}
+
+// 确保TwilioVerifier实现了WebhookVerifier接口
+var _ WebhookVerifier = TwilioVerifier{}
+
+// init 注册Twilio短信服务
+// 未设置Sender时创建兼容旧调用方式的TwilioLegacyClient
+func init() {
+	Register(SMS_TWILIO, func(config ProviderConfig) (SmsProvider, error) {
+		if config.Sender == "" {
+			return GetTwilioLegacyClient(config.AccessId, config.AccessKey, config.Template)
+		}
+
+		client, err := GetTwilioClient(config.AccessId, config.AccessKey, config.Template)
+		if err != nil {
+			return nil, err
+		}
+		client.SetSender(config.Sender)
+		return client, nil
+	}, "AccessId", "AccessKey", "Template")
+}
+
+// GetTwilioClient 创建Twilio短信客户端
+// 未通过SetSender设置发送方号码时，按旧调用方式以targetPhoneNumber[0]为发送方号码
+// 参数:
+//   - accessId: Twilio账户SID
+//   - accessKey: Twilio认证令牌
+//   - template: 短信模板
+//
+// 返回:
+//   - *TwilioClient: Twilio短信客户端实例
+//   - error: 错误信息
+func GetTwilioClient(accessId string, accessKey string, template string) (*TwilioClient, error) {
+	return newTwilioClient(accessId, accessKey, template, ""), nil
+}
+
+// GetTwilioLegacyClient 创建兼容旧调用方式的Twilio短信客户端
+// 参数:
+//   - accessId: Twilio账户SID
+//   - accessKey: Twilio认证令牌
+//   - template: 短信模板
+//
+// 返回:
+//   - *TwilioLegacyClient: 以targetPhoneNumber[0]为发送方号码的Twilio短信客户端实例
+//   - error: 错误信息
+func GetTwilioLegacyClient(accessId string, accessKey string, template string) (*TwilioLegacyClient, error) {
+	return &TwilioLegacyClient{TwilioClient: newTwilioClient(accessId, accessKey, template, "")}, nil
+}
+
+// newTwilioClient 创建Twilio短信客户端
+// 参数:
+//   - accessId: Twilio账户SID
+//   - accessKey: Twilio认证令牌
+//   - template: 短信模板
+//   - sender: 发送方号码
+//
+// 返回:
+//   - *TwilioClient: Twilio短信客户端实例
+func newTwilioClient(accessId string, accessKey string, template string, sender string) *TwilioClient {
+	client := twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: accessId,
+		Password: accessKey,
+	})
+
+	return &TwilioClient{
+		core:     client,
+		template: template,
+		sender:   sender,
+	}
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *TwilioClient) SetHTTPClient(client *http.Client) {
+	base := c.core.Client
+	if endpointClient, ok := base.(*twilioEndpointClient); ok {
+		base = endpointClient.BaseClient
+	}
+	if core, ok := base.(*twclient.Client); ok {
+		core.HTTPClient = client
+	}
+}
+
+// SetEndpoint 设置服务端点
+// SDK生成的请求URL的协议和主机替换为服务端点，请求路径保持不变（如"/2010-04-01/Accounts/..."），默认为TWILIO_ENDPOINT
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *TwilioClient) SetEndpoint(endpoint string) {
+	base := c.core.Client
+	if endpointClient, ok := base.(*twilioEndpointClient); ok {
+		base = endpointClient.BaseClient
+	}
+	c.core.Client = &twilioEndpointClient{BaseClient: base, endpoint: endpoint}
+}
+
+// SetSender 设置发送方号码
+// 设置后targetPhoneNumber中的号码均为接收方，可以与故障转移、路由等组合服务商一起使用
+// 参数:
+//   - sender: 发送方号码（如"+15550001111"）或字母数字发送方ID
+func (c *TwilioClient) SetSender(sender string) {
+	c.sender = sender
+}
+
+// SetStatusCallback 设置状态报告回调地址
+// 参数:
+//   - callbackUrl: 状态报告回调地址（StatusCallback），Twilio以表单格式推送消息状态变化
+func (c *TwilioClient) SetStatusCallback(callbackUrl string) {
+	c.statusCallback = callbackUrl
+}
+
+// SetSegmentLimit 设置短信拆分条数限制
+// 参数:
+//   - limit: 拆分条数限制
+func (c *TwilioClient) SetSegmentLimit(limit SegmentLimit) {
+	c.segmentLimit = limit
+}
+
+// SetMessageTemplate 设置短信模板
+// 参数:
+//   - text: 模板内容
+//   - mode: 渲染方式
+func (c *TwilioClient) SetMessageTemplate(text string, mode RenderMode) {
+	c.template = text
+	c.renderMode = mode
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - error: 错误信息
+func (c *TwilioClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 设置了发送方号码时targetPhoneNumber中的号码均为接收方；未设置时按旧调用方式以targetPhoneNumber[0]为发送方号码
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 目标手机号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *TwilioClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if c.sender == "" {
+		return (&TwilioLegacyClient{TwilioClient: c}).SendMessageContext(ctx, param, targetPhoneNumber...)
+	}
+	return c.send(ctx, c.sender, param, targetPhoneNumber)
+}
+
+// TwilioLegacyClient 兼容旧调用方式的Twilio短信客户端
+// targetPhoneNumber[0]为发送方号码，[1:]为接收方。
+// 故障转移、路由、号码校验和频率限制等组合服务商会把发送方号码当作接收方处理，因此不能与其组合使用，
+// 新代码应通过SetSender或ProviderConfig.Sender设置发送方号码
+type TwilioLegacyClient struct {
+	*TwilioClient // Twilio短信客户端
+}
+
+// SendMessage 发送短信
+// 注意: targetPhoneNumber[0]是发送方号码，因此targetPhoneNumber至少需要两个参数
+// 参数:
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 手机号码列表（[0]为发送方，[1:]为接收方）
+//
+// 返回:
+//   - error: 错误信息
+func (c *TwilioLegacyClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 注意: targetPhoneNumber[0]是发送方号码，因此targetPhoneNumber至少需要两个参数
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（填充短信模板中的占位符）
+//   - targetPhoneNumber: 手机号码列表（[0]为发送方，[1:]为接收方）
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *TwilioLegacyClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) < 2 {
+		return nil, fmt.Errorf("bad parameter: targetPhoneNumber")
+	}
+
+	return c.send(ctx, targetPhoneNumber[0], param, targetPhoneNumber[1:])
+}
+
+// send 从指定的发送方号码发送短信
+// 参数:
+//   - ctx: 上下文
+//   - sender: 发送方号码
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 接收方号码列表
+//
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *TwilioClient) send(ctx context.Context, sender string, param map[string]string, targetPhoneNumber []string) (*SendResult, error) {
+	bodyContent, err := RenderMessage(c.template, c.renderMode, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if sender == "" {
+		return nil, fmt.Errorf("missing parameter: sender")
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	if err = c.segmentLimit.check(bodyContent); err != nil {
+		return nil, err
+	}
+
+	phoneNumbers, err := twilioNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &openapi.CreateMessageParams{}
+	params.SetFrom(sender)
+	params.SetBody(bodyContent)
+	if c.statusCallback != "" {
+		params.SetStatusCallback(c.statusCallback)
+	}
+
+	result := newSendResult(SMS_TWILIO)
+	for i := range targetPhoneNumber {
+		params.SetTo(phoneNumbers[i])
+		message, err := callWithContext(ctx, func() (*openapi.ApiV2010Message, error) {
+			return c.core.Api.CreateMessage(params)
+		})
+		if err != nil {
+			return result, twilioSdkError(err, targetPhoneNumber[i])
+		}
+
+		recipient := result.add(&RecipientResult{
+			PhoneNumber: targetPhoneNumber[i],
+			MessageId:   stringValue(message.Sid),
+			Accepted:    true,
+			Code:        stringValue(message.Status),
+		})
+		if segments, err := strconv.Atoi(stringValue(message.NumSegments)); err == nil {
+			recipient.Segments = segments
+			recipient.Fee = segments
+		}
+		if raw, err := json.Marshal(message); err == nil {
+			recipient.Raw = string(raw)
+		}
+	}
+
+	return result, nil
+}
+
+// QueryStatus 查询短信送达状态
+// 通过Message Fetch接口按消息SID查询
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询条件列表
+//
+// 返回:
+//   - []*DeliveryReport: 与查询条件一一对应的状态报告
+//   - error: 错误信息
+func (c *TwilioClient) QueryStatus(ctx context.Context, queries ...StatusQuery) ([]*DeliveryReport, error) {
+	reports := make([]*DeliveryReport, 0, len(queries))
+	for _, query := range queries {
+		if query.MessageId == "" {
+			return reports, fmt.Errorf("missing parameter: messageId")
+		}
+
+		message, err := callWithContext(ctx, func() (*openapi.ApiV2010Message, error) {
+			return c.core.Api.FetchMessage(query.MessageId, nil)
+		})
+		if err != nil {
+			return reports, twilioSdkError(err, query.PhoneNumber)
+		}
+
+		report := newDeliveryReport(SMS_TWILIO, query)
+		if to := stringValue(message.To); to != "" {
+			report.PhoneNumber = to
+		}
+		report.Code = stringValue(message.Status)
+		report.Message = stringValue(message.ErrorMessage)
+		if message.ErrorCode != nil && *message.ErrorCode != 0 {
+			report.Code = strconv.Itoa(*message.ErrorCode)
+		}
+		report.SentAt = parseTime(time.RFC1123Z, stringValue(message.DateSent), time.UTC)
+		if raw, err := json.Marshal(message); err == nil {
+			report.Raw = string(raw)
+		}
+
+		report.Status = twilioDeliveryStatus(stringValue(message.Status))
+		if report.Status == DELIVERY_DELIVERED || report.Status == DELIVERY_FAILED {
+			report.DeliveredAt = parseTime(time.RFC1123Z, stringValue(message.DateUpdated), time.UTC)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// parseTwilioReport 解析Twilio状态回调请求
+// 参数:
+//   - r: HTTP请求
+//
+// 返回:
+//   - []*DeliveryReport: 送达状态报告
+//   - error: 错误信息
+func parseTwilioReport(r *http.Request) ([]*DeliveryReport, error) {
+	form, err := readForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	messageSid := form.Get("MessageSid")
+	if messageSid == "" {
+		return nil, fmt.Errorf("bad callback: missing MessageSid")
+	}
+
+	status := form.Get("MessageStatus")
+	report := &DeliveryReport{
+		MessageId:   messageSid,
+		PhoneNumber: form.Get("To"),
+		Status:      twilioDeliveryStatus(status),
+		Code:        status,
+		Raw:         form.Encode(),
+	}
+	if errorCode := form.Get("ErrorCode"); errorCode != "" && errorCode != "0" {
+		report.Code = errorCode
+	}
+
+	return []*DeliveryReport{report}, nil
+}
+
+// Verify 校验Twilio回调请求签名
+// 表单请求的签名包含按参数名排序的全部表单参数；JSON请求的签名只包含URL，
+// 请求体通过URL中的bodySHA256参数校验
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体
+//
+// 返回:
+//   - error: 签名不匹配时返回ErrInvalidSignature
+func (v TwilioVerifier) Verify(r *http.Request, body []byte) error {
+	signature := r.Header.Get("X-Twilio-Signature")
+	if signature == "" || v.AuthToken == "" {
+		return ErrInvalidSignature
+	}
+
+	callbackUrl := v.URL
+	if callbackUrl == "" {
+		callbackUrl = requestURL(r)
+	}
+
+	data := callbackUrl
+	if bodySHA256 := r.URL.Query().Get("bodySHA256"); bodySHA256 != "" {
+		sum := sha256.Sum256(body)
+		if !secureEqual(hex.EncodeToString(sum[:]), bodySHA256) {
+			return ErrInvalidSignature
+		}
+	} else if len(body) > 0 {
+		form, err := bodyForm(r, body)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		keys := make([]string, 0, len(form))
+		for key := range form {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var builder strings.Builder
+		builder.WriteString(callbackUrl)
+		for _, key := range keys {
+			for _, value := range form[key] {
+				builder.WriteString(key)
+				builder.WriteString(value)
+			}
+		}
+		data = builder.String()
+	}
+
+	mac := hmac.New(sha1.New, []byte(v.AuthToken))
+	mac.Write([]byte(data))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !secureEqual(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// parseTwilioInbound 解析Twilio上行短信推送请求
+// 需在Twilio控制台将号码的"A message comes in"配置为回调地址
+// 参数:
+//   - r: HTTP请求
+//
+// 返回:
+//   - []*InboundMessage: 上行短信列表
+//   - error: 错误信息
+func parseTwilioInbound(r *http.Request) ([]*InboundMessage, error) {
+	form, err := readForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	messageSid := form.Get("MessageSid")
+	if messageSid == "" {
+		return nil, fmt.Errorf("bad callback: missing MessageSid")
+	}
+
+	message := &InboundMessage{
+		MessageId: messageSid,
+		From:      form.Get("From"),
+		To:        form.Get("To"),
+		Text:      form.Get("Body"),
+		Raw:       form.Encode(),
+	}
+	return []*InboundMessage{message}, nil
+}
+
+// twilioDeliveryStatus 将Twilio消息状态转换为标准化的送达状态
+// 参数:
+//   - status: Twilio消息状态
+//
+// 返回:
+//   - DeliveryStatus: 送达状态
+func twilioDeliveryStatus(status string) DeliveryStatus {
+	switch status {
+	case "accepted", "scheduled", "queued", "sending":
+		return DELIVERY_QUEUED
+	case "sent":
+		return DELIVERY_SENT
+	case "delivered", "read":
+		return DELIVERY_DELIVERED
+	case "failed", "undelivered", "canceled":
+		return DELIVERY_FAILED
+	default:
+		return DELIVERY_UNKNOWN
+	}
+}
+
+// twilioEndpointClient 将请求发往自定义服务端点的Twilio SDK客户端
+// Twilio的SDK没有提供设置服务端点的选项，因此在发送请求前替换请求URL
+type twilioEndpointClient struct {
+	twclient.BaseClient        // 实际发送请求的SDK客户端
+	endpoint            string // 服务端点基础URL
+}
+
+// SendRequest 发送请求
+// 参数:
+//   - method: 请求方法
+//   - rawURL: SDK生成的请求URL
+//   - data: 请求参数
+//   - headers: 请求头
+//   - body: 请求体
+//
+// 返回:
+//   - *http.Response: 响应
+//   - error: 错误信息
+func (c *twilioEndpointClient) SendRequest(method string, rawURL string, data url.Values, headers map[string]interface{}, body ...byte) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	target := joinEndpoint(c.endpoint, u.EscapedPath())
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return c.BaseClient.SendRequest(method, target, data, headers, body...)
+}
+
+// twilioSdkError 将Twilio SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+//   - phoneNumber: 接收方号码
+//
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func twilioSdkError(err error, phoneNumber string) error {
+	var restErr *twclient.TwilioRestError
+	if errors.As(err, &restErr) {
+		return newSmsError(SMS_TWILIO, strconv.Itoa(restErr.Code), restErr.Message, twilioErrorCodes).withStatus(restErr.Status).forNumber(phoneNumber)
+	}
+	return err
+}
@@ -1,88 +1,207 @@
-// Package sms UCloud短信服务实现
-package sms
-
-import (
-	"fmt"
-
-	"github.com/ucloud/ucloud-sdk-go/services/usms"
-	"github.com/ucloud/ucloud-sdk-go/ucloud"
-	"github.com/ucloud/ucloud-sdk-go/ucloud/auth"
-	"github.com/ucloud/ucloud-sdk-go/ucloud/config"
-)
-
-// UcloudClient UCloud短信客户端
-// 封装UCloud短信API调用
-type UcloudClient struct {
-	core       *usms.USMSClient // UCloud USMS客户端
-	ProjectId  string           // 项目ID
-	PrivateKey string           // 私钥
-	PublicKey  string           // 公钥
-	Sign       string           // 短信签名
-	Template   string           // 短信模板ID
-}
-
-// GetUcloudClient 创建UCloud短信客户端
-// 参数:
-//   - publicKey: UCloud公钥
-//   - privateKey: UCloud私钥
-//   - sign: 短信签名
-//   - template: 短信模板ID
-//   - projectId: 项目ID列表
-// 返回:
-//   - *UcloudClient: UCloud短信客户端实例
-//   - error: 错误信息
-func GetUcloudClient(publicKey string, privateKey string, sign string, template string, projectId []string) (*UcloudClient, error) {
-	if len(projectId) == 0 {
-		return nil, fmt.Errorf("missing parameter: projectId")
-	}
-
-	cfg := config.NewConfig()
-	cfg.ProjectId = projectId[0]
-	credential := auth.NewCredential()
-	credential.PublicKey = publicKey
-	credential.PrivateKey = privateKey
-
-	client := usms.NewClient(&cfg, &credential)
-
-	ucloudClient := &UcloudClient{
-		core:       client,
-		ProjectId:  projectId[0],
-		PrivateKey: privateKey,
-		PublicKey:  publicKey,
-		Sign:       sign,
-		Template:   template,
-	}
-
-	return ucloudClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数（需要包含"code"字段）
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *UcloudClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	code, ok := param["code"]
-	if !ok {
-		return fmt.Errorf("missing parameter: code")
-	}
-
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	req := c.core.NewSendUSMSMessageRequest()
-	req.SigContent = ucloud.String(c.Sign)
-	req.TemplateId = ucloud.String(c.Template)
-	req.PhoneNumbers = targetPhoneNumber
-	req.TemplateParams = []string{code}
-	response, err := c.core.SendUSMSMessage(req)
-	if err != nil {
-		return err
-	}
-	if response.RetCode != 0 {
-		return fmt.Errorf(response.Message)
-	}
-	return nil
-}
+// Package sms UCloud短信服务实现
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/smart-unicom/sms/phone"
+	"github.com/ucloud/ucloud-sdk-go/services/usms"
+	"github.com/ucloud/ucloud-sdk-go/ucloud"
+	"github.com/ucloud/ucloud-sdk-go/ucloud/auth"
+	"github.com/ucloud/ucloud-sdk-go/ucloud/config"
+	uerr "github.com/ucloud/ucloud-sdk-go/ucloud/error"
+)
+
+// ucloudNumberFormat UCloud要求的号码格式
+// 中国大陆号码不带国家代码，其他号码为"(国家代码)号码"，如"(852)55559999"，由ucloudNumbers转换
+var ucloudNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL, international: phone.FORMAT_DIGITS}
+
+// ucloudErrorCodes UCloud错误码与标准错误的对应关系
+var ucloudErrorCodes = map[string]error{
+	"171": ErrAuthFailed, // 签名校验失败
+}
+
+// UcloudClient UCloud短信客户端
+// 封装UCloud短信API调用
+type UcloudClient struct {
+	core       *usms.USMSClient // UCloud USMS客户端
+	ProjectId  string           // 项目ID
+	PrivateKey string           // 私钥
+	PublicKey  string           // 公钥
+	Sign       string           // 短信签名
+	Template   string           // 短信模板ID
+}
+
+// init 注册UCloud短信服务
+func init() {
+	Register(SMS_UCloud, func(config ProviderConfig) (SmsProvider, error) {
+		return GetUcloudClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.ProjectId))
+	}, "AccessId", "AccessKey", "Sign", "Template", "ProjectId")
+}
+
+// GetUcloudClient 创建UCloud短信客户端
+// 参数:
+//   - publicKey: UCloud公钥
+//   - privateKey: UCloud私钥
+//   - sign: 短信签名
+//   - template: 短信模板ID
+//   - projectId: 项目ID列表
+// 返回:
+//   - *UcloudClient: UCloud短信客户端实例
+//   - error: 错误信息
+func GetUcloudClient(publicKey string, privateKey string, sign string, template string, projectId []string) (*UcloudClient, error) {
+	if len(projectId) == 0 {
+		return nil, fmt.Errorf("missing parameter: projectId")
+	}
+
+	cfg := config.NewConfig()
+	cfg.ProjectId = projectId[0]
+	credential := auth.NewCredential()
+	credential.PublicKey = publicKey
+	credential.PrivateKey = privateKey
+
+	client := usms.NewClient(&cfg, &credential)
+
+	ucloudClient := &UcloudClient{
+		core:       client,
+		ProjectId:  projectId[0],
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		Sign:       sign,
+		Template:   template,
+	}
+
+	return ucloudClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// UCloud SDK仅使用HTTP客户端的Transport和Timeout，且须在首次发送前设置
+// 参数:
+//   - client: HTTP客户端
+func (c *UcloudClient) SetHTTPClient(client *http.Client) {
+	if client.Transport != nil {
+		c.core.SetTransport(client.Transport)
+	}
+	if client.Timeout > 0 {
+		c.core.GetConfig().Timeout = client.Timeout
+	}
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *UcloudClient) SetEndpoint(endpoint string) {
+	c.core.GetConfig().BaseUrl = endpoint
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *UcloudClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数（需要包含"code"字段）
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *UcloudClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	code, ok := param["code"]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter: code")
+	}
+
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := ucloudNumbers(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.core.NewSendUSMSMessageRequest()
+	req.SigContent = ucloud.String(c.Sign)
+	req.TemplateId = ucloud.String(c.Template)
+	req.PhoneNumbers = phoneNumbers
+	req.TemplateParams = []string{code}
+	response, err := callWithContext(ctx, func() (*usms.SendUSMSMessageResponse, error) {
+		return c.core.SendUSMSMessage(req)
+	})
+	if err != nil {
+		return nil, ucloudSdkError(err)
+	}
+
+	result := newSendResult(SMS_UCloud)
+	result.RequestId = response.GetRequestUUID()
+	if response.RetCode != 0 {
+		for _, phoneNumber := range targetPhoneNumber {
+			result.add(&RecipientResult{
+				PhoneNumber: phoneNumber,
+				Code:        strconv.Itoa(response.RetCode),
+				Message:     response.Message,
+			})
+		}
+		return result, newSmsError(SMS_UCloud, strconv.Itoa(response.RetCode), response.Message, ucloudErrorCodes)
+	}
+
+	// UCloud以会话编号标识一次提交的所有短信
+	result.acceptAll(response.SessionNo, targetPhoneNumber)
+	return result, nil
+}
+
+// ucloudNumbers 将号码列表转换为UCloud要求的格式
+// 参数:
+//   - phoneNumbers: 调用方传入的号码列表
+// 返回:
+//   - []string: 与号码列表一一对应的UCloud格式的号码
+//   - error: 错误信息
+func ucloudNumbers(phoneNumbers []string) ([]string, error) {
+	formatted := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		number, domestic, err := ucloudNumberFormat.parse(phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		if domestic {
+			formatted = append(formatted, number.Format(ucloudNumberFormat.domestic))
+		} else {
+			formatted = append(formatted, fmt.Sprintf("(%s)%s", number.CountryCode, number.NationalNumber))
+		}
+	}
+	return formatted, nil
+}
+
+// ucloudSdkError 将UCloud SDK返回的错误转换为短信服务商错误
+// 参数:
+//   - err: SDK返回的错误
+// 返回:
+//   - error: 转换后的错误，非SDK错误时原样返回
+func ucloudSdkError(err error) error {
+	var sdkErr uerr.Error
+	if !errors.As(err, &sdkErr) {
+		return err
+	}
+
+	smsErr := newSmsError(SMS_UCloud, strconv.Itoa(sdkErr.Code()), sdkErr.Message(), ucloudErrorCodes).withStatus(sdkErr.StatusCode())
+	if smsErr.Err == nil && sdkErr.Retryable() {
+		smsErr.Err = ErrServiceUnavailable
+	}
+	if smsErr.Err == nil {
+		smsErr.Err = sdkErr.OriginError()
+	}
+
+	return smsErr
+}
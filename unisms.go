@@ -1,76 +1,173 @@
-// Package sms UniSMS短信服务实现
-package sms
-
-import (
-	"errors"
-	"fmt"
-	"strings"
-
-	unisms "github.com/apistd/uni-go-sdk/sms"
-)
-
-// UnismsClient UniSMS短信客户端
-// 封装UniSMS短信API调用
-type UnismsClient struct {
-	core     *unisms.UniSMSClient // UniSMS核心客户端
-	sign     string               // 短信签名
-	template string               // 短信模板
-}
-
-// GetUnismsClient 创建UniSMS短信客户端
-// 参数:
-//   - accessId: 访问ID
-//   - accessKey: 访问密钥
-//   - signature: 短信签名
-//   - templateId: 短信模板ID
-// 返回:
-//   - *UnismsClient: UniSMS短信客户端实例
-//   - error: 错误信息
-func GetUnismsClient(accessId string, accessKey string, signature string, templateId string) (*UnismsClient, error) {
-	client := unisms.NewClient(accessId, accessKey)
-
-	// 检查accessId和accessKey的正确性
-	msg := unisms.BuildMessage()
-	msg.SetTo("test")
-	msg.SetTemplateId("pub_verif_register") // 免费模板
-	_, err := client.Send(msg)
-	if strings.Contains(err.Error(), "[104111] InvalidAccessKeyId") {
-		return nil, err
-	}
-
-	unismsClient := &UnismsClient{
-		core:     client,
-		sign:     signature,
-		template: templateId,
-	}
-
-	return unismsClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *UnismsClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	msg := unisms.BuildMessage()
-	msg.SetTo(targetPhoneNumber...)
-	msg.SetSignature(c.sign)
-	msg.SetTemplateId(c.template)
-
-	resp, err := c.core.Send(msg)
-	if err != nil {
-		return err
-	}
-
-	if resp.Code != "0" {
-		return errors.New(resp.Message)
-	}
-
-	return nil
-}
+// Package sms UniSMS短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	uni "github.com/apistd/uni-go-sdk"
+	unisms "github.com/apistd/uni-go-sdk/sms"
+	"github.com/smart-unicom/sms/phone"
+)
+
+// unismsNumberFormat UniSMS要求的号码格式（E.164），未包含国家代码的号码按中国大陆号码处理
+var unismsNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_E164, international: phone.FORMAT_E164}
+
+// unismsErrorPattern UniSMS SDK错误信息格式："[错误码] 错误信息, RequestId: 请求ID"
+var unismsErrorPattern = regexp.MustCompile(`^\[(\w+)\] (.*), RequestId: .*$`)
+
+// UnismsClient UniSMS短信客户端
+// 封装UniSMS短信API调用
+type UnismsClient struct {
+	core     *unisms.UniSMSClient // UniSMS核心客户端
+	sign     string               // 短信签名
+	template string               // 短信模板
+}
+
+// UnismsSendData UniSMS发送响应数据
+type UnismsSendData struct {
+	Messages []UnismsMessage `json:"messages"` // 各接收方的消息
+}
+
+// UnismsMessage UniSMS单条消息
+type UnismsMessage struct {
+	Id           string `json:"id"`           // 消息ID
+	To           string `json:"to"`           // 接收方号码
+	Status       string `json:"status"`       // 消息状态
+	MessageCount int    `json:"messageCount"` // 计费条数
+}
+
+// init 注册UniSMS短信服务
+func init() {
+	Register(SMS_UNI, func(config ProviderConfig) (SmsProvider, error) {
+		return GetUnismsClient(config.AccessId, config.AccessKey, config.Sign, config.Template)
+	}, "AccessId", "Sign", "Template")
+}
+
+// GetUnismsClient 创建UniSMS短信客户端
+// 参数:
+//   - accessId: 访问ID
+//   - accessKey: 访问密钥
+//   - signature: 短信签名
+//   - templateId: 短信模板ID
+// 返回:
+//   - *UnismsClient: UniSMS短信客户端实例
+//   - error: 错误信息
+func GetUnismsClient(accessId string, accessKey string, signature string, templateId string) (*UnismsClient, error) {
+	client := unisms.NewClient(accessId, accessKey)
+
+	// 检查accessId和accessKey的正确性
+	msg := unisms.BuildMessage()
+	msg.SetTo("test")
+	msg.SetTemplateId("pub_verif_register") // 免费模板
+	_, err := client.Send(msg)
+	if strings.Contains(err.Error(), "[104111] InvalidAccessKeyId") {
+		return nil, err
+	}
+
+	unismsClient := &UnismsClient{
+		core:     client,
+		sign:     signature,
+		template: templateId,
+	}
+
+	return unismsClient, nil
+}
+
+// SetEndpoint 设置服务端点
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *UnismsClient) SetEndpoint(endpoint string) {
+	c.core.Client.SetEndpoint(endpoint)
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *UnismsClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *UnismsClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := unismsNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := unisms.BuildMessage()
+	msg.SetTo(phoneNumbers...)
+	msg.SetSignature(c.sign)
+	msg.SetTemplateId(c.template)
+
+	resp, err := callWithContext(ctx, func() (*uni.UniResponse, error) {
+		return c.core.Send(msg)
+	})
+	if err != nil {
+		return nil, unismsSdkError(err)
+	}
+
+	result := newSendResult(SMS_UNI)
+	result.RequestId = resp.RequestId
+
+	if resp.Code != "0" {
+		return result, newSmsError(SMS_UNI, resp.Code, resp.Message, nil).withStatus(resp.Status)
+	}
+
+	raw, err := json.Marshal(resp.Data)
+	if err != nil {
+		return result, err
+	}
+	result.Raw = string(raw)
+
+	var data UnismsSendData
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return result, err
+	}
+
+	for _, message := range data.Messages {
+		result.add(&RecipientResult{
+			PhoneNumber: message.To,
+			MessageId:   message.Id,
+			Accepted:    true,
+			Code:        message.Status,
+			Fee:         message.MessageCount,
+			Segments:    message.MessageCount,
+		})
+	}
+	result.restoreNumbers(phoneNumbers, targetPhoneNumber)
+
+	return result, nil
+}
+
+// unismsSdkError 将UniSMS SDK返回的错误转换为短信服务商错误
+// SDK只返回拼接后的错误字符串，这里从中解析出原始错误码
+// 参数:
+//   - err: SDK返回的错误
+// 返回:
+//   - error: 转换后的错误，无法解析时原样返回
+func unismsSdkError(err error) error {
+	matches := unismsErrorPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+	return newSmsError(SMS_UNI, matches[1], matches[2], nil)
+}
@@ -0,0 +1,106 @@
+// Package sms 手机号码校验
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/smart-unicom/sms/phone"
+)
+
+// ValidateNumber 校验手机号码
+// 按内置的元数据校验国家代码、国内有效号码长度和手机号段，不需要联网
+// 参数:
+//   - phoneNumber: 手机号码
+//   - defaultRegion: 号码未包含国家代码时使用的默认地区（ISO 3166-1二字母代码，如"CN"），为空时号码必须以"+"或"00"开头
+// 返回:
+//   - error: 号码无效时返回包含原因的ErrInvalidNumber
+func ValidateNumber(phoneNumber string, defaultRegion string) error {
+	_, err := phone.Validate(phoneNumber, defaultRegion)
+	return err
+}
+
+// ValidatingProvider 发送前校验号码的短信服务提供商
+// 无效号码不会发送给服务商，标记为未受理并返回ErrInvalidNumber；
+// 有效号码以E.164格式传给被包装的服务提供商，发送结果中仍为调用方传入的原始号码
+type ValidatingProvider struct {
+	provider      SmsProvider // 被包装的服务提供商
+	defaultRegion string      // 号码未包含国家代码时使用的默认地区
+}
+
+// 确保ValidatingProvider实现了ContextSmsProvider接口
+var _ ContextSmsProvider = &ValidatingProvider{}
+
+// WithValidation 为短信服务提供商添加发送前的号码校验
+// 参数:
+//   - provider: 短信服务提供商
+//   - defaultRegion: 号码未包含国家代码时使用的默认地区，为空时号码必须以"+"或"00"开头
+// 返回:
+//   - *ValidatingProvider: 发送前校验号码的短信服务提供商
+func WithValidation(provider SmsProvider, defaultRegion string) *ValidatingProvider {
+	return &ValidatingProvider{
+		provider:      provider,
+		defaultRegion: defaultRegion,
+	}
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (v *ValidatingProvider) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := v.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 无效号码标记为未受理，其余号码正常发送；所有号码均无效时不请求服务商
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息，存在无效号码时包含ErrInvalidNumber
+func (v *ValidatingProvider) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	var errs []error
+	invalid := make([]*RecipientResult, 0)
+	valid := make([]string, 0, len(targetPhoneNumber))
+	formatted := make([]string, 0, len(targetPhoneNumber))
+	for _, phoneNumber := range targetPhoneNumber {
+		number, err := phone.Validate(phoneNumber, v.defaultRegion)
+		if err != nil {
+			invalid = append(invalid, &RecipientResult{PhoneNumber: phoneNumber, Message: err.Error()})
+			errs = append(errs, err)
+			continue
+		}
+		valid = append(valid, phoneNumber)
+		formatted = append(formatted, number.String())
+	}
+
+	result := newSendResult("")
+	if len(formatted) > 0 {
+		sent, err := SendMessageContext(ctx, v.provider, param, formatted...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if sent != nil {
+			sent.restoreNumbers(formatted, valid)
+			result = sent
+		}
+	}
+
+	for _, recipient := range invalid {
+		result.add(recipient)
+	}
+	result.sortRecipients(targetPhoneNumber)
+
+	return result, errors.Join(errs...)
+}
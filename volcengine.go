@@ -1,86 +1,179 @@
-// Package sms 火山引擎短信服务实现
-package sms
-
-import (
-	"encoding/json"
-	"fmt"
-	"strings"
-
-	"github.com/volcengine/volc-sdk-golang/service/sms"
-)
-
-// VolcClient 火山引擎短信客户端
-// 封装火山引擎短信API调用
-type VolcClient struct {
-	core       *sms.SMS // 火山引擎SMS客户端
-	sign       string   // 短信签名
-	template   string   // 短信模板ID
-	smsAccount string   // 短信账户
-}
-
-// GetVolcClient 创建火山引擎短信客户端
-// 参数:
-//   - accessId: 火山引擎访问ID
-//   - accessKey: 火山引擎访问密钥
-//   - sign: 短信签名
-//   - templateId: 短信模板ID
-//   - smsAccount: 短信账户列表
-// 返回:
-//   - *VolcClient: 火山引擎短信客户端实例
-//   - error: 错误信息
-func GetVolcClient(accessId, accessKey, sign, templateId string, smsAccount []string) (*VolcClient, error) {
-	if len(smsAccount) == 0 {
-		return nil, fmt.Errorf("missing parameter: smsAccount")
-	}
-
-	client := sms.NewInstance()
-	client.Client.SetAccessKey(accessId)
-	client.Client.SetSecretKey(accessKey)
-
-	volcClient := &VolcClient{
-		core:       client,
-		sign:       sign,
-		template:   templateId,
-		smsAccount: smsAccount[0],
-	}
-
-	return volcClient, nil
-}
-
-// SendMessage 发送短信
-// 参数:
-//   - param: 短信模板参数
-//   - targetPhoneNumber: 目标手机号码列表
-// 返回:
-//   - error: 错误信息
-func (c *VolcClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
-	if len(targetPhoneNumber) == 0 {
-		return fmt.Errorf("missing parameter: targetPhoneNumber")
-	}
-
-	requestParam, err := json.Marshal(param)
-	if err != nil {
-		return err
-	}
-
-	req := &sms.SmsRequest{
-		SmsAccount:    c.smsAccount,
-		Sign:          c.sign,
-		TemplateID:    c.template,
-		TemplateParam: string(requestParam),
-		PhoneNumbers:  strings.Join(targetPhoneNumber, ","),
-	}
-
-	resp, statusCode, err := c.core.Send(req)
-	if err != nil {
-		return fmt.Errorf("send message failed, error: %q", err.Error())
-	}
-	if statusCode < 200 || statusCode > 299 {
-		return fmt.Errorf("send message failed, statusCode: %d", statusCode)
-	}
-	if resp.ResponseMetadata.Error != nil {
-		return fmt.Errorf("send message failed, code: %q, message: %q", resp.ResponseMetadata.Error.Code, resp.ResponseMetadata.Error.Message)
-	}
-
-	return nil
-}
+// Package sms 火山引擎短信服务实现
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/smart-unicom/sms/phone"
+	"github.com/volcengine/volc-sdk-golang/service/sms"
+)
+
+// volcNumberFormat 火山引擎要求的号码格式
+// 国内号码不带国家代码，国际/港澳台号码为E.164格式
+var volcNumberFormat = numberFormat{region: "CN", domestic: phone.FORMAT_NATIONAL, international: phone.FORMAT_E164}
+
+// volcErrorCodes 火山引擎公共错误码与标准错误的对应关系
+var volcErrorCodes = map[string]error{
+	"MissingAuthenticationToken": ErrAuthFailed,
+	"InvalidAccessKey":           ErrAuthFailed,
+	"InvalidCredential":          ErrAuthFailed,
+	"SignatureDoesNotMatch":      ErrAuthFailed,
+	"AccessDenied":               ErrAuthFailed,
+	"FlowLimitExceeded":          ErrRateLimited,
+	"AccountFlowLimitExceeded":   ErrRateLimited,
+	"ServiceUnavailableTemp":     ErrServiceUnavailable,
+	"InternalError":              ErrServiceUnavailable,
+	"InternalServiceError":       ErrServiceUnavailable,
+	"InternalServiceTimeout":     ErrServiceUnavailable,
+}
+
+// VolcClient 火山引擎短信客户端
+// 封装火山引擎短信API调用
+type VolcClient struct {
+	core       *sms.SMS // 火山引擎SMS客户端
+	sign       string   // 短信签名
+	template   string   // 短信模板ID
+	smsAccount string   // 短信账户
+}
+
+// init 注册火山引擎短信服务
+func init() {
+	Register(SMS_VOCL, func(config ProviderConfig) (SmsProvider, error) {
+		return GetVolcClient(config.AccessId, config.AccessKey, config.Sign, config.Template, config.otherArgs(config.SmsAccount))
+	}, "AccessId", "AccessKey", "Sign", "Template", "SmsAccount")
+}
+
+// GetVolcClient 创建火山引擎短信客户端
+// 参数:
+//   - accessId: 火山引擎访问ID
+//   - accessKey: 火山引擎访问密钥
+//   - sign: 短信签名
+//   - templateId: 短信模板ID
+//   - smsAccount: 短信账户列表
+// 返回:
+//   - *VolcClient: 火山引擎短信客户端实例
+//   - error: 错误信息
+func GetVolcClient(accessId, accessKey, sign, templateId string, smsAccount []string) (*VolcClient, error) {
+	if len(smsAccount) == 0 {
+		return nil, fmt.Errorf("missing parameter: smsAccount")
+	}
+
+	client := sms.NewInstance()
+	client.Client.SetAccessKey(accessId)
+	client.Client.SetSecretKey(accessKey)
+
+	volcClient := &VolcClient{
+		core:       client,
+		sign:       sign,
+		template:   templateId,
+		smsAccount: smsAccount[0],
+	}
+
+	return volcClient, nil
+}
+
+// SetHTTPClient 设置HTTP客户端
+// 参数:
+//   - client: HTTP客户端
+func (c *VolcClient) SetHTTPClient(client *http.Client) {
+	c.core.Client.Client = client
+}
+
+// SetEndpoint 设置服务端点
+// 火山引擎SDK仅使用服务端点的协议和主机
+// 参数:
+//   - endpoint: 服务端点基础URL
+func (c *VolcClient) SetEndpoint(endpoint string) {
+	c.core.Client.ServiceInfo.Scheme, c.core.Client.ServiceInfo.Host = splitEndpoint(endpoint)
+}
+
+// SendMessage 发送短信
+// 参数:
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - error: 错误信息
+func (c *VolcClient) SendMessage(param map[string]string, targetPhoneNumber ...string) error {
+	_, err := c.SendMessageContext(context.Background(), param, targetPhoneNumber...)
+	return err
+}
+
+// SendMessageContext 发送短信（支持上下文）
+// 参数:
+//   - ctx: 上下文
+//   - param: 短信模板参数
+//   - targetPhoneNumber: 目标手机号码列表
+// 返回:
+//   - *SendResult: 发送结果
+//   - error: 错误信息
+func (c *VolcClient) SendMessageContext(ctx context.Context, param map[string]string, targetPhoneNumber ...string) (*SendResult, error) {
+	if len(targetPhoneNumber) == 0 {
+		return nil, fmt.Errorf("missing parameter: targetPhoneNumber")
+	}
+
+	phoneNumbers, err := volcNumberFormat.formatAll(targetPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	requestParam, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &sms.SmsRequest{
+		SmsAccount:    c.smsAccount,
+		Sign:          c.sign,
+		TemplateID:    c.template,
+		TemplateParam: string(requestParam),
+		PhoneNumbers:  strings.Join(phoneNumbers, ","),
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// SDK的Send方法不支持上下文，这里直接调用底层带上下文的JSON接口
+	// 服务端返回非2xx状态码时SDK同样返回错误，此时响应体中包含具体错误码
+	respBody, statusCode, err := c.core.Client.CtxJson(ctx, "SendSms", nil, string(reqBody))
+	if err != nil && len(respBody) == 0 {
+		return nil, fmt.Errorf("send message failed, error: %w", err)
+	}
+
+	resp := &sms.SmsResponse{}
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		if statusCode < 200 || statusCode > 299 {
+			return nil, newHttpStatusError(SMS_VOCL, statusCode, string(respBody))
+		}
+		return nil, fmt.Errorf("send message failed, error: %q", err.Error())
+	}
+
+	result := newSendResult(SMS_VOCL)
+	result.RequestId = resp.ResponseMetadata.RequestId
+	result.Raw = string(respBody)
+
+	if resp.ResponseMetadata.Error != nil {
+		return result, newSmsError(SMS_VOCL, resp.ResponseMetadata.Error.Code, resp.ResponseMetadata.Error.Message, volcErrorCodes).withStatus(statusCode)
+	}
+	if statusCode < 200 || statusCode > 299 {
+		return result, newHttpStatusError(SMS_VOCL, statusCode, string(respBody))
+	}
+
+	// 火山引擎按号码顺序返回消息ID
+	for i, phoneNumber := range targetPhoneNumber {
+		recipient := result.add(&RecipientResult{
+			PhoneNumber: phoneNumber,
+			Accepted:    true,
+		})
+		if resp.Result != nil && i < len(resp.Result.MessageID) {
+			recipient.MessageId = resp.Result.MessageID[i]
+		}
+	}
+
+	return result, nil
+}
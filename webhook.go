@@ -0,0 +1,310 @@
+// Package sms 短信状态报告回调处理
+package sms
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// MAX_WEBHOOK_BODY 回调请求体的最大字节数
+const MAX_WEBHOOK_BODY = 1 << 20
+
+// ErrInvalidSignature 回调请求签名校验失败
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// StatusCallbackSetter 支持在发送时指定状态报告回调地址的短信服务提供商
+// SUBMAIL和Msg91的回调地址只能在服务商控制台配置
+type StatusCallbackSetter interface {
+	// SetStatusCallback 设置状态报告回调地址
+	// 参数:
+	//   - callbackUrl: 状态报告回调地址，为空时不指定
+	SetStatusCallback(callbackUrl string)
+}
+
+// ReportCallback 状态报告回调函数
+// 在处理回调请求的goroutine中同步调用，耗时操作应自行异步处理
+type ReportCallback func(report *DeliveryReport)
+
+// reportParser 将服务商的回调请求解析为送达状态报告
+type reportParser func(r *http.Request) ([]*DeliveryReport, error)
+
+// reportHandshake 处理服务商的回调地址验证请求
+// 返回true表示请求为验证请求且已响应
+type reportHandshake func(w http.ResponseWriter, r *http.Request) bool
+
+// reportParsers 支持状态报告回调的服务商及其解析函数
+var reportParsers = map[string]reportParser{
+	SMS_AZURE:   parseAzureReport,
+	SMS_HUAWEI:  parseHuaweiReport,
+	SMS_TWILIO:  parseTwilioReport,
+	SMS_INFOBIP: parseInfobipReport,
+	SMS_SUBMAIL: parseSubmailReport,
+	SMS_MSG91:   parseMsg91Report,
+}
+
+// reportHandshakes 需要验证回调地址的服务商及其处理函数
+var reportHandshakes = map[string]reportHandshake{
+	SMS_AZURE: azureEventGridHandshake,
+}
+
+// ReportHandler 状态报告回调处理器
+// 将服务商推送的状态报告解析为DeliveryReport后交给回调函数，请求格式错误时返回400
+type ReportHandler struct {
+	provider  string          // 服务提供商类型
+	parse     reportParser    // 回调请求解析函数
+	handshake reportHandshake // 回调地址验证处理函数
+	callback  ReportCallback  // 状态报告回调函数
+}
+
+// WebhookVerifier 回调请求校验器
+// 用于拒绝伪造的回调请求，配合VerifyWebhook使用
+type WebhookVerifier interface {
+	// Verify 校验回调请求
+	// 参数:
+	//   - r: HTTP请求
+	//   - body: 请求体，校验器应使用该参数而不是读取r.Body
+	// 返回:
+	//   - error: 校验失败时返回ErrInvalidSignature或包含ErrInvalidSignature的错误
+	Verify(r *http.Request, body []byte) error
+}
+
+// BasicAuthVerifier 校验HTTP Basic认证的回调请求校验器
+// 适用于不对回调请求签名、但支持在回调地址中配置认证信息的服务商（如Infobip的BASIC安全设置）
+type BasicAuthVerifier struct {
+	Username string // 用户名
+	Password string // 密码
+}
+
+// TokenVerifier 校验共享密钥的回调请求校验器
+// 适用于不对回调请求签名的服务商（如Msg91、华为云、Azure Event Grid），
+// 将密钥作为请求头或回调地址的查询参数，如"https://example.com/sms/status?token=xxx"
+type TokenVerifier struct {
+	Name  string // 请求头或查询参数名
+	Token string // 共享密钥
+}
+
+// 确保ReportHandler实现了http.Handler接口
+var _ http.Handler = &ReportHandler{}
+
+// SetStatusCallback 为短信服务提供商设置状态报告回调地址
+// 参数:
+//   - provider: 短信服务提供商实例
+//   - callbackUrl: 状态报告回调地址
+// 返回:
+//   - error: 错误信息，服务商不支持在发送时指定回调地址时返回错误
+func SetStatusCallback(provider SmsProvider, callbackUrl string) error {
+	if callbackUrl == "" {
+		return fmt.Errorf("missing parameter: callbackUrl")
+	}
+
+	setter, ok := provider.(StatusCallbackSetter)
+	if !ok {
+		return fmt.Errorf("provider does not support status callback: %T", provider)
+	}
+
+	setter.SetStatusCallback(callbackUrl)
+	return nil
+}
+
+// NewReportHandler 创建状态报告回调处理器
+// 支持Azure（Event Grid）、华为云、Twilio、Infobip、SUBMAIL和Msg91
+// 参数:
+//   - provider: 服务提供商类型（SMS_*常量）
+//   - callback: 状态报告回调函数
+// 返回:
+//   - *ReportHandler: 状态报告回调处理器
+//   - error: 错误信息，服务商不支持状态报告回调时返回错误
+func NewReportHandler(provider string, callback ReportCallback) (*ReportHandler, error) {
+	if callback == nil {
+		return nil, fmt.Errorf("missing parameter: callback")
+	}
+
+	parse, ok := reportParsers[provider]
+	if !ok {
+		return nil, fmt.Errorf("provider does not support status report callback: %s", provider)
+	}
+
+	return &ReportHandler{
+		provider:  provider,
+		parse:     parse,
+		handshake: reportHandshakes[provider],
+		callback:  callback,
+	}, nil
+}
+
+// ServeHTTP 处理状态报告回调请求
+// 参数:
+//   - w: HTTP响应
+//   - r: HTTP请求
+func (h *ReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)
+	if h.handshake != nil && h.handshake(w, r) {
+		return
+	}
+
+	reports, err := h.parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, report := range reports {
+		report.Provider = h.provider
+		h.callback(report)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// VerifyWebhook 为回调处理器添加请求校验
+// 校验失败时返回403，不再调用handler
+// 参数:
+//   - verifier: 回调请求校验器
+//   - handler: 回调处理器
+// 返回:
+//   - http.Handler: 带请求校验的回调处理器
+func VerifyWebhook(verifier WebhookVerifier, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err = verifier.Verify(r, body); err != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Verify 校验HTTP Basic认证
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体（不使用）
+// 返回:
+//   - error: 认证信息不匹配时返回ErrInvalidSignature
+func (v BasicAuthVerifier) Verify(r *http.Request, body []byte) error {
+	username, password, ok := r.BasicAuth()
+	if !ok || !secureEqual(username, v.Username) || !secureEqual(password, v.Password) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Verify 校验共享密钥
+// 优先使用请求头，请求头不存在时使用查询参数
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体（不使用）
+// 返回:
+//   - error: 密钥不匹配时返回ErrInvalidSignature
+func (v TokenVerifier) Verify(r *http.Request, body []byte) error {
+	token := r.Header.Get(v.Name)
+	if token == "" {
+		token = r.URL.Query().Get(v.Name)
+	}
+	if v.Token == "" || !secureEqual(token, v.Token) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// secureEqual 以固定时间比较两个字符串，避免时序攻击
+// 参数:
+//   - a: 字符串
+//   - b: 字符串
+// 返回:
+//   - bool: 是否相等
+func secureEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// bodyForm 从已读取的请求体解析表单参数
+// 参数:
+//   - r: HTTP请求
+//   - body: 请求体
+// 返回:
+//   - url.Values: 表单参数
+//   - error: 错误信息
+func bodyForm(r *http.Request, body []byte) (url.Values, error) {
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.PostForm = nil
+	req.Form = nil
+	req.MultipartForm = nil
+	return readForm(req)
+}
+
+// requestURL 还原回调请求的完整URL
+// 位于反向代理之后时使用X-Forwarded-Proto判断协议
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - string: 请求URL
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// readForm 读取表单格式的回调请求
+// 同时支持application/x-www-form-urlencoded和multipart/form-data
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - url.Values: 表单参数
+//   - error: 错误信息
+func readForm(r *http.Request) (url.Values, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(MAX_WEBHOOK_BODY); err != nil {
+			return nil, fmt.Errorf("bad callback form: %w", err)
+		}
+		return r.PostForm, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("bad callback form: %w", err)
+	}
+	return r.PostForm, nil
+}
+
+// readBody 读取回调请求体
+// 参数:
+//   - r: HTTP请求
+// 返回:
+//   - []byte: 请求体
+//   - error: 错误信息
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, fmt.Errorf("bad callback body: larger than %d bytes", maxErr.Limit)
+		}
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,468 @@
+package sms
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc 以函数实现的HTTP传输
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+// RoundTrip 执行HTTP请求
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// serveWebhook 通过VerifyWebhook处理回调请求
+// 返回响应状态码及回调处理器是否被调用
+func serveWebhook(verifier WebhookVerifier, r *http.Request) (int, bool) {
+	called := false
+	handler := VerifyWebhook(verifier, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code, called
+}
+
+// twilioSignature 计算Twilio回调请求签名
+func twilioSignature(authToken string, data string) string {
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestTwilioVerifier(t *testing.T) {
+	// 示例来自Twilio文档: https://www.twilio.com/docs/usage/security#validating-requests
+	const docUrl = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	docForm := "CallSid=CA1234567890ABCDE&Caller=%2B12349013030&Digits=1234&From=%2B12349013030&To=%2B18005551212"
+	const docSignature = "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+
+	jsonBody := `{"MessageSid":"SM123","MessageStatus":"delivered"}`
+	sum := sha256.Sum256([]byte(jsonBody))
+	jsonUrl := "https://example.com/sms/status?bodySHA256=" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		verifier    TwilioVerifier
+		url         string
+		contentType string
+		body        string
+		header      map[string]string
+		wantCode    int
+	}{
+		{
+			name:        "form with configured url",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: docUrl},
+			url:         "http://localhost/myapp.php?foo=1&bar=2",
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "form with url from request",
+			verifier:    TwilioVerifier{AuthToken: "12345"},
+			url:         "http://mycompany.com/myapp.php?foo=1&bar=2",
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			header:      map[string]string{"X-Twilio-Signature": docSignature, "X-Forwarded-Proto": "https"},
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "form parameters in different order",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: docUrl},
+			url:         docUrl,
+			contentType: "application/x-www-form-urlencoded",
+			body:        "To=%2B18005551212&From=%2B12349013030&Digits=1234&Caller=%2B12349013030&CallSid=CA1234567890ABCDE",
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "tampered form",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: docUrl},
+			url:         docUrl,
+			contentType: "application/x-www-form-urlencoded",
+			body:        strings.Replace(docForm, "Digits=1234", "Digits=4321", 1),
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "wrong auth token",
+			verifier:    TwilioVerifier{AuthToken: "54321", URL: docUrl},
+			url:         docUrl,
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "url mismatch",
+			verifier:    TwilioVerifier{AuthToken: "12345"},
+			url:         "http://mycompany.com/myapp.php?foo=1&bar=2",
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "missing signature",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: docUrl},
+			url:         docUrl,
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "missing auth token",
+			verifier:    TwilioVerifier{URL: docUrl},
+			url:         docUrl,
+			contentType: "application/x-www-form-urlencoded",
+			body:        docForm,
+			header:      map[string]string{"X-Twilio-Signature": docSignature},
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "json with body hash",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: jsonUrl},
+			url:         jsonUrl,
+			contentType: "application/json",
+			body:        jsonBody,
+			header:      map[string]string{"X-Twilio-Signature": twilioSignature("12345", jsonUrl)},
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "json with tampered body",
+			verifier:    TwilioVerifier{AuthToken: "12345", URL: jsonUrl},
+			url:         jsonUrl,
+			contentType: "application/json",
+			body:        strings.Replace(jsonBody, "delivered", "failed", 1),
+			header:      map[string]string{"X-Twilio-Signature": twilioSignature("12345", jsonUrl)},
+			wantCode:    http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			for key, value := range tt.header {
+				r.Header.Set(key, value)
+			}
+
+			code, called := serveWebhook(tt.verifier, r)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, !called)
+			}
+		})
+	}
+}
+
+func TestSnsVerifier(t *testing.T) {
+	const certUrl = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+	const topicArn = "arn:aws:sns:us-east-1:123456789012:sms-status"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(message SnsMessage, key *rsa.PrivateKey) SnsMessage {
+		hash := crypto.SHA1
+		if message.SignatureVersion == "2" {
+			hash = crypto.SHA256
+		}
+		h := hash.New()
+		h.Write([]byte(message.signingString()))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, hash, h.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		message.Signature = base64.StdEncoding.EncodeToString(signature)
+		return message
+	}
+
+	notification := SnsMessage{
+		Type:             "Notification",
+		MessageId:        "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:         topicArn,
+		Message:          `{"status":"SUCCESS"}`,
+		Timestamp:        "2024-01-01T00:00:00.000Z",
+		SignatureVersion: "1",
+		SigningCertURL:   certUrl,
+	}
+	confirmation := notification
+	confirmation.Type = "SubscriptionConfirmation"
+	confirmation.Token = "token"
+	confirmation.SubscribeURL = "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription"
+	confirmation.SignatureVersion = "2"
+
+	tampered := sign(notification, key)
+	tampered.Message = `{"status":"FAILURE"}`
+	untrustedHost := notification
+	untrustedHost.SigningCertURL = "https://sns.us-east-1.amazonaws.com.example.com/cert.pem"
+	untrustedScheme := notification
+	untrustedScheme.SigningCertURL = "http://sns.us-east-1.amazonaws.com/cert.pem"
+	unsupportedVersion := notification
+	unsupportedVersion.SignatureVersion = "3"
+	otherTopic := notification
+	otherTopic.TopicArn = "arn:aws:sns:us-east-1:123456789012:other"
+
+	tests := []struct {
+		name     string
+		message  SnsMessage
+		wantCode int
+	}{
+		{"notification signed with sha1", sign(notification, key), http.StatusOK},
+		{"subscription confirmation signed with sha256", sign(confirmation, key), http.StatusOK},
+		{"tampered message", tampered, http.StatusForbidden},
+		{"signed with other key", sign(notification, otherKey), http.StatusForbidden},
+		{"untrusted cert host", sign(untrustedHost, key), http.StatusForbidden},
+		{"untrusted cert scheme", sign(untrustedScheme, key), http.StatusForbidden},
+		{"unsupported signature version", unsupportedVersion, http.StatusForbidden},
+		{"unexpected topic", sign(otherTopic, key), http.StatusForbidden},
+	}
+
+	downloads := 0
+	verifier := NewSnsVerifier(topicArn)
+	verifier.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		downloads++
+		if r.URL.String() != certUrl {
+			t.Errorf("downloaded cert from %s, want %s", r.URL, certUrl)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(certPem)),
+			Request:    r,
+		}, nil
+	})})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodPost, "https://example.com/sms/status", strings.NewReader(string(body)))
+
+			code, called := serveWebhook(verifier, r)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, !called)
+			}
+		})
+	}
+
+	if downloads != 1 {
+		t.Errorf("downloaded cert %d times, want 1", downloads)
+	}
+}
+
+func TestBasicAuthVerifier(t *testing.T) {
+	verifier := BasicAuthVerifier{Username: "infobip", Password: "secret"}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		auth     bool
+		wantCode int
+	}{
+		{"valid credentials", "infobip", "secret", true, http.StatusOK},
+		{"wrong password", "infobip", "wrong", true, http.StatusForbidden},
+		{"wrong username", "other", "secret", true, http.StatusForbidden},
+		{"missing credentials", "", "", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "https://example.com/sms/status", strings.NewReader("{}"))
+			if tt.auth {
+				r.SetBasicAuth(tt.username, tt.password)
+			}
+
+			code, called := serveWebhook(verifier, r)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, !called)
+			}
+		})
+	}
+}
+
+func TestTokenVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier TokenVerifier
+		url      string
+		header   string
+		wantCode int
+	}{
+		{"valid header", TokenVerifier{Name: "X-Token", Token: "secret"}, "https://example.com/sms/status", "secret", http.StatusOK},
+		{"valid query", TokenVerifier{Name: "token", Token: "secret"}, "https://example.com/sms/status?token=secret", "", http.StatusOK},
+		{"header takes precedence", TokenVerifier{Name: "token", Token: "secret"}, "https://example.com/sms/status?token=secret", "wrong", http.StatusForbidden},
+		{"wrong token", TokenVerifier{Name: "token", Token: "secret"}, "https://example.com/sms/status?token=wrong", "", http.StatusForbidden},
+		{"missing token", TokenVerifier{Name: "token", Token: "secret"}, "https://example.com/sms/status", "", http.StatusForbidden},
+		{"empty configured token", TokenVerifier{Name: "token"}, "https://example.com/sms/status?token=", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader("{}"))
+			if tt.header != "" {
+				r.Header.Set(tt.verifier.Name, tt.header)
+			}
+
+			code, called := serveWebhook(tt.verifier, r)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, !called)
+			}
+		})
+	}
+}
+
+func TestAzureEventGridHandshake(t *testing.T) {
+	validation := `[{"id":"1","eventType":"Microsoft.EventGrid.SubscriptionValidationEvent","data":{"validationCode":"512d38b6-c7b8-40c8-89fe-f46f9e9622b6"}}]`
+
+	tests := []struct {
+		name      string
+		url       string
+		eventType string
+		body      string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:      "validation with token",
+			url:       "https://example.com/sms/status?token=secret",
+			eventType: "SubscriptionValidation",
+			body:      validation,
+			wantCode:  http.StatusOK,
+			wantBody:  `{"validationResponse":"512d38b6-c7b8-40c8-89fe-f46f9e9622b6"}`,
+		},
+		{
+			name:      "validation with wrong token",
+			url:       "https://example.com/sms/status?token=wrong",
+			eventType: "SubscriptionValidation",
+			body:      validation,
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name:      "validation without code",
+			url:       "https://example.com/sms/status?token=secret",
+			eventType: "SubscriptionValidation",
+			body:      `[{"id":"1","eventType":"Microsoft.EventGrid.SubscriptionValidationEvent","data":{}}]`,
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "validation with bad body",
+			url:       "https://example.com/sms/status?token=secret",
+			eventType: "SubscriptionValidation",
+			body:      "{",
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "notification is not a handshake",
+			url:       "https://example.com/sms/status?token=secret",
+			eventType: "Notification",
+			body:      `[]`,
+			wantCode:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := 0
+			handler, err := NewReportHandler(SMS_AZURE, func(report *DeliveryReport) { reports++ })
+			if err != nil {
+				t.Fatalf("NewReportHandler() error = %v", err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("aeg-event-type", tt.eventType)
+
+			w := httptest.NewRecorder()
+			VerifyWebhook(TokenVerifier{Name: "token", Token: "secret"}, handler).ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			if reports != 0 {
+				t.Errorf("got %d reports, want 0", reports)
+			}
+		})
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		proto  string
+		want   string
+	}{
+		{"plain http", "http://example.com/status?a=1", "", "http://example.com/status?a=1"},
+		{"tls", "https://example.com/status", "", "https://example.com/status"},
+		{"behind proxy", "http://example.com/status", "https", "https://example.com/status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if got := requestURL(r); got != tt.want {
+				t.Errorf("requestURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}